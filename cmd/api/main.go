@@ -3,30 +3,70 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	_ "time/tzdata"
 
+	"github.com/RuLap/meetly-api/meetly/internal/app/admin"
 	"github.com/RuLap/meetly-api/meetly/internal/app/auth"
+	"github.com/RuLap/meetly-api/meetly/internal/app/chat"
 	"github.com/RuLap/meetly-api/meetly/internal/app/event"
 	mail_services "github.com/RuLap/meetly-api/meetly/internal/app/mail/services"
+	"github.com/RuLap/meetly-api/meetly/internal/app/media"
+	"github.com/RuLap/meetly-api/meetly/internal/app/notification"
 	"github.com/RuLap/meetly-api/meetly/internal/app/user"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/blob"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/config"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/dedup"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/eventstream"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/jwt_helper"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/logger"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/middleware"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/outbox"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/pubsub"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/scheduler"
 	postgres "github.com/RuLap/meetly-api/meetly/internal/pkg/storage"
 	validation "github.com/RuLap/meetly-api/meetly/internal/pkg/validator"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush"
 	"github.com/darahayes/go-boom"
 	"github.com/go-chi/chi/v5"
 	chi_middleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/redis/go-redis/v9"
 )
 
+const reminderInterval = time.Minute
+
+// deferredEmailInterval — как часто проверяются письма, отложенные до конца тихих часов.
+const deferredEmailInterval = time.Minute
+
+// outboxRelayInterval — как часто сообщения из outbox публикуются в брокер.
+const outboxRelayInterval = time.Second
+
+// messageDedupTTL — сколько потребители помнят обработанные сообщения. Не
+// меньше срока хранения опубликованных сообщений в outbox.
+const messageDedupTTL = 7 * 24 * time.Hour
+
+// shutdownTimeout ограничивает остановку HTTP-сервера.
+const shutdownTimeout = 10 * time.Second
+
+// Буфер потока события для возобновления SSE по Last-Event-ID.
+const (
+	eventStreamMaxLen = 1000
+	eventStreamTTL    = time.Hour
+)
+
 func main() {
 	cfg := config.MustLoad()
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	logger := logger.New(logger.Config{
 		Level:   cfg.Env,
 		LokiURL: cfg.Log.LokiURL,
@@ -38,7 +78,7 @@ func main() {
 	redisClient := initRedis(logger, &cfg.Redis)
 	logger.Info("init redis successfully")
 
-	rabbitmqClient := initRabbitMQ(logger, &cfg.RabbitMQ)
+	rabbitmqClient := initRabbitMQ(logger, &cfg.RabbitMQ, redisClient)
 	logger.Info("init rabbitmq successfully")
 
 	storage, err := postgres.InitDB(cfg.PostgresConnString)
@@ -53,40 +93,136 @@ func main() {
 		return
 	}
 
-	authModule := auth.NewModule(logger, storage.Database(), jwtHelper, &cfg.GoogleOAuth, redisClient, rabbitmqClient)
+	// Письма и доменные события пишутся в outbox и без брокера: их опубликует
+	// ретранслятор этого или другого экземпляра, подключённого к RabbitMQ.
+	emailOutbox := outbox.New(storage.Database())
+
+	authModule := auth.NewModule(logger, storage.Database(), jwtHelper, &cfg.GoogleOAuth, redisClient, emailOutbox)
 	userModule := user.NewModule(logger, storage.Database())
 
 	userProvider := user.NewUserProvider(userModule.Service)
 
-	eventModule := event.NewModule(logger, storage.Database(), userProvider)
+	pushSender, err := initPushSender(&cfg.Notifications.Push)
+	if err != nil {
+		logger.Error("failed to initialize web push", "error", err)
+		return
+	}
+	if pushSender == nil {
+		logger.Warn("VAPID keys not configured - push notifications will be disabled")
+	}
+
+	notificationModule := notification.NewModule(
+		logger,
+		storage.Database(),
+		userProvider,
+		pushSender,
+		cfg.Notifications.Push.TTL,
+		cfg.Notifications.Push.AllowInsecureEndpoints,
+	)
+
+	chatPubSub := initChatPubSub(logger, &cfg.Chat, redisClient)
+	eventStream := eventstream.New(logger, redisClient, chatPubSub, eventStreamMaxLen, eventStreamTTL)
+	go eventStream.Run(context.Background())
+
+	eventModule := event.NewModule(
+		logger,
+		storage.Database(),
+		userProvider,
+		eventStream,
+		notificationModule.Provider,
+		chat.NewChatProvider(chatPubSub),
+	)
+	eventProvider := event.NewEventProvider(eventModule.Service)
+
+	chatModule := chat.NewModule(logger, storage.Database(), chatPubSub, eventStream, eventProvider, userProvider, notificationModule.Provider)
+	go chatModule.Hub.Run(context.Background())
+	go chatModule.ConversationHub.Run(context.Background())
+
+	blobStore, err := initBlobStore(&cfg.Media)
+	if err != nil {
+		logger.Error("failed to initialize blob store", "storage", cfg.Media.Storage, "error", err)
+		return
+	}
+	adminModule := admin.NewModule(logger, rabbitmqClient)
+	mediaModule := media.NewModule(logger, blobStore, cfg.Media.PublicURL, cfg.Media.MaxUploadSize, cfg.Media.PresignTTL, userProvider, eventProvider)
 	logger.Info("Init modules successfully")
 
-	var mailService *mail_services.MailService
-	if rabbitmqClient != nil {
-		mailService = mail_services.NewMailService(
+	// mailDone закрывается, когда обработчик писем остановился и дописал начатые письма.
+	mailDone := make(chan struct{})
+	switch {
+	case cfg.Mail.Standalone:
+		close(mailDone)
+		logger.Info("mail service runs standalone - emails are sent by cmd/mailer")
+	case rabbitmqClient != nil:
+		mailService, err := mail_services.NewMailService(
 			logger,
 			rabbitmqClient,
 			&cfg.SMTP,
+			&cfg.Mail,
+			notificationModule.PreferenceProvider,
+			cfg.Notifications.UnsubscribeURL,
 		)
+		if err != nil {
+			logger.Error("failed to initialize mail service", "error", err)
+			return
+		}
 
 		go func() {
+			defer close(mailDone)
+
 			logger.Info("starting mail service consumer")
-			if err := mailService.StartConsumer(context.Background()); err != nil {
+			if err := mailService.StartConsumer(ctx); err != nil {
 				logger.Error("mail service consumer failed", "error", err)
 			}
 		}()
-	} else {
+	default:
+		close(mailDone)
 		logger.Warn("mail service not started - RabbitMQ not available")
 	}
 	logger.Info("Init mail service successfully")
 
+	if rabbitmqClient != nil {
+		outboxRelay := outbox.NewRelay(logger, storage.Database(), rabbitmqClient)
+		outboxScheduler := scheduler.New(
+			logger,
+			"outbox_relay",
+			scheduler.NewLock(redisClient, "scheduler:outbox_relay:leader", 3*outboxRelayInterval),
+			outboxRelayInterval,
+			outboxRelay.Run,
+		)
+
+		go outboxScheduler.Start(context.Background())
+	} else {
+		logger.Warn("outbox relay not started - RabbitMQ not available")
+	}
+
+	reminderScheduler := scheduler.New(
+		logger,
+		"event_reminders",
+		scheduler.NewLock(redisClient, "scheduler:event_reminders:leader", 3*reminderInterval),
+		reminderInterval,
+		eventModule.Service.SendDueReminders,
+	)
+
+	go reminderScheduler.Start(context.Background())
+
+	deferredEmailScheduler := scheduler.New(
+		logger,
+		"deferred_emails",
+		scheduler.NewLock(redisClient, "scheduler:deferred_emails:leader", 3*deferredEmailInterval),
+		deferredEmailInterval,
+		notificationModule.Service.SendDeferredEmails,
+	)
+
+	go deferredEmailScheduler.Start(context.Background())
+
 	router := chi.NewRouter()
 
 	router.Use(chi_middleware.RequestID)
 	router.Use(chi_middleware.RealIP)
-	router.Use(chi_middleware.Logger)
+	router.Use(middleware.Logger)
 	router.Use(chi_middleware.Recoverer)
-	router.Use(chi_middleware.Timeout(60 * time.Second))
+	router.Use(middleware.SkipForStreams(chi_middleware.Timeout(60 * time.Second)))
 
 	router.Route("/v1", func(r chi.Router) {
 		r.Route("/auth", func(r chi.Router) {
@@ -109,21 +245,125 @@ func main() {
 		r.Route("/users", func(r chi.Router) {
 			r.Use(middleware.AuthMiddleware(jwtHelper))
 
+			r.Post("/me/avatar", mediaModule.Handler.UploadAvatar)
+			r.Get("/me/blocks", userModule.Handler.GetBlockedUsers)
+			r.Get("/me/direct-messages", userModule.Handler.GetDirectMessageSettings)
+			r.Put("/me/direct-messages", userModule.Handler.UpdateDirectMessageSettings)
+
 			r.Get("/{id}", userModule.Handler.GetUserByID)
 			r.Put("/{id}", userModule.Handler.UpdateUser)
+			r.Post("/{id}/block", userModule.Handler.BlockUser)
+			r.Delete("/{id}/block", userModule.Handler.UnblockUser)
 		})
 
 		r.Route("/events", func(r chi.Router) {
 			r.Use(middleware.AuthMiddleware(jwtHelper))
 
+			r.Get("/{id}/participants", eventModule.Handler.GetParticipants)
 			r.Post("/{id}/participants", eventModule.Handler.AddParticipant)
-
+			r.Delete("/{id}/participants/me", eventModule.Handler.LeaveEvent)
+			r.Delete("/{id}/participants/{userID}", eventModule.Handler.RemoveParticipant)
+			r.Post("/{id}/participants/{userID}/ban", eventModule.Handler.BanParticipant)
+
+			r.Delete("/{id}/occurrences/{startsAt}", eventModule.Handler.CancelOccurrence)
+			r.Post("/{id}/occurrences/{startsAt}/participants", eventModule.Handler.JoinOccurrence)
+			r.Delete("/{id}/occurrences/{startsAt}/participants/me", eventModule.Handler.LeaveOccurrence)
+
+			r.Get("/{id}/invites", eventModule.Handler.GetInvites)
+			r.Post("/{id}/invites", eventModule.Handler.CreateInvite)
+			r.Delete("/{id}/invites/{inviteID}", eventModule.Handler.RevokeInvite)
+
+			r.Get("/{id}/requests", eventModule.Handler.GetJoinRequests)
+			r.Post("/{id}/requests/{requestID}/approve", eventModule.Handler.ApproveJoinRequest)
+			r.Post("/{id}/requests/{requestID}/reject", eventModule.Handler.RejectJoinRequest)
+
+			r.Get("/{id}/chat", chatModule.Handler.Connect)
+			r.Get("/{id}/messages", chatModule.Handler.GetMessages)
+			r.Post("/{id}/messages", chatModule.Handler.SendMessage)
+			r.Post("/{id}/messages/read", chatModule.Handler.MarkRead)
+			r.Patch("/{id}/messages/{messageID}", chatModule.Handler.EditMessage)
+			r.Delete("/{id}/messages/{messageID}", chatModule.Handler.DeleteMessage)
+			r.Get("/{id}/messages/{messageID}/edits", chatModule.Handler.GetMessageEdits)
+			r.Post("/{id}/messages/{messageID}/reactions", chatModule.Handler.AddReaction)
+			r.Delete("/{id}/messages/{messageID}/reactions/{emoji}", chatModule.Handler.RemoveReaction)
+
+			r.Get("/{id}/stream", eventModule.Handler.Stream)
+			r.Post("/{id}/cover", mediaModule.Handler.UploadEventCover)
+			r.Post("/{id}/cancel", eventModule.Handler.CancelEvent)
+
+			r.Get("/{id}.ics", eventModule.Handler.ExportEvent)
 			r.Get("/{id}", eventModule.Handler.GetEventWithDetails)
+			r.Patch("/{id}", eventModule.Handler.UpdateEvent)
 			r.Get("/", eventModule.Handler.GetShortEvents)
 			r.Post("/", eventModule.Handler.CreateEvent)
 
 			r.Get("/categories", eventModule.Handler.GetAllCategories)
 		})
+
+		r.With(middleware.AuthMiddleware(jwtHelper)).Get("/chats", chatModule.Handler.GetChats)
+
+		r.Route("/conversations", func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware(jwtHelper))
+
+			r.Get("/", chatModule.Handler.GetConversations)
+			r.Post("/", chatModule.Handler.StartConversation)
+			r.Get("/{id}/chat", chatModule.Handler.ConnectConversation)
+			r.Get("/{id}/messages", chatModule.Handler.GetConversationMessages)
+			r.Post("/{id}/messages", chatModule.Handler.SendDirectMessage)
+			r.Post("/{id}/messages/read", chatModule.Handler.MarkConversationRead)
+		})
+
+		r.Route("/notifications", func(r chi.Router) {
+			r.Get("/unsubscribe", notificationModule.Handler.UnsubscribePage)
+			r.Post("/unsubscribe", notificationModule.Handler.Unsubscribe)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(jwtHelper))
+
+				r.Get("/", notificationModule.Handler.GetNotifications)
+				r.Get("/unread-count", notificationModule.Handler.GetUnreadCount)
+				r.Post("/read-all", notificationModule.Handler.MarkAllRead)
+				r.Post("/{id}/read", notificationModule.Handler.MarkRead)
+				r.Get("/preferences", notificationModule.Handler.GetPreferences)
+				r.Put("/preferences", notificationModule.Handler.UpdatePreferences)
+			})
+		})
+
+		r.Route("/push", func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware(jwtHelper))
+
+			r.Get("/public-key", notificationModule.Handler.GetPushPublicKey)
+			r.Post("/subscriptions", notificationModule.Handler.SubscribePush)
+			r.Delete("/subscriptions", notificationModule.Handler.UnsubscribePush)
+		})
+
+		r.Get("/media/*", mediaModule.Handler.Serve)
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware(jwtHelper))
+			r.Use(middleware.AdminMiddleware(cfg.Admin.UserIDs))
+
+			r.Get("/email/dead-letters", adminModule.Handler.GetEmailDeadLetters)
+			r.Post("/email/dead-letters/replay", adminModule.Handler.ReplayEmailDeadLetters)
+		})
+
+		r.Route("/invites", func(r chi.Router) {
+			r.Use(middleware.AuthMiddleware(jwtHelper))
+
+			r.Get("/{token}", eventModule.Handler.GetInvitePreview)
+			r.Post("/{token}/accept", eventModule.Handler.AcceptInvite)
+		})
+
+		r.Route("/calendar", func(r chi.Router) {
+			r.Get("/{token}.ics", eventModule.Handler.ExportCalendarFeed)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(jwtHelper))
+
+				r.Get("/", eventModule.Handler.GetCalendarFeed)
+				r.Post("/rotate", eventModule.Handler.RotateCalendarFeed)
+			})
+		})
 	})
 
 	router.Get("/health/rabbitmq", func(w http.ResponseWriter, r *http.Request) {
@@ -132,15 +372,17 @@ func main() {
 			return
 		}
 
+		status, code := "healthy", http.StatusOK
 		if err := rabbitmqClient.HealthCheck(); err != nil {
-			boom.ServerUnavailable(w, "RabbitMQ connection failed")
-			return
+			status, code = "unhealthy", http.StatusServiceUnavailable
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "healthy",
-			"service": "rabbitmq",
+			"status":     status,
+			"service":    "rabbitmq",
+			"connection": rabbitmqClient.Status(),
 		})
 	})
 
@@ -152,10 +394,31 @@ func main() {
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
 	}
 
-	logger.Info("starting", "address", cfg.HTTPServer.Address)
-	if err := server.ListenAndServe(); err != nil {
-		logger.Error("server error: ", "error", err)
+	go func() {
+		logger.Info("starting", "address", cfg.HTTPServer.Address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server error: ", "error", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shutdown server", "error", err)
 	}
+
+	<-mailDone
+
+	if rabbitmqClient != nil {
+		rabbitmqClient.Close()
+	}
+
+	logger.Info("shutdown complete")
 }
 
 func initRedis(logger *slog.Logger, cfg *config.RedisConfig) *redis.Client {
@@ -174,12 +437,62 @@ func initRedis(logger *slog.Logger, cfg *config.RedisConfig) *redis.Client {
 	return rdb
 }
 
-func initRabbitMQ(logger *slog.Logger, cfg *config.RabbitMQConfig) *rabbitmq.Client {
+func initChatPubSub(logger *slog.Logger, cfg *config.ChatConfig, redisClient *redis.Client) pubsub.PubSub {
+	if cfg.PubSub == "memory" {
+		logger.Warn("chat and event stream use in-memory pubsub - updates will not reach other instances")
+		return pubsub.NewMemory()
+	}
+
+	return pubsub.NewRedis(redisClient)
+}
+
+func initBlobStore(cfg *config.MediaConfig) (blob.BlobStore, error) {
+	if cfg.Storage == "s3" {
+		return blob.NewS3(blob.S3Config{
+			Endpoint:       cfg.S3.Endpoint,
+			PublicEndpoint: cfg.S3.PublicEndpoint,
+			Region:         cfg.S3.Region,
+			Bucket:         cfg.S3.Bucket,
+			AccessKey:      cfg.S3.AccessKey,
+			SecretKey:      cfg.S3.SecretKey,
+			PathStyle:      cfg.S3.PathStyle,
+		}, nil)
+	}
+
+	return blob.NewFileSystem(cfg.FSRoot)
+}
+
+// initPushSender возвращает nil, если ключи VAPID не заданы.
+func initPushSender(cfg *config.PushConfig) (*webpush.Sender, error) {
+	if cfg.VAPIDPrivateKey == "" {
+		return nil, nil
+	}
+
+	return webpush.NewSender(webpush.VAPID{
+		PublicKey:  cfg.VAPIDPublicKey,
+		PrivateKey: cfg.VAPIDPrivateKey,
+		Subject:    cfg.Subject,
+	}, nil)
+}
+
+func initRabbitMQ(logger *slog.Logger, cfg *config.RabbitMQConfig, redisClient *redis.Client) *rabbitmq.Client {
 	var rabbitmqClient *rabbitmq.Client
 	var err error
 
 	if cfg.URL != "" {
-		rabbitmqClient, err = rabbitmq.NewClient(cfg.URL, logger)
+		rabbitmqClient, err = rabbitmq.NewClient(cfg.URL, logger, rabbitmq.Options{
+			EmailQueue: cfg.QueueName,
+			Exchange:   cfg.Exchange,
+			Retry: rabbitmq.RetryPolicy{
+				MaxRetries:   cfg.Retry.MaxRetries,
+				InitialDelay: cfg.Retry.InitialDelay,
+				MaxDelay:     cfg.Retry.MaxDelay,
+			},
+			ReconnectDelay:    cfg.ReconnectDelay,
+			MaxReconnectDelay: cfg.MaxReconnectDelay,
+			PublishBufferSize: cfg.PublishBufferSize,
+			Deduplicator:      dedup.NewRedis(redisClient, "dedup:", messageDedupTTL),
+		})
 		if err != nil {
 			logger.Error("failed to connect to RabbitMQ",
 				"error", err,
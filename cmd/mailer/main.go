@@ -0,0 +1,188 @@
+// Команда mailer отправляет письма из очереди RabbitMQ отдельно от API, чтобы
+// API и отправку писем можно было масштабировать независимо. Чтобы API не
+// читал очередь сам, включите mail.standalone (MAIL_STANDALONE=true).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata"
+
+	mail_services "github.com/RuLap/meetly-api/meetly/internal/app/mail/services"
+	"github.com/RuLap/meetly-api/meetly/internal/app/notification"
+	"github.com/RuLap/meetly-api/meetly/internal/app/user"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/config"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/dedup"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/logger"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
+	postgres "github.com/RuLap/meetly-api/meetly/internal/pkg/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// messageDedupTTL — сколько обработанные письма помнятся для отсечения
+// повторов. Совпадает со значением в cmd/api.
+const messageDedupTTL = 7 * 24 * time.Hour
+
+// shutdownTimeout ограничивает остановку HTTP-сервера.
+const shutdownTimeout = 5 * time.Second
+
+func main() {
+	cfg := config.MustLoad()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger := logger.New(logger.Config{
+		Level:   cfg.Env,
+		LokiURL: cfg.Log.LokiURL,
+		Labels:  cfg.Log.LokiLabels,
+	})
+
+	if cfg.RabbitMQ.URL == "" {
+		logger.Error("RabbitMQ URL not configured")
+		os.Exit(1)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Address,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		logger.Error("failed to connect to redis", "error", err)
+	}
+
+	rabbitmqClient, err := rabbitmq.NewClient(cfg.RabbitMQ.URL, logger, rabbitmq.Options{
+		EmailQueue: cfg.RabbitMQ.QueueName,
+		Exchange:   cfg.RabbitMQ.Exchange,
+		Retry: rabbitmq.RetryPolicy{
+			MaxRetries:   cfg.RabbitMQ.Retry.MaxRetries,
+			InitialDelay: cfg.RabbitMQ.Retry.InitialDelay,
+			MaxDelay:     cfg.RabbitMQ.Retry.MaxDelay,
+		},
+		ReconnectDelay:    cfg.RabbitMQ.ReconnectDelay,
+		MaxReconnectDelay: cfg.RabbitMQ.MaxReconnectDelay,
+		Deduplicator:      dedup.NewRedis(redisClient, "dedup:", messageDedupTTL),
+	})
+	if err != nil {
+		logger.Error("failed to connect to RabbitMQ", "error", err)
+		os.Exit(1)
+	}
+	defer rabbitmqClient.Close()
+
+	storage, err := postgres.InitDB(cfg.PostgresConnString)
+	if err != nil {
+		logger.Error("failed to initialize database", "error", err)
+		os.Exit(1)
+	}
+
+	// Письму нужны только настройки уведомлений получателя: push здесь не отправляется.
+	userModule := user.NewModule(logger, storage.Database())
+	notificationModule := notification.NewModule(
+		logger,
+		storage.Database(),
+		user.NewUserProvider(userModule.Service),
+		nil,
+		0,
+		false,
+	)
+
+	mailService, err := mail_services.NewMailService(
+		logger,
+		rabbitmqClient,
+		&cfg.SMTP,
+		&cfg.Mail,
+		notificationModule.PreferenceProvider,
+		cfg.Notifications.UnsubscribeURL,
+	)
+	if err != nil {
+		logger.Error("failed to initialize mail service", "error", err)
+		os.Exit(1)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/health", healthHandler(rabbitmqClient))
+	router.Get("/metrics", metricsHandler(mailService, rabbitmqClient))
+
+	server := &http.Server{
+		Addr:              cfg.Mail.HealthAddress,
+		Handler:           router,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		logger.Info("starting health server", "address", cfg.Mail.HealthAddress)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("health server error", "error", err)
+			stop()
+		}
+	}()
+
+	logger.Info("starting mail service consumer")
+	if err := mailService.StartConsumer(ctx); err != nil {
+		logger.Error("mail service consumer failed", "error", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shutdown health server", "error", err)
+	}
+
+	logger.Info("mailer stopped")
+}
+
+func healthHandler(rabbitmqClient *rabbitmq.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, code := "healthy", http.StatusOK
+		if err := rabbitmqClient.HealthCheck(); err != nil {
+			status, code = "unhealthy", http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     status,
+			"service":    "mailer",
+			"connection": rabbitmqClient.Status(),
+		})
+	}
+}
+
+// metricsHandler отдаёт счётчики в текстовом формате Prometheus.
+func metricsHandler(mailService *mail_services.MailService, rabbitmqClient *rabbitmq.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := mailService.Stats()
+		status := rabbitmqClient.Status()
+
+		connected := 0
+		if status.State == rabbitmq.StateConnected {
+			connected = 1
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		writeMetric(w, "meetly_mail_sent_total", "counter", "Emails accepted by the SMTP server.", stats.Sent)
+		writeMetric(w, "meetly_mail_skipped_total", "counter", "Emails skipped by recipient preferences.", stats.Skipped)
+		writeMetric(w, "meetly_mail_deferred_total", "counter", "Emails deferred until the end of quiet hours.", stats.Deferred)
+		writeMetric(w, "meetly_mail_failed_total", "counter", "Emails failed with a temporary error and scheduled for retry.", stats.Failed)
+		writeMetric(w, "meetly_mail_rejected_total", "counter", "Emails failed permanently and dead-lettered.", stats.Rejected)
+		writeMetric(w, "meetly_mail_in_flight", "gauge", "Emails being processed right now.", stats.InFlight)
+		writeMetric(w, "meetly_rabbitmq_connected", "gauge", "Whether the RabbitMQ connection is up.", connected)
+		writeMetric(w, "meetly_rabbitmq_reconnects_total", "counter", "RabbitMQ reconnects since start.", status.Reconnects)
+	}
+}
+
+func writeMetric(w io.Writer, name, typ, help string, value any) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, typ, name, value)
+}
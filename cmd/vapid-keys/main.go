@@ -0,0 +1,21 @@
+// Команда vapid-keys генерирует пару ключей VAPID для push-уведомлений.
+// Вывод подходит для .env: VAPID_PUBLIC_KEY и VAPID_PRIVATE_KEY.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush"
+)
+
+func main() {
+	publicKey, privateKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to generate VAPID keys:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\n", publicKey)
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n", privateKey)
+}
@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type GetDeadLettersResponse struct {
+	Total    int                     `json:"total"`
+	Messages []GetDeadLetterResponse `json:"messages"`
+}
+
+type GetDeadLetterResponse struct {
+	MessageID      string     `json:"message_id"`
+	Queue          string     `json:"queue,omitempty"`
+	Retries        int        `json:"retries"`
+	LastError      string     `json:"last_error,omitempty"`
+	FirstFailureAt *time.Time `json:"first_failure_at,omitempty"`
+	DeadLetteredAt *time.Time `json:"dead_lettered_at,omitempty"`
+	// Payload — исходное событие. Если тело не JSON, передаётся строкой.
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ReplayDeadLettersRequest — какие письма вернуть в очередь отправки.
+// Пустой список означает все недоставленные письма.
+type ReplayDeadLettersRequest struct {
+	MessageIDs []string `json:"message_ids" validate:"omitempty,max=1000,dive,required,max=64"`
+}
+
+type ReplayDeadLettersResponse struct {
+	Replayed int `json:"replayed"`
+}
@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	validation "github.com/RuLap/meetly-api/meetly/internal/pkg/validator"
+	"github.com/darahayes/go-boom"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetEmailDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		boom.BadRequest(w, err.Error())
+		return
+	}
+
+	result, err := h.service.GetEmailDeadLetters(r.Context(), limit)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) ReplayEmailDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var req ReplayDeadLettersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	result, err := h.service.ReplayEmailDeadLetters(r.Context(), &req)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func parseLimit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, fmt.Errorf("limit должен быть числом от 1 до %d", maxPageLimit)
+	}
+
+	return limit, nil
+}
+
+func (h *Handler) sendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrQueueUnavailable):
+		boom.ServerUnavailable(w, err.Error())
+	default:
+		boom.Internal(w, err)
+	}
+}
+
+func (h *Handler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"encoding/json"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
+)
+
+func DeadLettersToGetResponse(letters *rabbitmq.DeadLetters) *GetDeadLettersResponse {
+	result := &GetDeadLettersResponse{
+		Total:    letters.Total,
+		Messages: make([]GetDeadLetterResponse, 0, len(letters.Messages)),
+	}
+
+	for _, letter := range letters.Messages {
+		result.Messages = append(result.Messages, GetDeadLetterResponse{
+			MessageID:      letter.MessageID,
+			Queue:          letter.Queue,
+			Retries:        letter.Retries,
+			LastError:      letter.LastError,
+			FirstFailureAt: letter.FirstFailureAt,
+			DeadLetteredAt: letter.DeadLetteredAt,
+			Payload:        bodyToPayload(letter.Body),
+		})
+	}
+
+	return result
+}
+
+func bodyToPayload(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return body
+	}
+
+	payload, _ := json.Marshal(string(body))
+	return payload
+}
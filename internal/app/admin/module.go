@@ -0,0 +1,22 @@
+package admin
+
+import (
+	"log/slog"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
+)
+
+type Module struct {
+	Service Service
+	Handler *Handler
+}
+
+func NewModule(log *slog.Logger, rabbitmq *rabbitmq.Client) *Module {
+	service := NewService(log, rabbitmq)
+	handler := NewHandler(service)
+
+	return &Module{
+		Service: service,
+		Handler: handler,
+	}
+}
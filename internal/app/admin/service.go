@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
+)
+
+type Service interface {
+	GetEmailDeadLetters(ctx context.Context, limit int) (*GetDeadLettersResponse, error)
+	ReplayEmailDeadLetters(ctx context.Context, req *ReplayDeadLettersRequest) (*ReplayDeadLettersResponse, error)
+}
+
+var ErrQueueUnavailable = errors.New("очередь сообщений недоступна")
+
+type service struct {
+	log      *slog.Logger
+	rabbitmq *rabbitmq.Client
+}
+
+func NewService(log *slog.Logger, rabbitmq *rabbitmq.Client) Service {
+	return &service{
+		log:      log,
+		rabbitmq: rabbitmq,
+	}
+}
+
+func (s *service) GetEmailDeadLetters(ctx context.Context, limit int) (*GetDeadLettersResponse, error) {
+	if s.rabbitmq == nil {
+		return nil, ErrQueueUnavailable
+	}
+
+	letters, err := s.rabbitmq.PeekEmailDeadLetters(ctx, limit)
+	if err != nil {
+		s.log.Error("failed to get email dead letters", "error", err)
+		return nil, err
+	}
+
+	return DeadLettersToGetResponse(letters), nil
+}
+
+func (s *service) ReplayEmailDeadLetters(ctx context.Context, req *ReplayDeadLettersRequest) (*ReplayDeadLettersResponse, error) {
+	if s.rabbitmq == nil {
+		return nil, ErrQueueUnavailable
+	}
+
+	replayed, err := s.rabbitmq.ReplayEmailDeadLetters(ctx, req.MessageIDs)
+	if err != nil {
+		s.log.Error("failed to replay email dead letters", "replayed", replayed, "error", err)
+		return nil, err
+	}
+
+	s.log.Info("email dead letters replayed", "replayed", replayed, "requested", len(req.MessageIDs))
+	return &ReplayDeadLettersResponse{Replayed: replayed}, nil
+}
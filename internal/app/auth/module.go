@@ -5,7 +5,7 @@ import (
 
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/config"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/jwt_helper"
-	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/outbox"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
@@ -22,7 +22,7 @@ func NewModule(
 	jwtHelper *jwt_helper.JWTHelper,
 	googleCfg *config.GoogleOAuth,
 	redis *redis.Client,
-	rabbitmq *rabbitmq.Client,
+	outbox *outbox.Outbox,
 ) *Module {
 	googleConfig := &GoogleOAuthConfig{
 		ClientID:     googleCfg.ClientID,
@@ -31,7 +31,7 @@ func NewModule(
 	}
 
 	repo := NewRepository(pool)
-	service := NewService(log, jwtHelper, googleConfig, redis, rabbitmq, repo)
+	service := NewService(log, jwtHelper, googleConfig, redis, outbox, repo)
 	handler := NewHandler(service)
 
 	return &Module{
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/outbox"
 	"github.com/jackc/pgx"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -16,7 +17,7 @@ var (
 )
 
 type Repository interface {
-	CreateUser(ctx context.Context, user *User) (*string, error)
+	CreateUser(ctx context.Context, user *User, after func(ctx context.Context, tx outbox.DB, userID string) error) (*string, error)
 	MakeEmailConfirmed(ctx context.Context, userID string) error
 	GetByEmailProvider(ctx context.Context, email string, provider Provider) (*User, error)
 	GetPasswordHashByEmail(ctx context.Context, email string) (*string, error)
@@ -31,15 +32,24 @@ func NewRepository(pool *pgxpool.Pool) Repository {
 	return &repository{pool}
 }
 
-func (r *repository) CreateUser(ctx context.Context, user *User) (*string, error) {
+// CreateUser создаёт пользователя. after вызывается с ID нового пользователя
+// в той же транзакции, перед фиксацией: через него сервис записывает в outbox
+// событие о регистрации.
+func (r *repository) CreateUser(ctx context.Context, user *User, after func(ctx context.Context, tx outbox.DB, userID string) error) (*string, error) {
 	query := `
 		INSERT INTO users (email, provider, provider_id, password)
 		VALUES ($1, $2, $3, $4)
 		RETURNING id
 	`
 
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	var userID string
-	err := r.pool.QueryRow(
+	err = tx.QueryRow(
 		ctx,
 		query,
 		user.Email,
@@ -55,6 +65,14 @@ func (r *repository) CreateUser(ctx context.Context, user *User) (*string, error
 		return nil, fmt.Errorf("не удалось создать пользователя: %w", err)
 	}
 
+	if err := after(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось создать пользователя: %w", err)
+	}
+
 	return &userID, nil
 }
 
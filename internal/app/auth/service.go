@@ -12,9 +12,10 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/bus"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/jwt_helper"
-	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/outbox"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
@@ -43,7 +44,7 @@ type service struct {
 	jwtHelper    *jwt_helper.JWTHelper
 	googleConfig *GoogleOAuthConfig
 	redis        *redis.Client
-	rabbitmq     *rabbitmq.Client
+	outbox       *outbox.Outbox
 	repo         Repository
 }
 
@@ -52,7 +53,7 @@ func NewService(
 	jwtHelper *jwt_helper.JWTHelper,
 	googleConfig *GoogleOAuthConfig,
 	redis *redis.Client,
-	rabbitmq *rabbitmq.Client,
+	outbox *outbox.Outbox,
 	repo Repository,
 ) Service {
 	return &service{
@@ -60,7 +61,7 @@ func NewService(
 		jwtHelper:    jwtHelper,
 		googleConfig: googleConfig,
 		redis:        redis,
-		rabbitmq:     rabbitmq,
+		outbox:       outbox,
 		repo:         repo,
 	}
 }
@@ -86,20 +87,21 @@ func (s *service) SendConfirmationLink(ctx context.Context, req *SendConfirmatio
 
 	confirmationURL := fmt.Sprintf("https://meetlyplus.ru/confirm?token=%s", token)
 
-	if s.rabbitmq != nil {
-		event := events.EmailEvent{
-			To:       req.Email,
-			Template: "email_confirmation",
-			Subject:  "Подтвердите ваш email",
-			Data: map[string]interface{}{
-				"confirmation_url": confirmationURL,
-				"user_email":       req.Email,
-			},
-		}
+	event := events.EmailEvent{
+		To:       req.Email,
+		Template: "email_confirmation",
+		Subject:  "Подтвердите ваш email",
+		Data: map[string]interface{}{
+			"confirmation_url": confirmationURL,
+			"user_email":       req.Email,
+		},
+	}
 
-		if err := s.rabbitmq.PublishEmailEvent(event); err != nil {
-			s.log.Error("failed to publish email event", "error", err)
-		}
+	// Токен уже сохранён: без записи письма в outbox пользователь не
+	// получил бы ссылку, поэтому ошибка возвращается.
+	if err := s.outbox.AddEmail(ctx, &event); err != nil {
+		s.log.Error("failed to enqueue confirmation email", "error", err, "user_id", req.UserID)
+		return fmt.Errorf("не удалось отправить письмо")
 	}
 
 	s.log.Info("confirmation link sent", "email", req.Email, "user_id", req.UserID)
@@ -163,7 +165,7 @@ func (s *service) Register(ctx context.Context, req RegisterRequest) (*AuthRespo
 
 	user := RegisterRequestToUser(&req, hashedPasswordStr)
 
-	userID, err := s.repo.CreateUser(ctx, user)
+	userID, err := s.repo.CreateUser(ctx, user, s.addRegistered(user.Email, LocalProvider))
 	if err != nil {
 		s.log.Error("failed to create user", "error", err, "email", user.Email)
 		return nil, err
@@ -264,7 +266,7 @@ func (s *service) GoogleAuth(ctx context.Context, req GoogleAuthRequest) (*AuthR
 		EmailConfirmed: true,
 	}
 
-	userID, err := s.repo.CreateUser(ctx, user)
+	userID, err := s.repo.CreateUser(ctx, user, s.addRegistered(user.Email, GoogleProvider))
 	if err != nil {
 		s.log.Error("failed to create google user", "error", err, "email", userInfo.Email)
 		return nil, err
@@ -465,3 +467,30 @@ func generateState() (string, error) {
 	}
 	return fmt.Sprintf("%x", b), nil
 }
+
+// addRegistered записывает событие о регистрации пользователя в транзакции
+// его создания. Ошибка откатывает создание пользователя.
+func (s *service) addRegistered(email string, provider Provider) func(ctx context.Context, tx outbox.DB, userID string) error {
+	return func(ctx context.Context, tx outbox.DB, userID string) error {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			s.log.Error("failed to parse user id", "error", err, "user_id", userID)
+			return err
+		}
+
+		envelope, err := bus.NewEnvelope(events.UserRegistered{
+			UserID:   id,
+			Email:    email,
+			Provider: string(provider),
+		})
+		if err == nil {
+			err = outbox.AddEvent(ctx, tx, envelope)
+		}
+		if err != nil {
+			s.log.Error("failed to add user registered event", "error", err, "user_id", userID)
+			return err
+		}
+
+		return nil
+	}
+}
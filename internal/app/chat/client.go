@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait — сколько ждать записи кадра в соединение.
+	writeWait = 10 * time.Second
+	// pongWait — сколько ждать pong от клиента, прежде чем считать соединение разорванным.
+	pongWait = 60 * time.Second
+	// pingPeriod — как часто отправлять ping; должен быть меньше pongWait.
+	pingPeriod = pongWait * 9 / 10
+	// maxFrameSize — максимальный размер входящего кадра в байтах.
+	maxFrameSize = 16 * 1024
+	// sendBufferSize — сколько исходящих кадров может ждать отправки.
+	sendBufferSize = 64
+)
+
+// Client — WebSocket-соединение пользователя с комнатой: чатом события или личным диалогом.
+type Client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	roomID uuid.UUID
+	userID uuid.UUID
+
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewClient(hub *Hub, conn *websocket.Conn, roomID, userID uuid.UUID) *Client {
+	return &Client{
+		hub:    hub,
+		conn:   conn,
+		roomID: roomID,
+		userID: userID,
+		send:   make(chan []byte, sendBufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// Send ставит кадр в очередь на отправку этому клиенту.
+func (c *Client) Send(frame *OutgoingFrame) {
+	payload, err := json.Marshal(frame)
+	if err != nil {
+		c.hub.log.Error("failed to marshal chat frame", "type", frame.Type, "error", err)
+		return
+	}
+
+	if !c.enqueue(payload) {
+		c.close()
+	}
+}
+
+func (c *Client) enqueue(payload []byte) bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+	}
+
+	select {
+	case c.send <- payload:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// ReadPump читает кадры клиента и передаёт их handle, пока соединение открыто.
+// Отсутствие pong дольше pongWait считается разрывом соединения.
+func (c *Client) ReadPump(handle func(frame *IncomingFrame)) {
+	defer func() {
+		c.hub.Unregister(c)
+		c.close()
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxFrameSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var frame IncomingFrame
+		if err := c.conn.ReadJSON(&frame); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.Send(&OutgoingFrame{Type: FrameError, Error: "неверный формат кадра"})
+				continue
+			}
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				c.hub.log.Warn("chat connection closed unexpectedly", "room_id", c.roomID, "user_id", c.userID, "error", err)
+			}
+			return
+		}
+
+		handle(&frame)
+	}
+}
+
+// WritePump отправляет кадры из очереди и периодический ping.
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			// Кадры, поставленные в очередь перед закрытием (например,
+			// access.revoked), дописываются до кадра закрытия.
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			for len(c.send) > 0 {
+				if err := c.conn.WriteMessage(websocket.TextMessage, <-c.send); err != nil {
+					return
+				}
+			}
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+			return
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrConversationNotFound = errors.New("диалог не найден")
+
+type ConversationRepository interface {
+	GetOrCreate(ctx context.Context, userA, userB uuid.UUID) (*Conversation, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Conversation, error)
+	GetSummaries(ctx context.Context, userID uuid.UUID) ([]ConversationSummary, error)
+	CreateMessage(ctx context.Context, model *DirectMessage) (*DirectMessage, error)
+	GetMessageByID(ctx context.Context, conversationID, id uuid.UUID) (*DirectMessage, error)
+	GetRecentMessages(ctx context.Context, conversationID uuid.UUID, limit int) ([]DirectMessage, error)
+	GetMessagesPage(ctx context.Context, conversationID uuid.UUID, before *Cursor, limit int) ([]DirectMessage, error)
+	GetMessagesAfter(ctx context.Context, conversationID, afterID uuid.UUID, limit int) ([]DirectMessage, error)
+	MarkRead(ctx context.Context, mark *ReadMark) (bool, error)
+	GetReads(ctx context.Context, conversationID uuid.UUID) ([]ReadMark, error)
+}
+
+type conversationRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewConversationRepository(pool *pgxpool.Pool) ConversationRepository {
+	return &conversationRepository{pool}
+}
+
+const directMessageColumns = `m.id, m.conversation_id, m.user_id, m.text, m.created_at`
+
+// GetOrCreate возвращает диалог пары пользователей, создавая его при первом обращении.
+func (r *conversationRepository) GetOrCreate(ctx context.Context, userA, userB uuid.UUID) (*Conversation, error) {
+	low, high := userA, userB
+	if low.String() > high.String() {
+		low, high = high, low
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO conversations (user_low_id, user_high_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_low_id, user_high_id) DO NOTHING
+	`, low, high)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать диалог: %w", err)
+	}
+
+	query := `
+		SELECT id, user_low_id, user_high_id, created_at
+		FROM conversations
+		WHERE user_low_id = $1 AND user_high_id = $2
+	`
+
+	var conversation Conversation
+	err = r.pool.QueryRow(ctx, query, low, high).Scan(
+		&conversation.ID,
+		&conversation.UserLowID,
+		&conversation.UserHighID,
+		&conversation.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить диалог: %w", err)
+	}
+
+	return &conversation, nil
+}
+
+func (r *conversationRepository) GetByID(ctx context.Context, id uuid.UUID) (*Conversation, error) {
+	query := `
+		SELECT id, user_low_id, user_high_id, created_at
+		FROM conversations
+		WHERE id = $1
+	`
+
+	var conversation Conversation
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&conversation.ID,
+		&conversation.UserLowID,
+		&conversation.UserHighID,
+		&conversation.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, fmt.Errorf("не удалось получить диалог: %w", err)
+	}
+
+	return &conversation, nil
+}
+
+// GetSummaries возвращает диалоги пользователя с последним сообщением и числом
+// непрочитанных сообщений собеседника: сначала с самыми свежими сообщениями.
+func (r *conversationRepository) GetSummaries(ctx context.Context, userID uuid.UUID) ([]ConversationSummary, error) {
+	query := `
+		SELECT c.id, c.user_low_id, c.user_high_id, c.created_at, cr.message_id, unread.count,
+			last.id, last.user_id, last.text, last.created_at
+		FROM conversations c
+		LEFT JOIN conversation_reads cr ON cr.conversation_id = c.id AND cr.user_id = $1
+		LEFT JOIN LATERAL (
+			SELECT m.id, m.user_id, m.text, m.created_at
+			FROM direct_messages m
+			WHERE m.conversation_id = c.id
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT 1
+		) last ON TRUE
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS count
+			FROM direct_messages m
+			WHERE m.conversation_id = c.id
+				AND m.user_id <> $1
+				AND (cr.message_id IS NULL OR (m.created_at, m.id) > (cr.message_created_at, cr.message_id))
+		) unread
+		WHERE c.user_low_id = $1 OR c.user_high_id = $1
+		ORDER BY COALESCE(last.created_at, c.created_at) DESC, c.id
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить диалоги: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]ConversationSummary, 0)
+	for rows.Next() {
+		var summary ConversationSummary
+		var lastID, lastUserID *uuid.UUID
+		var lastText *string
+		var lastCreatedAt *time.Time
+		if err := rows.Scan(
+			&summary.ID,
+			&summary.UserLowID,
+			&summary.UserHighID,
+			&summary.CreatedAt,
+			&summary.LastRead,
+			&summary.UnreadCount,
+			&lastID,
+			&lastUserID,
+			&lastText,
+			&lastCreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("не удалось получить диалог: %w", err)
+		}
+
+		if lastID != nil {
+			summary.LastMessage = &DirectMessage{
+				ID:             *lastID,
+				ConversationID: summary.ID,
+				UserID:         *lastUserID,
+				Text:           *lastText,
+				CreatedAt:      *lastCreatedAt,
+			}
+		}
+
+		result = append(result, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить диалоги: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *conversationRepository) CreateMessage(ctx context.Context, model *DirectMessage) (*DirectMessage, error) {
+	query := `
+		INSERT INTO direct_messages (conversation_id, user_id, text)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, model.ConversationID, model.UserID, model.Text).Scan(
+		&model.ID,
+		&model.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить сообщение: %w", err)
+	}
+
+	return model, nil
+}
+
+func (r *conversationRepository) GetMessageByID(ctx context.Context, conversationID, id uuid.UUID) (*DirectMessage, error) {
+	query := `
+		SELECT ` + directMessageColumns + `
+		FROM direct_messages m
+		WHERE m.id = $1 AND m.conversation_id = $2
+	`
+
+	var message DirectMessage
+	if err := scanDirectMessage(r.pool.QueryRow(ctx, query, id, conversationID), &message); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("не удалось получить сообщение: %w", err)
+	}
+
+	return &message, nil
+}
+
+// GetRecentMessages возвращает последние limit сообщений диалога в хронологическом порядке.
+func (r *conversationRepository) GetRecentMessages(ctx context.Context, conversationID uuid.UUID, limit int) ([]DirectMessage, error) {
+	query := `
+		SELECT * FROM (
+			SELECT ` + directMessageColumns + `
+			FROM direct_messages m
+			WHERE m.conversation_id = $1
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT $2
+		) recent
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.pool.Query(ctx, query, conversationID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщения: %w", err)
+	}
+
+	return scanDirectMessages(rows)
+}
+
+// GetMessagesPage возвращает до limit сообщений, отправленных раньше курсора
+// before (или последних, если курсор не задан), от новых к старым.
+func (r *conversationRepository) GetMessagesPage(ctx context.Context, conversationID uuid.UUID, before *Cursor, limit int) ([]DirectMessage, error) {
+	var beforeCreatedAt *time.Time
+	var beforeID *uuid.UUID
+	if before != nil {
+		beforeCreatedAt = &before.CreatedAt
+		beforeID = &before.ID
+	}
+
+	query := `
+		SELECT ` + directMessageColumns + `
+		FROM direct_messages m
+		WHERE m.conversation_id = $1
+			AND ($2::timestamptz IS NULL OR (m.created_at, m.id) < ($2, $3))
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $4
+	`
+
+	rows, err := r.pool.Query(ctx, query, conversationID, beforeCreatedAt, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщения: %w", err)
+	}
+
+	return scanDirectMessages(rows)
+}
+
+// GetMessagesAfter возвращает до limit сообщений, отправленных после сообщения afterID.
+func (r *conversationRepository) GetMessagesAfter(ctx context.Context, conversationID, afterID uuid.UUID, limit int) ([]DirectMessage, error) {
+	after, err := r.GetMessageByID(ctx, conversationID, afterID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + directMessageColumns + `
+		FROM direct_messages m
+		WHERE m.conversation_id = $1
+			AND (m.created_at, m.id) > ($2, $3)
+		ORDER BY m.created_at, m.id
+		LIMIT $4
+	`
+
+	rows, err := r.pool.Query(ctx, query, conversationID, after.CreatedAt, after.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщения: %w", err)
+	}
+
+	return scanDirectMessages(rows)
+}
+
+// MarkRead сдвигает отметку прочтения вперёд. Возвращает false, если
+// пользователь уже прочитал это или более позднее сообщение.
+func (r *conversationRepository) MarkRead(ctx context.Context, mark *ReadMark) (bool, error) {
+	query := `
+		INSERT INTO conversation_reads (conversation_id, user_id, message_id, message_created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (conversation_id, user_id) DO UPDATE
+		SET message_id = EXCLUDED.message_id,
+			message_created_at = EXCLUDED.message_created_at,
+			read_at = NOW()
+		WHERE (EXCLUDED.message_created_at, EXCLUDED.message_id) > (conversation_reads.message_created_at, conversation_reads.message_id)
+		RETURNING read_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, mark.RoomID, mark.UserID, mark.MessageID, mark.MessageCreatedAt).Scan(&mark.ReadAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("не удалось отметить сообщения прочитанными: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *conversationRepository) GetReads(ctx context.Context, conversationID uuid.UUID) ([]ReadMark, error) {
+	query := `
+		SELECT conversation_id, user_id, message_id, message_created_at, read_at
+		FROM conversation_reads
+		WHERE conversation_id = $1
+	`
+
+	rows, err := r.pool.Query(ctx, query, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить отметки прочтения: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]ReadMark, 0)
+	for rows.Next() {
+		var mark ReadMark
+		if err := rows.Scan(&mark.RoomID, &mark.UserID, &mark.MessageID, &mark.MessageCreatedAt, &mark.ReadAt); err != nil {
+			return nil, fmt.Errorf("не удалось получить отметку прочтения: %w", err)
+		}
+
+		result = append(result, mark)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить отметки прочтения: %w", err)
+	}
+
+	return result, nil
+}
+
+func scanDirectMessage(row pgx.Row, message *DirectMessage) error {
+	return row.Scan(
+		&message.ID,
+		&message.ConversationID,
+		&message.UserID,
+		&message.Text,
+		&message.CreatedAt,
+	)
+}
+
+func scanDirectMessages(rows pgx.Rows) ([]DirectMessage, error) {
+	defer rows.Close()
+
+	result := make([]DirectMessage, 0)
+	for rows.Next() {
+		var message DirectMessage
+		if err := scanDirectMessage(rows, &message); err != nil {
+			return nil, fmt.Errorf("не удалось получить сообщение: %w", err)
+		}
+
+		result = append(result, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщения: %w", err)
+	}
+
+	return result, nil
+}
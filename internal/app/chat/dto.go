@@ -1 +1,165 @@
 package chat
+
+import "time"
+
+type GetMessageResponse struct {
+	ID             string                `json:"id"`
+	EventID        string                `json:"event_id,omitempty"`
+	ConversationID string                `json:"conversation_id,omitempty"`
+	UserID         string                `json:"user_id"`
+	Author         *GetAuthorResponse    `json:"author"`
+	Text           string                `json:"text"`
+	Reactions      []GetReactionResponse `json:"reactions"`
+	CreatedAt      time.Time             `json:"created_at"`
+	EditedAt       *time.Time            `json:"edited_at"`
+	Deleted        bool                  `json:"deleted"`
+	DeletedAt      *time.Time            `json:"deleted_at,omitempty"`
+}
+
+// GetReactionResponse — реакции одним эмодзи, в порядке их добавления.
+type GetReactionResponse struct {
+	Emoji   string   `json:"emoji"`
+	Count   int      `json:"count"`
+	UserIDs []string `json:"user_ids"`
+}
+
+// GetReactionChangeResponse описывает добавленную или снятую реакцию.
+type GetReactionChangeResponse struct {
+	MessageID string `json:"message_id"`
+	UserID    string `json:"user_id"`
+	Emoji     string `json:"emoji"`
+}
+
+// GetMessageEditsResponse — история правок для организатора. Edits идут от
+// исходного текста к последней заменённой версии; Text — текущий текст.
+type GetMessageEditsResponse struct {
+	MessageID string                   `json:"message_id"`
+	Text      string                   `json:"text"`
+	Deleted   bool                     `json:"deleted"`
+	Edits     []GetMessageEditResponse `json:"edits"`
+}
+
+type GetMessageEditResponse struct {
+	Text     string    `json:"text"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+type GetAuthorResponse struct {
+	ID        string `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	AvatarUrl string `json:"avatar_url"`
+}
+
+// GetMessagesPageResponse — страница истории в хронологическом порядке.
+// NextCursor передаётся в параметре before, чтобы получить более старые сообщения.
+type GetMessagesPageResponse struct {
+	Items      []GetMessageResponse `json:"items"`
+	NextCursor *string              `json:"next_cursor"`
+}
+
+// SendMessageRequest — новое сообщение. Mentions содержит ID упомянутых
+// участников: им приходит уведомление.
+type SendMessageRequest struct {
+	Text     string   `json:"text" validate:"required,max=4000"`
+	ClientID string   `json:"client_id,omitempty" validate:"omitempty,max=64"`
+	Mentions []string `json:"mentions,omitempty" validate:"omitempty,max=20,dive,uuid"`
+}
+
+type EditMessageRequest struct {
+	Text string `json:"text" validate:"required,max=4000"`
+}
+
+type MarkReadRequest struct {
+	MessageID string `json:"message_id" validate:"required,uuid"`
+}
+
+// GetReadReceiptResponse — до какого сообщения пользователь прочитал чат.
+type GetReadReceiptResponse struct {
+	UserID    string    `json:"user_id"`
+	MessageID string    `json:"message_id"`
+	ReadAt    time.Time `json:"read_at"`
+}
+
+// GetChatResponse — чат события в списке чатов пользователя.
+type GetChatResponse struct {
+	EventID           string                     `json:"event_id"`
+	Title             string                     `json:"title"`
+	StartsAt          *time.Time                 `json:"starts_at"`
+	Cancelled         bool                       `json:"cancelled"`
+	LastMessage       *GetMessagePreviewResponse `json:"last_message"`
+	UnreadCount       int                        `json:"unread_count"`
+	LastReadMessageID *string                    `json:"last_read_message_id"`
+}
+
+// GetConversationResponse — личный диалог в списке диалогов пользователя.
+type GetConversationResponse struct {
+	ID                string                     `json:"id"`
+	PeerID            string                     `json:"peer_id"`
+	Peer              *GetAuthorResponse         `json:"peer"`
+	LastMessage       *GetMessagePreviewResponse `json:"last_message"`
+	UnreadCount       int                        `json:"unread_count"`
+	LastReadMessageID *string                    `json:"last_read_message_id"`
+	CreatedAt         time.Time                  `json:"created_at"`
+}
+
+type StartConversationRequest struct {
+	UserID string `json:"user_id" validate:"required,uuid"`
+}
+
+type GetMessagePreviewResponse struct {
+	ID        string             `json:"id"`
+	Author    *GetAuthorResponse `json:"author"`
+	Text      string             `json:"text"`
+	CreatedAt time.Time          `json:"created_at"`
+	EditedAt  *time.Time         `json:"edited_at"`
+}
+
+type AddReactionRequest struct {
+	Emoji string `json:"emoji" validate:"required,max=32"`
+}
+
+// Типы кадров, которыми клиент и сервер обмениваются по WebSocket.
+const (
+	FrameHistory         = "history"
+	FrameMessageSend     = "message.send"
+	FrameMessageCreated  = "message.created"
+	FrameMessageEdit     = "message.edit"
+	FrameMessageEdited   = "message.edited"
+	FrameMessageDelete   = "message.delete"
+	FrameMessageDeleted  = "message.deleted"
+	FrameReactionAdd     = "reaction.add"
+	FrameReactionAdded   = "reaction.added"
+	FrameReactionRemove  = "reaction.remove"
+	FrameReactionRemoved = "reaction.removed"
+	FrameMessageRead     = "message.read"
+	FrameReadReceipt     = "read.receipt"
+	FramePing            = "ping"
+	FramePong            = "pong"
+	FrameError           = "error"
+	// FrameAccessRevoked получает пользователь, которого удалили из события
+	// или который его покинул, перед закрытием соединения.
+	FrameAccessRevoked = "access.revoked"
+)
+
+type IncomingFrame struct {
+	Type      string   `json:"type"`
+	MessageID string   `json:"message_id,omitempty"`
+	Text      string   `json:"text,omitempty"`
+	Emoji     string   `json:"emoji,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Mentions  []string `json:"mentions,omitempty"`
+}
+
+type OutgoingFrame struct {
+	Type     string                     `json:"type"`
+	Message  *GetMessageResponse        `json:"message,omitempty"`
+	Messages []GetMessageResponse       `json:"messages,omitempty"`
+	Reaction *GetReactionChangeResponse `json:"reaction,omitempty"`
+	Receipt  *GetReadReceiptResponse    `json:"receipt,omitempty"`
+	Receipts []GetReadReceiptResponse   `json:"receipts,omitempty"`
+	HasMore  bool                       `json:"has_more,omitempty"`
+	ClientID string                     `json:"client_id,omitempty"`
+	Error    string                     `json:"error,omitempty"`
+	UserID   string                     `json:"user_id,omitempty"`
+}
@@ -1 +1,761 @@
 package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	validation "github.com/RuLap/meetly-api/meetly/internal/pkg/validator"
+	"github.com/darahayes/go-boom"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+type Handler struct {
+	service         Service
+	hub             *Hub
+	conversationHub *Hub
+	upgrader        websocket.Upgrader
+}
+
+func NewHandler(service Service, hub *Hub, conversationHub *Hub) *Handler {
+	return &Handler{
+		service:         service,
+		hub:             hub,
+		conversationHub: conversationHub,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			// Аутентификация идёт по токену, а не по cookie, поэтому
+			// запрос с чужого origin не получит доступа к чату.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// Connect открывает WebSocket-соединение с чатом события. После подключения
+// клиент получает кадр history; при переподключении он передаёт параметр after
+// с ID последнего полученного сообщения, чтобы получить только пропущенные.
+func (h *Handler) Connect(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	after, ok := parseAfter(w, r)
+	if !ok {
+		return
+	}
+
+	// Соединение живёт дольше запроса, поэтому не наследует его отмену.
+	ctx := context.WithoutCancel(r.Context())
+
+	if err := h.service.CheckAccess(ctx, eventID, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	history, err := h.service.GetBackfill(ctx, eventID, after)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.serve(w, r, h.hub, eventID, userID, history, func(client *Client, frame *IncomingFrame) {
+		h.handleFrame(ctx, client, frame)
+	})
+}
+
+// ConnectConversation открывает WebSocket-соединение с личным диалогом.
+// Параметр after работает так же, как в Connect.
+func (h *Handler) ConnectConversation(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	after, ok := parseAfter(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := context.WithoutCancel(r.Context())
+
+	if _, err := h.service.CheckConversationAccess(ctx, conversationID, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	history, err := h.service.GetConversationBackfill(ctx, conversationID, after)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.serve(w, r, h.conversationHub, conversationID, userID, history, func(client *Client, frame *IncomingFrame) {
+		h.handleConversationFrame(ctx, client, frame)
+	})
+}
+
+// serve переключает соединение на WebSocket, отправляет историю и обрабатывает
+// кадры клиента, пока соединение открыто.
+func (h *Handler) serve(
+	w http.ResponseWriter,
+	r *http.Request,
+	hub *Hub,
+	roomID, userID uuid.UUID,
+	history *OutgoingFrame,
+	handle func(client *Client, frame *IncomingFrame),
+) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	client := NewClient(hub, conn, roomID, userID)
+	hub.Register(client)
+	go client.WritePump()
+
+	client.Send(history)
+	client.ReadPump(func(frame *IncomingFrame) {
+		handle(client, frame)
+	})
+}
+
+func (h *Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	var before *Cursor
+	if value := r.URL.Query().Get("before"); value != "" {
+		before, err = DecodeCursor(value)
+		if err != nil {
+			boom.BadRequest(w, err.Error())
+			return
+		}
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		boom.BadRequest(w, err.Error())
+		return
+	}
+
+	result, err := h.service.GetMessages(r.Context(), eventID, userID, before, limit)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+// SendMessage отправляет сообщение без WebSocket-соединения. Сообщение
+// рассылается подключённым участникам так же, как отправленное через сокет.
+func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	var req SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.SendMessage(r.Context(), eventID, userID, &req)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusCreated)
+}
+
+func (h *Handler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	eventID, messageID, ok := h.parseMessageParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.EditMessage(r.Context(), eventID, messageID, userID, &req)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	eventID, messageID, ok := h.parseMessageParams(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.DeleteMessage(r.Context(), eventID, messageID, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetMessageEdits(w http.ResponseWriter, r *http.Request) {
+	eventID, messageID, ok := h.parseMessageParams(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetMessageEdits(r.Context(), eventID, messageID, userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) AddReaction(w http.ResponseWriter, r *http.Request) {
+	eventID, messageID, ok := h.parseMessageParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req AddReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.AddReaction(r.Context(), eventID, messageID, userID, req.Emoji); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	eventID, messageID, ok := h.parseMessageParams(w, r)
+	if !ok {
+		return
+	}
+
+	emoji, err := url.PathUnescape(chi.URLParam(r, "emoji"))
+	if err != nil {
+		boom.BadRequest(w, ErrInvalidEmoji.Error())
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.RemoveReaction(r.Context(), eventID, messageID, userID, emoji); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	var req MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	messageID, err := uuid.Parse(req.MessageID)
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID сообщения")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.MarkRead(r.Context(), eventID, messageID, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetChats(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetChats(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetConversations(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetConversations(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+// StartConversation возвращает диалог с пользователем, создавая его при первом обращении.
+func (h *Handler) StartConversation(w http.ResponseWriter, r *http.Request) {
+	var req StartConversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	peerID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID пользователя")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.StartConversation(r.Context(), userID, peerID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetConversationMessages(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	var before *Cursor
+	if value := r.URL.Query().Get("before"); value != "" {
+		before, err = DecodeCursor(value)
+		if err != nil {
+			boom.BadRequest(w, err.Error())
+			return
+		}
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		boom.BadRequest(w, err.Error())
+		return
+	}
+
+	result, err := h.service.GetConversationMessages(r.Context(), conversationID, userID, before, limit)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) SendDirectMessage(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	var req SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.SendDirectMessage(r.Context(), conversationID, userID, &req)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusCreated)
+}
+
+func (h *Handler) MarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	var req MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	messageID, err := uuid.Parse(req.MessageID)
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID сообщения")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.MarkConversationRead(r.Context(), conversationID, messageID, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleConversationFrame(ctx context.Context, client *Client, frame *IncomingFrame) {
+	switch frame.Type {
+	case FramePing:
+		client.Send(&OutgoingFrame{Type: FramePong})
+	case FrameMessageSend:
+		req := SendMessageRequest{Text: frame.Text, ClientID: frame.ClientID}
+		if errors := validation.ValidateStruct(req); errors != nil {
+			client.Send(&OutgoingFrame{Type: FrameError, ClientID: frame.ClientID, Error: "Ошибки валидации"})
+			return
+		}
+
+		_, err := h.service.SendDirectMessage(ctx, client.roomID, client.userID, &req)
+		h.sendFrameError(client, frame, err)
+	case FrameMessageRead:
+		messageID, ok := h.parseFrameMessageID(client, frame)
+		if !ok {
+			return
+		}
+
+		h.sendFrameError(client, frame, h.service.MarkConversationRead(ctx, client.roomID, messageID, client.userID))
+	default:
+		client.Send(&OutgoingFrame{Type: FrameError, Error: "неизвестный тип кадра"})
+	}
+}
+
+func (h *Handler) handleFrame(ctx context.Context, client *Client, frame *IncomingFrame) {
+	switch frame.Type {
+	case FramePing:
+		client.Send(&OutgoingFrame{Type: FramePong})
+	case FrameMessageSend:
+		req := SendMessageRequest{Text: frame.Text, ClientID: frame.ClientID, Mentions: frame.Mentions}
+		if errors := validation.ValidateStruct(req); errors != nil {
+			client.Send(&OutgoingFrame{Type: FrameError, ClientID: frame.ClientID, Error: "Ошибки валидации"})
+			return
+		}
+
+		_, err := h.service.SendMessage(ctx, client.roomID, client.userID, &req)
+		h.sendFrameError(client, frame, err)
+	case FrameMessageEdit:
+		messageID, ok := h.parseFrameMessageID(client, frame)
+		if !ok {
+			return
+		}
+
+		req := EditMessageRequest{Text: frame.Text}
+		if errors := validation.ValidateStruct(req); errors != nil {
+			client.Send(&OutgoingFrame{Type: FrameError, ClientID: frame.ClientID, Error: "Ошибки валидации"})
+			return
+		}
+
+		_, err := h.service.EditMessage(ctx, client.roomID, messageID, client.userID, &req)
+		h.sendFrameError(client, frame, err)
+	case FrameMessageDelete:
+		messageID, ok := h.parseFrameMessageID(client, frame)
+		if !ok {
+			return
+		}
+
+		h.sendFrameError(client, frame, h.service.DeleteMessage(ctx, client.roomID, messageID, client.userID))
+	case FrameReactionAdd, FrameReactionRemove:
+		messageID, ok := h.parseFrameMessageID(client, frame)
+		if !ok {
+			return
+		}
+
+		var err error
+		if frame.Type == FrameReactionAdd {
+			err = h.service.AddReaction(ctx, client.roomID, messageID, client.userID, frame.Emoji)
+		} else {
+			err = h.service.RemoveReaction(ctx, client.roomID, messageID, client.userID, frame.Emoji)
+		}
+		h.sendFrameError(client, frame, err)
+	case FrameMessageRead:
+		messageID, ok := h.parseFrameMessageID(client, frame)
+		if !ok {
+			return
+		}
+
+		h.sendFrameError(client, frame, h.service.MarkRead(ctx, client.roomID, messageID, client.userID))
+	default:
+		client.Send(&OutgoingFrame{Type: FrameError, Error: "неизвестный тип кадра"})
+	}
+}
+
+func (h *Handler) parseFrameMessageID(client *Client, frame *IncomingFrame) (uuid.UUID, bool) {
+	messageID, err := uuid.Parse(frame.MessageID)
+	if err != nil {
+		client.Send(&OutgoingFrame{Type: FrameError, ClientID: frame.ClientID, Error: "неверный формат ID сообщения"})
+		return uuid.Nil, false
+	}
+
+	return messageID, true
+}
+
+// sendFrameError сообщает клиенту об ошибке обработки кадра. Текст внутренних
+// ошибок не раскрывается.
+func (h *Handler) sendFrameError(client *Client, frame *IncomingFrame, err error) {
+	if err == nil {
+		return
+	}
+
+	message := "не удалось выполнить действие"
+	for _, known := range []error{
+		ErrNotParticipant, ErrEmptyMessage, ErrMessageNotFound, ErrMessageDeleted,
+		ErrNotAuthor, ErrCannotDelete, ErrInvalidEmoji, ErrUserBlocked, ErrDirectMessagesNotAllowed,
+	} {
+		if errors.Is(err, known) {
+			message = err.Error()
+			break
+		}
+	}
+
+	client.Send(&OutgoingFrame{Type: FrameError, ClientID: frame.ClientID, Error: message})
+}
+
+func (h *Handler) parseMessageParams(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "messageID"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID сообщения")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return eventID, messageID, true
+}
+
+func parseAfter(w http.ResponseWriter, r *http.Request) (*uuid.UUID, bool) {
+	value := r.URL.Query().Get("after")
+	if value == "" {
+		return nil, true
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		boom.BadRequest(w, "неверный формат after")
+		return nil, false
+	}
+
+	return &id, true
+}
+
+func parseLimit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, fmt.Errorf("limit должен быть числом от 1 до %d", maxPageLimit)
+	}
+
+	return limit, nil
+}
+
+func (h *Handler) sendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotParticipant),
+		errors.Is(err, ErrNotAuthor),
+		errors.Is(err, ErrCannotDelete),
+		errors.Is(err, ErrNotOrganizer),
+		errors.Is(err, ErrUserBlocked),
+		errors.Is(err, ErrDirectMessagesNotAllowed):
+		boom.Forbidden(w, err.Error())
+	case errors.Is(err, ErrEmptyMessage),
+		errors.Is(err, ErrInvalidCursor),
+		errors.Is(err, ErrInvalidEmoji),
+		errors.Is(err, ErrCannotMessageSelf):
+		boom.BadRequest(w, err.Error())
+	case errors.Is(err, ErrMessageDeleted):
+		boom.ResourceGone(w, err.Error())
+	case errors.Is(err, ErrMessageNotFound),
+		errors.Is(err, ErrConversationNotFound),
+		errors.Is(err, ErrRecipientNotFound):
+		boom.NotFound(w, err.Error())
+	default:
+		boom.Internal(w, err)
+	}
+}
+
+func currentUserID(r *http.Request) (uuid.UUID, error) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user_id not found in context")
+	}
+
+	return uuid.Parse(userID)
+}
+
+func (h *Handler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
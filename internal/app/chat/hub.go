@@ -0,0 +1,172 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/pubsub"
+	"github.com/google/uuid"
+)
+
+// Префиксы каналов pub/sub: по одному каналу на комнату.
+const (
+	eventChannelPrefix        = "chat:event:"
+	conversationChannelPrefix = "chat:conversation:"
+)
+
+// resubscribeDelay — пауза перед повторной подпиской после ошибки.
+const resubscribeDelay = 5 * time.Second
+
+// Hub хранит открытые соединения, сгруппированные по комнатам: чатам событий
+// или личным диалогам. Кадры рассылаются через pub/sub с префиксом канала prefix,
+// поэтому доходят и до соединений, открытых на других экземплярах.
+type Hub struct {
+	log    *slog.Logger
+	pubsub pubsub.PubSub
+	prefix string
+	mu     sync.RWMutex
+	rooms  map[uuid.UUID]map[*Client]struct{}
+}
+
+func NewHub(log *slog.Logger, pubsub pubsub.PubSub, prefix string) *Hub {
+	return &Hub{
+		log:    log,
+		pubsub: pubsub,
+		prefix: prefix,
+		rooms:  make(map[uuid.UUID]map[*Client]struct{}),
+	}
+}
+
+// Run получает кадры из pub/sub и раздаёт их локальным соединениям.
+// Если подписаться не удалось, попытка повторяется. Блокирует выполнение до отмены ctx.
+func (h *Hub) Run(ctx context.Context) {
+	h.log.Info("starting chat hub subscription", "prefix", h.prefix)
+
+	for {
+		err := h.pubsub.Subscribe(ctx, h.prefix, func(msg *pubsub.Message) {
+			roomID, err := uuid.Parse(strings.TrimPrefix(msg.Channel, h.prefix))
+			if err != nil {
+				h.log.Warn("unexpected chat channel", "channel", msg.Channel)
+				return
+			}
+
+			h.deliver(roomID, msg.Payload)
+		})
+		if err != nil {
+			h.log.Error("chat hub subscription failed", "prefix", h.prefix, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			h.log.Info("chat hub stopped", "prefix", h.prefix)
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+func (h *Hub) Register(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[client.roomID]
+	if !ok {
+		room = make(map[*Client]struct{})
+		h.rooms[client.roomID] = room
+	}
+	room[client] = struct{}{}
+}
+
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[client.roomID]
+	if !ok {
+		return
+	}
+
+	delete(room, client)
+	if len(room) == 0 {
+		delete(h.rooms, client.roomID)
+	}
+}
+
+// Broadcast публикует кадр для всех соединений комнаты на всех экземплярах.
+// Если pub/sub недоступен, кадр получат хотя бы соединения этого экземпляра.
+func (h *Hub) Broadcast(ctx context.Context, roomID uuid.UUID, frame *OutgoingFrame) {
+	payload, err := json.Marshal(frame)
+	if err != nil {
+		h.log.Error("failed to marshal chat frame", "room_id", roomID, "type", frame.Type, "error", err)
+		return
+	}
+
+	if err := h.pubsub.Publish(ctx, h.prefix+roomID.String(), payload); err != nil {
+		h.log.Error("failed to publish chat frame, delivering locally", "room_id", roomID, "type", frame.Type, "error", err)
+		h.deliver(roomID, payload)
+	}
+}
+
+// deliver отправляет кадр локальным соединениям комнаты. Клиент, который не
+// успевает читать и переполнил буфер, отключается и должен переподключиться.
+func (h *Hub) deliver(roomID uuid.UUID, payload []byte) {
+	if userID, ok := revokedUserID(payload); ok {
+		h.revoke(roomID, userID, payload)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.rooms[roomID] {
+		if !client.enqueue(payload) {
+			h.log.Warn("dropping slow chat client", "room_id", roomID, "user_id", client.userID)
+			client.close()
+		}
+	}
+}
+
+// revoke отправляет кадр access.revoked соединениям пользователя в комнате
+// и закрывает их. Остальным участникам кадр не пересылается.
+func (h *Hub) revoke(roomID, userID uuid.UUID, payload []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.rooms[roomID] {
+		if client.userID != userID {
+			continue
+		}
+
+		h.log.Info("revoking chat access", "room_id", roomID, "user_id", userID)
+		client.enqueue(payload)
+		client.close()
+	}
+}
+
+// revokedUserID возвращает пользователя из кадра access.revoked. Остальные
+// кадры разбираются, только если содержат имя этого типа.
+func revokedUserID(payload []byte) (uuid.UUID, bool) {
+	if !bytes.Contains(payload, []byte(FrameAccessRevoked)) {
+		return uuid.Nil, false
+	}
+
+	var frame struct {
+		Type   string `json:"type"`
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(payload, &frame); err != nil || frame.Type != FrameAccessRevoked {
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(frame.UserID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
@@ -1 +1,243 @@
 package chat
+
+import (
+	"strings"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/google/uuid"
+)
+
+// MessageToGetResponse скрывает текст и реакции удалённого сообщения:
+// участники видят только, что оно было удалено.
+func MessageToGetResponse(model *Message, author *GetAuthorResponse, reactions []Reaction) *GetMessageResponse {
+	result := &GetMessageResponse{
+		ID:        model.ID.String(),
+		EventID:   model.EventID.String(),
+		UserID:    model.UserID.String(),
+		Author:    author,
+		Text:      model.Text,
+		Reactions: ReactionsToGetResponse(reactions),
+		CreatedAt: model.CreatedAt,
+		EditedAt:  model.EditedAt,
+		Deleted:   model.DeletedAt != nil,
+		DeletedAt: model.DeletedAt,
+	}
+
+	if result.Deleted {
+		result.Text = ""
+		result.Reactions = []GetReactionResponse{}
+	}
+
+	return result
+}
+
+// MessagesToGetResponse сопоставляет сообщения с авторами и реакциями. Если автор
+// не найден (например, удалён), поле author остаётся пустым.
+func MessagesToGetResponse(models []Message, authors map[string]providers.UserInfo, reactions map[uuid.UUID][]Reaction) []GetMessageResponse {
+	result := make([]GetMessageResponse, 0, len(models))
+	for _, model := range models {
+		var author *GetAuthorResponse
+		if user, ok := authors[model.UserID.String()]; ok {
+			author = UserInfoToGetAuthorResponse(&user)
+		}
+
+		result = append(result, *MessageToGetResponse(&model, author, reactions[model.ID]))
+	}
+
+	return result
+}
+
+// ReactionsToGetResponse группирует реакции по эмодзи в порядке первого появления.
+func ReactionsToGetResponse(models []Reaction) []GetReactionResponse {
+	result := make([]GetReactionResponse, 0)
+	index := make(map[string]int)
+	for _, model := range models {
+		i, ok := index[model.Emoji]
+		if !ok {
+			i = len(result)
+			index[model.Emoji] = i
+			result = append(result, GetReactionResponse{Emoji: model.Emoji, UserIDs: []string{}})
+		}
+
+		result[i].Count++
+		result[i].UserIDs = append(result[i].UserIDs, model.UserID.String())
+	}
+
+	return result
+}
+
+func ReactionToGetChangeResponse(model *Reaction) *GetReactionChangeResponse {
+	return &GetReactionChangeResponse{
+		MessageID: model.MessageID.String(),
+		UserID:    model.UserID.String(),
+		Emoji:     model.Emoji,
+	}
+}
+
+func MessageEditsToGetResponse(message *Message, edits []MessageEdit) *GetMessageEditsResponse {
+	result := &GetMessageEditsResponse{
+		MessageID: message.ID.String(),
+		Text:      message.Text,
+		Deleted:   message.DeletedAt != nil,
+		Edits:     make([]GetMessageEditResponse, 0, len(edits)),
+	}
+
+	for _, edit := range edits {
+		result.Edits = append(result.Edits, GetMessageEditResponse{
+			Text:     edit.Text,
+			EditedAt: edit.EditedAt,
+		})
+	}
+
+	return result
+}
+
+func ReadMarkToGetReceiptResponse(model *ReadMark) *GetReadReceiptResponse {
+	return &GetReadReceiptResponse{
+		UserID:    model.UserID.String(),
+		MessageID: model.MessageID.String(),
+		ReadAt:    model.ReadAt,
+	}
+}
+
+func ReadMarksToGetReceiptResponse(models []ReadMark) []GetReadReceiptResponse {
+	result := make([]GetReadReceiptResponse, 0, len(models))
+	for _, model := range models {
+		result = append(result, *ReadMarkToGetReceiptResponse(&model))
+	}
+
+	return result
+}
+
+func ChatSummaryToGetResponse(event *providers.EventInfo, summary *ChatSummary, authors map[string]providers.UserInfo) *GetChatResponse {
+	result := &GetChatResponse{
+		EventID:           event.ID.String(),
+		Title:             event.Title,
+		StartsAt:          event.StartsAt,
+		Cancelled:         event.CancelledAt != nil,
+		UnreadCount:       summary.UnreadCount,
+		LastReadMessageID: uuidToString(summary.LastRead),
+	}
+
+	if message := summary.LastMessage; message != nil {
+		result.LastMessage = MessageToGetPreviewResponse(message.ID, message.UserID, message.Text, message.CreatedAt, message.EditedAt, authors)
+	}
+
+	return result
+}
+
+func ConversationToGetResponse(model *Conversation, userID uuid.UUID, authors map[string]providers.UserInfo) *GetConversationResponse {
+	peerID := model.Peer(userID)
+
+	result := &GetConversationResponse{
+		ID:        model.ID.String(),
+		PeerID:    peerID.String(),
+		CreatedAt: model.CreatedAt,
+	}
+
+	if user, ok := authors[peerID.String()]; ok {
+		result.Peer = UserInfoToGetAuthorResponse(&user)
+	}
+
+	return result
+}
+
+func ConversationSummaryToGetResponse(summary *ConversationSummary, userID uuid.UUID, authors map[string]providers.UserInfo) *GetConversationResponse {
+	result := ConversationToGetResponse(&summary.Conversation, userID, authors)
+	result.UnreadCount = summary.UnreadCount
+	result.LastReadMessageID = uuidToString(summary.LastRead)
+
+	if message := summary.LastMessage; message != nil {
+		result.LastMessage = MessageToGetPreviewResponse(message.ID, message.UserID, message.Text, message.CreatedAt, nil, authors)
+	}
+
+	return result
+}
+
+// MessageToGetPreviewResponse обрезает текст сообщения до previewLength символов.
+func MessageToGetPreviewResponse(
+	id, userID uuid.UUID,
+	text string,
+	createdAt time.Time,
+	editedAt *time.Time,
+	authors map[string]providers.UserInfo,
+) *GetMessagePreviewResponse {
+	var author *GetAuthorResponse
+	if user, ok := authors[userID.String()]; ok {
+		author = UserInfoToGetAuthorResponse(&user)
+	}
+
+	runes := []rune(text)
+	if len(runes) > previewLength {
+		runes = append(runes[:previewLength], '…')
+	}
+
+	return &GetMessagePreviewResponse{
+		ID:        id.String(),
+		Author:    author,
+		Text:      string(runes),
+		CreatedAt: createdAt,
+		EditedAt:  editedAt,
+	}
+}
+
+func DirectMessageToGetResponse(model *DirectMessage, author *GetAuthorResponse) *GetMessageResponse {
+	return &GetMessageResponse{
+		ID:             model.ID.String(),
+		ConversationID: model.ConversationID.String(),
+		UserID:         model.UserID.String(),
+		Author:         author,
+		Text:           model.Text,
+		Reactions:      []GetReactionResponse{},
+		CreatedAt:      model.CreatedAt,
+	}
+}
+
+func DirectMessagesToGetResponse(models []DirectMessage, authors map[string]providers.UserInfo) []GetMessageResponse {
+	result := make([]GetMessageResponse, 0, len(models))
+	for _, model := range models {
+		var author *GetAuthorResponse
+		if user, ok := authors[model.UserID.String()]; ok {
+			author = UserInfoToGetAuthorResponse(&user)
+		}
+
+		result = append(result, *DirectMessageToGetResponse(&model, author))
+	}
+
+	return result
+}
+
+func SendMessageRequestToDirectMessage(dto *SendMessageRequest, conversationID, userID uuid.UUID) *DirectMessage {
+	return &DirectMessage{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Text:           strings.TrimSpace(dto.Text),
+	}
+}
+
+func uuidToString(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+
+	value := id.String()
+	return &value
+}
+
+func UserInfoToGetAuthorResponse(user *providers.UserInfo) *GetAuthorResponse {
+	return &GetAuthorResponse{
+		ID:        user.ID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		AvatarUrl: user.AvatarUrl,
+	}
+}
+
+func SendMessageRequestToModel(dto *SendMessageRequest, eventID, userID uuid.UUID) *Message {
+	return &Message{
+		EventID: eventID,
+		UserID:  userID,
+		Text:    strings.TrimSpace(dto.Text),
+	}
+}
@@ -1 +1,88 @@
 package chat
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Message struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    uuid.UUID  `db:"user_id"`
+	EventID   uuid.UUID  `db:"event_id"`
+	Text      string     `db:"text"`
+	CreatedAt time.Time  `db:"created_at"`
+	EditedAt  *time.Time `db:"edited_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+	DeletedBy *uuid.UUID `db:"deleted_by"`
+}
+
+// MessageEdit — версия текста сообщения, заменённая при редактировании в EditedAt.
+type MessageEdit struct {
+	ID        uuid.UUID `db:"id"`
+	MessageID uuid.UUID `db:"message_id"`
+	Text      string    `db:"text"`
+	EditedAt  time.Time `db:"edited_at"`
+}
+
+type Reaction struct {
+	MessageID uuid.UUID `db:"message_id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Emoji     string    `db:"emoji"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ReadMark — последнее прочитанное пользователем сообщение комнаты:
+// чата события или личного диалога.
+type ReadMark struct {
+	RoomID           uuid.UUID
+	UserID           uuid.UUID
+	MessageID        uuid.UUID
+	MessageCreatedAt time.Time
+	ReadAt           time.Time
+}
+
+// ChatSummary — состояние чата события для списка чатов пользователя.
+type ChatSummary struct {
+	EventID     uuid.UUID
+	LastMessage *Message
+	UnreadCount int
+	LastRead    *uuid.UUID
+}
+
+// Conversation — личный диалог двух пользователей. Собеседники хранятся
+// упорядоченно, чтобы у пары был только один диалог.
+type Conversation struct {
+	ID         uuid.UUID `db:"id"`
+	UserLowID  uuid.UUID `db:"user_low_id"`
+	UserHighID uuid.UUID `db:"user_high_id"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func (c *Conversation) HasMember(userID uuid.UUID) bool {
+	return c.UserLowID == userID || c.UserHighID == userID
+}
+
+// Peer возвращает собеседника пользователя userID.
+func (c *Conversation) Peer(userID uuid.UUID) uuid.UUID {
+	if c.UserLowID == userID {
+		return c.UserHighID
+	}
+	return c.UserLowID
+}
+
+type DirectMessage struct {
+	ID             uuid.UUID `db:"id"`
+	ConversationID uuid.UUID `db:"conversation_id"`
+	UserID         uuid.UUID `db:"user_id"`
+	Text           string    `db:"text"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// ConversationSummary — состояние диалога для списка диалогов пользователя.
+type ConversationSummary struct {
+	Conversation
+	LastMessage *DirectMessage
+	UnreadCount int
+	LastRead    *uuid.UUID
+}
@@ -1 +1,45 @@
 package chat
+
+import (
+	"log/slog"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/eventstream"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/pubsub"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Module struct {
+	Repo             Repository
+	ConversationRepo ConversationRepository
+	Hub              *Hub
+	ConversationHub  *Hub
+	Service          Service
+	Handler          *Handler
+}
+
+func NewModule(
+	log *slog.Logger,
+	pool *pgxpool.Pool,
+	pubsub pubsub.PubSub,
+	stream eventstream.Publisher,
+	eventProvider providers.EventProvider,
+	userProvider providers.UserProvider,
+	notifier providers.NotificationProvider,
+) *Module {
+	repo := NewRepository(pool)
+	conversationRepo := NewConversationRepository(pool)
+	hub := NewHub(log, pubsub, eventChannelPrefix)
+	conversationHub := NewHub(log, pubsub, conversationChannelPrefix)
+	service := NewService(log, repo, conversationRepo, hub, conversationHub, stream, eventProvider, userProvider, notifier)
+	handler := NewHandler(service, hub, conversationHub)
+
+	return &Module{
+		Repo:             repo,
+		ConversationRepo: conversationRepo,
+		Hub:              hub,
+		ConversationHub:  conversationHub,
+		Service:          service,
+		Handler:          handler,
+	}
+}
@@ -0,0 +1,30 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/pubsub"
+	"github.com/google/uuid"
+)
+
+// chatProvider публикует служебные кадры в каналы комнат чатов событий. Ему
+// нужен только pub/sub, поэтому его можно создать раньше модуля чата.
+type chatProvider struct {
+	pubsub pubsub.PubSub
+}
+
+func NewChatProvider(pubsub pubsub.PubSub) providers.ChatProvider {
+	return &chatProvider{pubsub: pubsub}
+}
+
+func (p *chatProvider) RevokeAccess(ctx context.Context, eventID, userID uuid.UUID) error {
+	payload, err := json.Marshal(&OutgoingFrame{Type: FrameAccessRevoked, UserID: userID.String()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal chat frame: %w", err)
+	}
+
+	return p.pubsub.Publish(ctx, eventChannelPrefix+eventID.String(), payload)
+}
@@ -1 +1,473 @@
 package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrMessageNotFound = errors.New("сообщение не найдено")
+
+type Repository interface {
+	Create(ctx context.Context, model *Message) (*Message, error)
+	GetRecentByEventID(ctx context.Context, eventID uuid.UUID, limit int) ([]Message, error)
+	GetPageByEventID(ctx context.Context, eventID uuid.UUID, before *Cursor, limit int) ([]Message, error)
+	GetAfter(ctx context.Context, eventID, afterID uuid.UUID, limit int) ([]Message, error)
+	GetByID(ctx context.Context, eventID, id uuid.UUID) (*Message, error)
+	Update(ctx context.Context, id uuid.UUID, text string) (*Message, error)
+	Delete(ctx context.Context, id, deletedBy uuid.UUID) (*Message, error)
+	GetEdits(ctx context.Context, messageID uuid.UUID) ([]MessageEdit, error)
+	AddReaction(ctx context.Context, reaction *Reaction) (bool, error)
+	RemoveReaction(ctx context.Context, reaction *Reaction) (bool, error)
+	GetReactionsByMessageIDs(ctx context.Context, messageIDs []uuid.UUID) ([]Reaction, error)
+	MarkRead(ctx context.Context, mark *ReadMark) (bool, error)
+	GetReadsByEventID(ctx context.Context, eventID uuid.UUID) ([]ReadMark, error)
+	GetSummaries(ctx context.Context, userID uuid.UUID, eventIDs []uuid.UUID) ([]ChatSummary, error)
+}
+
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) Repository {
+	return &repository{pool}
+}
+
+const messageColumns = `m.id, m.user_id, m.event_id, COALESCE(m.text, ''), m.created_at, m.edited_at, m.deleted_at, m.deleted_by`
+
+func (r *repository) Create(ctx context.Context, model *Message) (*Message, error) {
+	query := `
+		INSERT INTO messages (user_id, event_id, text)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, model.UserID, model.EventID, model.Text).Scan(
+		&model.ID,
+		&model.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить сообщение: %w", err)
+	}
+
+	return model, nil
+}
+
+// GetRecentByEventID возвращает последние limit сообщений чата в хронологическом порядке.
+func (r *repository) GetRecentByEventID(ctx context.Context, eventID uuid.UUID, limit int) ([]Message, error) {
+	query := `
+		SELECT * FROM (
+			SELECT ` + messageColumns + `
+			FROM messages m
+			WHERE m.event_id = $1
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT $2
+		) recent
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.pool.Query(ctx, query, eventID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщения: %w", err)
+	}
+
+	return scanMessages(rows)
+}
+
+// GetPageByEventID возвращает до limit сообщений, отправленных раньше курсора
+// before (или последних, если курсор не задан), от новых к старым.
+func (r *repository) GetPageByEventID(ctx context.Context, eventID uuid.UUID, before *Cursor, limit int) ([]Message, error) {
+	var beforeCreatedAt *time.Time
+	var beforeID *uuid.UUID
+	if before != nil {
+		beforeCreatedAt = &before.CreatedAt
+		beforeID = &before.ID
+	}
+
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.event_id = $1
+			AND ($2::timestamptz IS NULL OR (m.created_at, m.id) < ($2, $3))
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $4
+	`
+
+	rows, err := r.pool.Query(ctx, query, eventID, beforeCreatedAt, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщения: %w", err)
+	}
+
+	return scanMessages(rows)
+}
+
+// GetAfter возвращает до limit сообщений, отправленных после сообщения afterID.
+// Используется, чтобы после переподключения дослать пропущенные сообщения.
+func (r *repository) GetAfter(ctx context.Context, eventID, afterID uuid.UUID, limit int) ([]Message, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM messages WHERE id = $1 AND event_id = $2)
+	`, afterID, eventID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщения: %w", err)
+	}
+	if !exists {
+		return nil, ErrMessageNotFound
+	}
+
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m, messages after
+		WHERE after.id = $2
+			AND m.event_id = $1
+			AND (m.created_at, m.id) > (after.created_at, after.id)
+		ORDER BY m.created_at, m.id
+		LIMIT $3
+	`
+
+	rows, err := r.pool.Query(ctx, query, eventID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщения: %w", err)
+	}
+
+	return scanMessages(rows)
+}
+
+func (r *repository) GetByID(ctx context.Context, eventID, id uuid.UUID) (*Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		WHERE m.id = $1 AND m.event_id = $2
+	`
+
+	var message Message
+	if err := scanMessage(r.pool.QueryRow(ctx, query, id, eventID), &message); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("не удалось получить сообщение: %w", err)
+	}
+
+	return &message, nil
+}
+
+// Update заменяет текст сообщения, сохраняя предыдущую версию в message_edits.
+func (r *repository) Update(ctx context.Context, id uuid.UUID, text string) (*Message, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO message_edits (message_id, text)
+		SELECT id, COALESCE(text, '') FROM messages
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить версию сообщения: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrMessageNotFound
+	}
+
+	query := `
+		UPDATE messages m
+		SET text = $2, edited_at = NOW()
+		WHERE m.id = $1
+		RETURNING ` + messageColumns
+
+	var message Message
+	if err := scanMessage(tx.QueryRow(ctx, query, id, text), &message); err != nil {
+		return nil, fmt.Errorf("не удалось изменить сообщение: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось изменить сообщение: %w", err)
+	}
+
+	return &message, nil
+}
+
+// Delete помечает сообщение удалённым. Текст остаётся в базе для организатора.
+func (r *repository) Delete(ctx context.Context, id, deletedBy uuid.UUID) (*Message, error) {
+	query := `
+		UPDATE messages m
+		SET deleted_at = NOW(), deleted_by = $2
+		WHERE m.id = $1 AND m.deleted_at IS NULL
+		RETURNING ` + messageColumns
+
+	var message Message
+	if err := scanMessage(r.pool.QueryRow(ctx, query, id, deletedBy), &message); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("не удалось удалить сообщение: %w", err)
+	}
+
+	return &message, nil
+}
+
+// GetEdits возвращает предыдущие версии сообщения от исходной к последней.
+func (r *repository) GetEdits(ctx context.Context, messageID uuid.UUID) ([]MessageEdit, error) {
+	query := `
+		SELECT id, message_id, text, edited_at
+		FROM message_edits
+		WHERE message_id = $1
+		ORDER BY edited_at, id
+	`
+
+	rows, err := r.pool.Query(ctx, query, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить историю сообщения: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]MessageEdit, 0)
+	for rows.Next() {
+		var edit MessageEdit
+		if err := rows.Scan(&edit.ID, &edit.MessageID, &edit.Text, &edit.EditedAt); err != nil {
+			return nil, fmt.Errorf("не удалось получить версию сообщения: %w", err)
+		}
+
+		result = append(result, edit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить историю сообщения: %w", err)
+	}
+
+	return result, nil
+}
+
+// AddReaction добавляет реакцию. Возвращает false, если пользователь
+// уже поставил эту реакцию.
+func (r *repository) AddReaction(ctx context.Context, reaction *Reaction) (bool, error) {
+	query := `
+		INSERT INTO message_reactions (message_id, user_id, emoji)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (message_id, user_id, emoji) DO NOTHING
+		RETURNING created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, reaction.MessageID, reaction.UserID, reaction.Emoji).Scan(&reaction.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("не удалось добавить реакцию: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *repository) RemoveReaction(ctx context.Context, reaction *Reaction) (bool, error) {
+	query := `
+		DELETE FROM message_reactions
+		WHERE message_id = $1 AND user_id = $2 AND emoji = $3
+	`
+
+	tag, err := r.pool.Exec(ctx, query, reaction.MessageID, reaction.UserID, reaction.Emoji)
+	if err != nil {
+		return false, fmt.Errorf("не удалось удалить реакцию: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *repository) GetReactionsByMessageIDs(ctx context.Context, messageIDs []uuid.UUID) ([]Reaction, error) {
+	query := `
+		SELECT message_id, user_id, emoji, created_at
+		FROM message_reactions
+		WHERE message_id = ANY($1)
+		ORDER BY created_at, user_id
+	`
+
+	rows, err := r.pool.Query(ctx, query, messageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить реакции: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Reaction, 0)
+	for rows.Next() {
+		var reaction Reaction
+		if err := rows.Scan(&reaction.MessageID, &reaction.UserID, &reaction.Emoji, &reaction.CreatedAt); err != nil {
+			return nil, fmt.Errorf("не удалось получить реакцию: %w", err)
+		}
+
+		result = append(result, reaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить реакции: %w", err)
+	}
+
+	return result, nil
+}
+
+// MarkRead сдвигает отметку прочтения вперёд. Возвращает false, если
+// пользователь уже прочитал это или более позднее сообщение.
+func (r *repository) MarkRead(ctx context.Context, mark *ReadMark) (bool, error) {
+	query := `
+		INSERT INTO chat_reads (event_id, user_id, message_id, message_created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id, user_id) DO UPDATE
+		SET message_id = EXCLUDED.message_id,
+			message_created_at = EXCLUDED.message_created_at,
+			read_at = NOW()
+		WHERE (EXCLUDED.message_created_at, EXCLUDED.message_id) > (chat_reads.message_created_at, chat_reads.message_id)
+		RETURNING read_at
+	`
+
+	err := r.pool.QueryRow(ctx, query, mark.RoomID, mark.UserID, mark.MessageID, mark.MessageCreatedAt).Scan(&mark.ReadAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("не удалось отметить сообщения прочитанными: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *repository) GetReadsByEventID(ctx context.Context, eventID uuid.UUID) ([]ReadMark, error) {
+	query := `
+		SELECT event_id, user_id, message_id, message_created_at, read_at
+		FROM chat_reads
+		WHERE event_id = $1
+	`
+
+	rows, err := r.pool.Query(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить отметки прочтения: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]ReadMark, 0)
+	for rows.Next() {
+		var mark ReadMark
+		if err := rows.Scan(&mark.RoomID, &mark.UserID, &mark.MessageID, &mark.MessageCreatedAt, &mark.ReadAt); err != nil {
+			return nil, fmt.Errorf("не удалось получить отметку прочтения: %w", err)
+		}
+
+		result = append(result, mark)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить отметки прочтения: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetSummaries возвращает для каждого события последнее неудалённое сообщение
+// и число непрочитанных чужих сообщений. Порядок совпадает с eventIDs.
+func (r *repository) GetSummaries(ctx context.Context, userID uuid.UUID, eventIDs []uuid.UUID) ([]ChatSummary, error) {
+	query := `
+		SELECT e.id, cr.message_id, unread.count,
+			last.id, last.user_id, last.text, last.created_at, last.edited_at
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS e(id, position)
+		LEFT JOIN chat_reads cr ON cr.event_id = e.id AND cr.user_id = $1
+		LEFT JOIN LATERAL (
+			SELECT m.id, m.user_id, COALESCE(m.text, '') AS text, m.created_at, m.edited_at
+			FROM messages m
+			WHERE m.event_id = e.id AND m.deleted_at IS NULL
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT 1
+		) last ON TRUE
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS count
+			FROM messages m
+			WHERE m.event_id = e.id
+				AND m.deleted_at IS NULL
+				AND m.user_id <> $1
+				AND (cr.message_id IS NULL OR (m.created_at, m.id) > (cr.message_created_at, cr.message_id))
+		) unread
+		ORDER BY e.position
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить чаты: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]ChatSummary, 0, len(eventIDs))
+	for rows.Next() {
+		var summary ChatSummary
+		var lastID, lastUserID *uuid.UUID
+		var lastText *string
+		var lastCreatedAt, lastEditedAt *time.Time
+		if err := rows.Scan(
+			&summary.EventID,
+			&summary.LastRead,
+			&summary.UnreadCount,
+			&lastID,
+			&lastUserID,
+			&lastText,
+			&lastCreatedAt,
+			&lastEditedAt,
+		); err != nil {
+			return nil, fmt.Errorf("не удалось получить чат: %w", err)
+		}
+
+		if lastID != nil {
+			summary.LastMessage = &Message{
+				ID:        *lastID,
+				UserID:    *lastUserID,
+				EventID:   summary.EventID,
+				Text:      *lastText,
+				CreatedAt: *lastCreatedAt,
+				EditedAt:  lastEditedAt,
+			}
+		}
+
+		result = append(result, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить чаты: %w", err)
+	}
+
+	return result, nil
+}
+
+func scanMessage(row pgx.Row, message *Message) error {
+	return row.Scan(
+		&message.ID,
+		&message.UserID,
+		&message.EventID,
+		&message.Text,
+		&message.CreatedAt,
+		&message.EditedAt,
+		&message.DeletedAt,
+		&message.DeletedBy,
+	)
+}
+
+func scanMessages(rows pgx.Rows) ([]Message, error) {
+	defer rows.Close()
+
+	result := make([]Message, 0)
+	for rows.Next() {
+		var message Message
+		if err := scanMessage(rows, &message); err != nil {
+			return nil, fmt.Errorf("не удалось получить сообщение: %w", err)
+		}
+
+		result = append(result, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить сообщения: %w", err)
+	}
+
+	return result, nil
+}
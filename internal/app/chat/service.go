@@ -1 +1,937 @@
 package chat
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/eventstream"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/google/uuid"
+)
+
+type Service interface {
+	CheckAccess(ctx context.Context, eventID, userID uuid.UUID) error
+	GetBackfill(ctx context.Context, eventID uuid.UUID, after *uuid.UUID) (*OutgoingFrame, error)
+	GetMessages(ctx context.Context, eventID, userID uuid.UUID, before *Cursor, limit int) (*GetMessagesPageResponse, error)
+	SendMessage(ctx context.Context, eventID, userID uuid.UUID, req *SendMessageRequest) (*GetMessageResponse, error)
+	EditMessage(ctx context.Context, eventID, messageID, userID uuid.UUID, req *EditMessageRequest) (*GetMessageResponse, error)
+	DeleteMessage(ctx context.Context, eventID, messageID, userID uuid.UUID) error
+	GetMessageEdits(ctx context.Context, eventID, messageID, userID uuid.UUID) (*GetMessageEditsResponse, error)
+	AddReaction(ctx context.Context, eventID, messageID, userID uuid.UUID, emoji string) error
+	RemoveReaction(ctx context.Context, eventID, messageID, userID uuid.UUID, emoji string) error
+	MarkRead(ctx context.Context, eventID, messageID, userID uuid.UUID) error
+	GetChats(ctx context.Context, userID uuid.UUID) ([]GetChatResponse, error)
+
+	StartConversation(ctx context.Context, userID, peerID uuid.UUID) (*GetConversationResponse, error)
+	GetConversations(ctx context.Context, userID uuid.UUID) ([]GetConversationResponse, error)
+	CheckConversationAccess(ctx context.Context, conversationID, userID uuid.UUID) (*Conversation, error)
+	GetConversationBackfill(ctx context.Context, conversationID uuid.UUID, after *uuid.UUID) (*OutgoingFrame, error)
+	GetConversationMessages(ctx context.Context, conversationID, userID uuid.UUID, before *Cursor, limit int) (*GetMessagesPageResponse, error)
+	SendDirectMessage(ctx context.Context, conversationID, userID uuid.UUID, req *SendMessageRequest) (*GetMessageResponse, error)
+	MarkConversationRead(ctx context.Context, conversationID, messageID, userID uuid.UUID) error
+}
+
+var (
+	ErrNotParticipant = errors.New("чат доступен только участникам события")
+	ErrEmptyMessage   = errors.New("сообщение не может быть пустым")
+	ErrMessageDeleted = errors.New("сообщение удалено")
+	ErrNotAuthor      = errors.New("изменить сообщение может только его автор")
+	ErrCannotDelete   = errors.New("удалить сообщение может только автор или организатор")
+	ErrNotOrganizer   = errors.New("история правок доступна только организатору")
+	ErrInvalidEmoji   = errors.New("реакция должна быть эмодзи")
+
+	ErrCannotMessageSelf        = errors.New("нельзя написать самому себе")
+	ErrRecipientNotFound        = errors.New("пользователь не найден")
+	ErrUserBlocked              = errors.New("личные сообщения с этим пользователем недоступны")
+	ErrDirectMessagesNotAllowed = errors.New("личные сообщения доступны пользователям с общими событиями или если оба разрешили их")
+)
+
+const (
+	// historyLimit — сколько последних сообщений отправляется при подключении.
+	historyLimit = 50
+	// backfillLimit — сколько пропущенных сообщений досылается при переподключении.
+	// Если пропущено больше, клиент получает has_more и догружает историю сам.
+	backfillLimit = 200
+	// maxEmojiLength — максимальная длина реакции в байтах, как в message_reactions.emoji.
+	maxEmojiLength = 32
+	// previewLength — сколько символов сообщения показывается в списке чатов
+	// и в уведомлении об упоминании.
+	previewLength = 100
+)
+
+type service struct {
+	log              *slog.Logger
+	repo             Repository
+	conversationRepo ConversationRepository
+	hub              *Hub
+	conversationHub  *Hub
+	stream           eventstream.Publisher
+	eventProvider    providers.EventProvider
+	userProvider     providers.UserProvider
+	notifier         providers.NotificationProvider
+}
+
+func NewService(
+	log *slog.Logger,
+	repo Repository,
+	conversationRepo ConversationRepository,
+	hub *Hub,
+	conversationHub *Hub,
+	stream eventstream.Publisher,
+	eventProvider providers.EventProvider,
+	userProvider providers.UserProvider,
+	notifier providers.NotificationProvider,
+) Service {
+	return &service{
+		log:              log,
+		repo:             repo,
+		conversationRepo: conversationRepo,
+		hub:              hub,
+		conversationHub:  conversationHub,
+		stream:           stream,
+		eventProvider:    eventProvider,
+		userProvider:     userProvider,
+		notifier:         notifier,
+	}
+}
+
+func (s *service) CheckAccess(ctx context.Context, eventID, userID uuid.UUID) error {
+	isParticipant, err := s.eventProvider.IsParticipant(ctx, eventID, userID)
+	if err != nil {
+		s.log.Error("failed to check chat access", "event_id", eventID, "user_id", userID, "error", err)
+		return err
+	}
+
+	if !isParticipant {
+		s.log.Warn("non-participant tried to access chat", "event_id", eventID, "user_id", userID)
+		return ErrNotParticipant
+	}
+
+	return nil
+}
+
+// GetBackfill возвращает кадр с историей и отметками прочтения для нового
+// соединения. Если клиент переподключается и передал последнее полученное
+// сообщение, досылаются только сообщения после него; неизвестный after
+// считается первым подключением.
+func (s *service) GetBackfill(ctx context.Context, eventID uuid.UUID, after *uuid.UUID) (*OutgoingFrame, error) {
+	frame, err := s.getHistory(ctx, eventID, after)
+	if err != nil {
+		return nil, err
+	}
+
+	reads, err := s.repo.GetReadsByEventID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to get chat read marks", "event_id", eventID, "error", err)
+		return nil, err
+	}
+	frame.Receipts = ReadMarksToGetReceiptResponse(reads)
+
+	return frame, nil
+}
+
+func (s *service) getHistory(ctx context.Context, eventID uuid.UUID, after *uuid.UUID) (*OutgoingFrame, error) {
+	if after != nil {
+		messages, err := s.repo.GetAfter(ctx, eventID, *after, backfillLimit+1)
+		switch {
+		case err == nil:
+			hasMore := len(messages) > backfillLimit
+			if hasMore {
+				messages = messages[:backfillLimit]
+			}
+
+			items, err := s.hydrate(ctx, messages)
+			if err != nil {
+				return nil, err
+			}
+
+			return &OutgoingFrame{
+				Type:     FrameHistory,
+				Messages: items,
+				HasMore:  hasMore,
+			}, nil
+		case !errors.Is(err, ErrMessageNotFound):
+			s.log.Error("failed to get missed messages", "event_id", eventID, "after", *after, "error", err)
+			return nil, err
+		}
+	}
+
+	messages, err := s.repo.GetRecentByEventID(ctx, eventID, historyLimit)
+	if err != nil {
+		s.log.Error("failed to get chat history", "event_id", eventID, "error", err)
+		return nil, err
+	}
+
+	items, err := s.hydrate(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutgoingFrame{
+		Type:     FrameHistory,
+		Messages: items,
+		HasMore:  len(messages) == historyLimit,
+	}, nil
+}
+
+// GetMessages возвращает страницу истории, более старую, чем курсор before.
+func (s *service) GetMessages(ctx context.Context, eventID, userID uuid.UUID, before *Cursor, limit int) (*GetMessagesPageResponse, error) {
+	if err := s.CheckAccess(ctx, eventID, userID); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.repo.GetPageByEventID(ctx, eventID, before, limit+1)
+	if err != nil {
+		s.log.Error("failed to get chat messages page", "event_id", eventID, "error", err)
+		return nil, err
+	}
+
+	result := &GetMessagesPageResponse{}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		cursor := NewCursor(&messages[len(messages)-1]).Encode()
+		result.NextCursor = &cursor
+	}
+
+	slices.Reverse(messages)
+
+	result.Items, err = s.hydrate(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *service) SendMessage(ctx context.Context, eventID, userID uuid.UUID, req *SendMessageRequest) (*GetMessageResponse, error) {
+	if err := s.CheckAccess(ctx, eventID, userID); err != nil {
+		return nil, err
+	}
+
+	model := SendMessageRequestToModel(req, eventID, userID)
+	if model.Text == "" {
+		return nil, ErrEmptyMessage
+	}
+
+	message, err := s.repo.Create(ctx, model)
+	if err != nil {
+		s.log.Error("failed to create message", "event_id", eventID, "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	result := s.hydrateOne(ctx, message)
+	s.hub.Broadcast(ctx, eventID, &OutgoingFrame{
+		Type:     FrameMessageCreated,
+		Message:  result,
+		ClientID: req.ClientID,
+	})
+
+	// Клиенты без WebSocket узнают о сообщении из потока события (SSE).
+	if err := s.stream.Publish(ctx, eventID, FrameMessageCreated, result); err != nil {
+		s.log.Error("failed to publish message to event stream", "event_id", eventID, "message_id", result.ID, "error", err)
+	}
+
+	s.notifyMentions(ctx, message, req.Mentions)
+
+	return result, nil
+}
+
+// EditMessage меняет текст сообщения. Предыдущий текст сохраняется
+// и доступен организатору через GetMessageEdits.
+func (s *service) EditMessage(ctx context.Context, eventID, messageID, userID uuid.UUID, req *EditMessageRequest) (*GetMessageResponse, error) {
+	message, err := s.getActiveMessage(ctx, eventID, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.UserID != userID {
+		s.log.Warn("non-author tried to edit message", "message_id", messageID, "user_id", userID)
+		return nil, ErrNotAuthor
+	}
+
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, ErrEmptyMessage
+	}
+	if text == message.Text {
+		return s.hydrateOne(ctx, message), nil
+	}
+
+	message, err = s.repo.Update(ctx, messageID, text)
+	if err != nil {
+		s.log.Error("failed to edit message", "message_id", messageID, "error", err)
+		return nil, err
+	}
+
+	result := s.hydrateOne(ctx, message)
+	s.hub.Broadcast(ctx, eventID, &OutgoingFrame{
+		Type:    FrameMessageEdited,
+		Message: result,
+	})
+
+	return result, nil
+}
+
+// DeleteMessage скрывает сообщение от участников. Удалить может автор
+// или организатор события.
+func (s *service) DeleteMessage(ctx context.Context, eventID, messageID, userID uuid.UUID) error {
+	message, err := s.getActiveMessage(ctx, eventID, messageID, userID)
+	if err != nil {
+		return err
+	}
+
+	if message.UserID != userID {
+		isOrganizer, err := s.eventProvider.IsOrganizer(ctx, eventID, userID)
+		if err != nil {
+			s.log.Error("failed to check event organizer", "event_id", eventID, "user_id", userID, "error", err)
+			return err
+		}
+		if !isOrganizer {
+			s.log.Warn("user tried to delete foreign message", "message_id", messageID, "user_id", userID)
+			return ErrCannotDelete
+		}
+	}
+
+	message, err = s.repo.Delete(ctx, messageID, userID)
+	if err != nil {
+		if errors.Is(err, ErrMessageNotFound) {
+			return ErrMessageDeleted
+		}
+		s.log.Error("failed to delete message", "message_id", messageID, "error", err)
+		return err
+	}
+
+	s.hub.Broadcast(ctx, eventID, &OutgoingFrame{
+		Type:    FrameMessageDeleted,
+		Message: MessageToGetResponse(message, nil, nil),
+	})
+
+	return nil
+}
+
+// GetMessageEdits возвращает организатору исходный и все заменённые тексты
+// сообщения, в том числе удалённого.
+func (s *service) GetMessageEdits(ctx context.Context, eventID, messageID, userID uuid.UUID) (*GetMessageEditsResponse, error) {
+	isOrganizer, err := s.eventProvider.IsOrganizer(ctx, eventID, userID)
+	if err != nil {
+		s.log.Error("failed to check event organizer", "event_id", eventID, "user_id", userID, "error", err)
+		return nil, err
+	}
+	if !isOrganizer {
+		s.log.Warn("non-organizer tried to read message edits", "message_id", messageID, "user_id", userID)
+		return nil, ErrNotOrganizer
+	}
+
+	message, err := s.repo.GetByID(ctx, eventID, messageID)
+	if err != nil {
+		if !errors.Is(err, ErrMessageNotFound) {
+			s.log.Error("failed to get message", "message_id", messageID, "error", err)
+		}
+		return nil, err
+	}
+
+	edits, err := s.repo.GetEdits(ctx, messageID)
+	if err != nil {
+		s.log.Error("failed to get message edits", "message_id", messageID, "error", err)
+		return nil, err
+	}
+
+	return MessageEditsToGetResponse(message, edits), nil
+}
+
+// AddReaction ставит реакцию. Повторная такая же реакция пользователя ничего не меняет.
+func (s *service) AddReaction(ctx context.Context, eventID, messageID, userID uuid.UUID, emoji string) error {
+	reaction, err := s.newReaction(ctx, eventID, messageID, userID, emoji)
+	if err != nil {
+		return err
+	}
+
+	added, err := s.repo.AddReaction(ctx, reaction)
+	if err != nil {
+		s.log.Error("failed to add reaction", "message_id", messageID, "user_id", userID, "error", err)
+		return err
+	}
+
+	if added {
+		s.hub.Broadcast(ctx, eventID, &OutgoingFrame{
+			Type:     FrameReactionAdded,
+			Reaction: ReactionToGetChangeResponse(reaction),
+		})
+	}
+
+	return nil
+}
+
+func (s *service) RemoveReaction(ctx context.Context, eventID, messageID, userID uuid.UUID, emoji string) error {
+	reaction, err := s.newReaction(ctx, eventID, messageID, userID, emoji)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.repo.RemoveReaction(ctx, reaction)
+	if err != nil {
+		s.log.Error("failed to remove reaction", "message_id", messageID, "user_id", userID, "error", err)
+		return err
+	}
+
+	if removed {
+		s.hub.Broadcast(ctx, eventID, &OutgoingFrame{
+			Type:     FrameReactionRemoved,
+			Reaction: ReactionToGetChangeResponse(reaction),
+		})
+	}
+
+	return nil
+}
+
+func (s *service) newReaction(ctx context.Context, eventID, messageID, userID uuid.UUID, emoji string) (*Reaction, error) {
+	emoji = strings.TrimSpace(emoji)
+	if !isEmoji(emoji) {
+		return nil, ErrInvalidEmoji
+	}
+
+	if _, err := s.getActiveMessage(ctx, eventID, messageID, userID); err != nil {
+		return nil, err
+	}
+
+	return &Reaction{MessageID: messageID, UserID: userID, Emoji: emoji}, nil
+}
+
+// MarkRead отмечает чат прочитанным до сообщения messageID. Отметка только
+// сдвигается вперёд; об изменении узнают остальные участники чата.
+func (s *service) MarkRead(ctx context.Context, eventID, messageID, userID uuid.UUID) error {
+	if err := s.CheckAccess(ctx, eventID, userID); err != nil {
+		return err
+	}
+
+	message, err := s.repo.GetByID(ctx, eventID, messageID)
+	if err != nil {
+		if !errors.Is(err, ErrMessageNotFound) {
+			s.log.Error("failed to get message", "message_id", messageID, "error", err)
+		}
+		return err
+	}
+
+	mark := &ReadMark{
+		RoomID:           eventID,
+		UserID:           userID,
+		MessageID:        message.ID,
+		MessageCreatedAt: message.CreatedAt,
+	}
+
+	advanced, err := s.repo.MarkRead(ctx, mark)
+	if err != nil {
+		s.log.Error("failed to mark chat read", "event_id", eventID, "user_id", userID, "error", err)
+		return err
+	}
+
+	if advanced {
+		s.hub.Broadcast(ctx, eventID, &OutgoingFrame{
+			Type:    FrameReadReceipt,
+			Receipt: ReadMarkToGetReceiptResponse(mark),
+		})
+	}
+
+	return nil
+}
+
+// GetChats возвращает чаты событий пользователя: сначала с самыми свежими
+// сообщениями, затем чаты без сообщений от новых событий к старым.
+func (s *service) GetChats(ctx context.Context, userID uuid.UUID) ([]GetChatResponse, error) {
+	events, err := s.eventProvider.GetJoinedEvents(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get user events", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return []GetChatResponse{}, nil
+	}
+
+	eventIDs := make([]uuid.UUID, 0, len(events))
+	for _, event := range events {
+		eventIDs = append(eventIDs, event.ID)
+	}
+
+	summaries, err := s.repo.GetSummaries(ctx, userID, eventIDs)
+	if err != nil {
+		s.log.Error("failed to get chat summaries", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	authorIDs := make([]uuid.UUID, 0, len(summaries))
+	for _, summary := range summaries {
+		if summary.LastMessage != nil {
+			authorIDs = append(authorIDs, summary.LastMessage.UserID)
+		}
+	}
+
+	authors, err := s.userProvider.GetUsersByIDs(ctx, authorIDs)
+	if err != nil {
+		s.log.Error("failed to get message authors", "count", len(authorIDs), "error", err)
+		return nil, err
+	}
+
+	slices.SortStableFunc(summaries, func(a, b ChatSummary) int {
+		switch {
+		case a.LastMessage == nil && b.LastMessage == nil:
+			return 0
+		case a.LastMessage == nil:
+			return 1
+		case b.LastMessage == nil:
+			return -1
+		default:
+			return b.LastMessage.CreatedAt.Compare(a.LastMessage.CreatedAt)
+		}
+	})
+
+	byID := make(map[uuid.UUID]*providers.EventInfo, len(events))
+	for i := range events {
+		byID[events[i].ID] = &events[i]
+	}
+
+	result := make([]GetChatResponse, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *ChatSummaryToGetResponse(byID[summary.EventID], &summary, authors))
+	}
+
+	return result, nil
+}
+
+// StartConversation возвращает диалог с пользователем peerID, создавая его при
+// необходимости.
+func (s *service) StartConversation(ctx context.Context, userID, peerID uuid.UUID) (*GetConversationResponse, error) {
+	if userID == peerID {
+		return nil, ErrCannotMessageSelf
+	}
+
+	authors, err := s.userProvider.GetUsersByIDs(ctx, []uuid.UUID{peerID})
+	if err != nil {
+		s.log.Error("failed to get conversation peer", "peer_id", peerID, "error", err)
+		return nil, err
+	}
+	if _, ok := authors[peerID.String()]; !ok {
+		return nil, ErrRecipientNotFound
+	}
+
+	if err := s.checkCanMessage(ctx, userID, peerID); err != nil {
+		return nil, err
+	}
+
+	conversation, err := s.conversationRepo.GetOrCreate(ctx, userID, peerID)
+	if err != nil {
+		s.log.Error("failed to get or create conversation", "user_id", userID, "peer_id", peerID, "error", err)
+		return nil, err
+	}
+
+	return ConversationToGetResponse(conversation, userID, authors), nil
+}
+
+func (s *service) GetConversations(ctx context.Context, userID uuid.UUID) ([]GetConversationResponse, error) {
+	summaries, err := s.conversationRepo.GetSummaries(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get conversations", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	// Автор последнего сообщения — либо собеседник, либо сам пользователь.
+	userIDs := make([]uuid.UUID, 0, len(summaries)+1)
+	userIDs = append(userIDs, userID)
+	for _, summary := range summaries {
+		userIDs = append(userIDs, summary.Peer(userID))
+	}
+
+	authors, err := s.userProvider.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		s.log.Error("failed to get conversation peers", "count", len(userIDs), "error", err)
+		return nil, err
+	}
+
+	result := make([]GetConversationResponse, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *ConversationSummaryToGetResponse(&summary, userID, authors))
+	}
+
+	return result, nil
+}
+
+// CheckConversationAccess возвращает диалог, если пользователь в нём участвует.
+// Чужой диалог неотличим от несуществующего.
+func (s *service) CheckConversationAccess(ctx context.Context, conversationID, userID uuid.UUID) (*Conversation, error) {
+	conversation, err := s.conversationRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		if !errors.Is(err, ErrConversationNotFound) {
+			s.log.Error("failed to get conversation", "conversation_id", conversationID, "error", err)
+		}
+		return nil, err
+	}
+
+	if !conversation.HasMember(userID) {
+		s.log.Warn("non-member tried to access conversation", "conversation_id", conversationID, "user_id", userID)
+		return nil, ErrConversationNotFound
+	}
+
+	return conversation, nil
+}
+
+// GetConversationBackfill работает как GetBackfill для чата события.
+func (s *service) GetConversationBackfill(ctx context.Context, conversationID uuid.UUID, after *uuid.UUID) (*OutgoingFrame, error) {
+	frame, err := s.getConversationHistory(ctx, conversationID, after)
+	if err != nil {
+		return nil, err
+	}
+
+	reads, err := s.conversationRepo.GetReads(ctx, conversationID)
+	if err != nil {
+		s.log.Error("failed to get conversation read marks", "conversation_id", conversationID, "error", err)
+		return nil, err
+	}
+	frame.Receipts = ReadMarksToGetReceiptResponse(reads)
+
+	return frame, nil
+}
+
+func (s *service) getConversationHistory(ctx context.Context, conversationID uuid.UUID, after *uuid.UUID) (*OutgoingFrame, error) {
+	if after != nil {
+		messages, err := s.conversationRepo.GetMessagesAfter(ctx, conversationID, *after, backfillLimit+1)
+		switch {
+		case err == nil:
+			hasMore := len(messages) > backfillLimit
+			if hasMore {
+				messages = messages[:backfillLimit]
+			}
+
+			items, err := s.hydrateDirect(ctx, messages)
+			if err != nil {
+				return nil, err
+			}
+
+			return &OutgoingFrame{
+				Type:     FrameHistory,
+				Messages: items,
+				HasMore:  hasMore,
+			}, nil
+		case !errors.Is(err, ErrMessageNotFound):
+			s.log.Error("failed to get missed direct messages", "conversation_id", conversationID, "after", *after, "error", err)
+			return nil, err
+		}
+	}
+
+	messages, err := s.conversationRepo.GetRecentMessages(ctx, conversationID, historyLimit)
+	if err != nil {
+		s.log.Error("failed to get conversation history", "conversation_id", conversationID, "error", err)
+		return nil, err
+	}
+
+	items, err := s.hydrateDirect(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutgoingFrame{
+		Type:     FrameHistory,
+		Messages: items,
+		HasMore:  len(messages) == historyLimit,
+	}, nil
+}
+
+// GetConversationMessages возвращает страницу истории диалога, более старую, чем курсор before.
+func (s *service) GetConversationMessages(ctx context.Context, conversationID, userID uuid.UUID, before *Cursor, limit int) (*GetMessagesPageResponse, error) {
+	if _, err := s.CheckConversationAccess(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.conversationRepo.GetMessagesPage(ctx, conversationID, before, limit+1)
+	if err != nil {
+		s.log.Error("failed to get direct messages page", "conversation_id", conversationID, "error", err)
+		return nil, err
+	}
+
+	result := &GetMessagesPageResponse{}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		oldest := messages[len(messages)-1]
+		cursor := (&Cursor{CreatedAt: oldest.CreatedAt, ID: oldest.ID}).Encode()
+		result.NextCursor = &cursor
+	}
+
+	slices.Reverse(messages)
+
+	result.Items, err = s.hydrateDirect(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SendDirectMessage отправляет сообщение в диалог. Право писать проверяется при
+// каждой отправке: блокировка или выход из общих событий закрывают диалог.
+func (s *service) SendDirectMessage(ctx context.Context, conversationID, userID uuid.UUID, req *SendMessageRequest) (*GetMessageResponse, error) {
+	conversation, err := s.CheckConversationAccess(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCanMessage(ctx, userID, conversation.Peer(userID)); err != nil {
+		return nil, err
+	}
+
+	model := SendMessageRequestToDirectMessage(req, conversationID, userID)
+	if model.Text == "" {
+		return nil, ErrEmptyMessage
+	}
+
+	message, err := s.conversationRepo.CreateMessage(ctx, model)
+	if err != nil {
+		s.log.Error("failed to create direct message", "conversation_id", conversationID, "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	// Сообщение уже сохранено, поэтому без автора оно всё равно рассылается.
+	result := DirectMessageToGetResponse(message, nil)
+	if items, err := s.hydrateDirect(ctx, []DirectMessage{*message}); err == nil {
+		result = &items[0]
+	}
+
+	s.conversationHub.Broadcast(ctx, conversationID, &OutgoingFrame{
+		Type:     FrameMessageCreated,
+		Message:  result,
+		ClientID: req.ClientID,
+	})
+
+	return result, nil
+}
+
+func (s *service) MarkConversationRead(ctx context.Context, conversationID, messageID, userID uuid.UUID) error {
+	if _, err := s.CheckConversationAccess(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	message, err := s.conversationRepo.GetMessageByID(ctx, conversationID, messageID)
+	if err != nil {
+		if !errors.Is(err, ErrMessageNotFound) {
+			s.log.Error("failed to get direct message", "message_id", messageID, "error", err)
+		}
+		return err
+	}
+
+	mark := &ReadMark{
+		RoomID:           conversationID,
+		UserID:           userID,
+		MessageID:        message.ID,
+		MessageCreatedAt: message.CreatedAt,
+	}
+
+	advanced, err := s.conversationRepo.MarkRead(ctx, mark)
+	if err != nil {
+		s.log.Error("failed to mark conversation read", "conversation_id", conversationID, "user_id", userID, "error", err)
+		return err
+	}
+
+	if advanced {
+		s.conversationHub.Broadcast(ctx, conversationID, &OutgoingFrame{
+			Type:    FrameReadReceipt,
+			Receipt: ReadMarkToGetReceiptResponse(mark),
+		})
+	}
+
+	return nil
+}
+
+// checkCanMessage разрешает переписку, если никто из двоих не заблокировал
+// другого и у них есть общее событие либо оба открыли личные сообщения.
+func (s *service) checkCanMessage(ctx context.Context, userID, peerID uuid.UUID) error {
+	blocked, err := s.userProvider.HasBlock(ctx, userID, peerID)
+	if err != nil {
+		s.log.Error("failed to check user block", "user_id", userID, "peer_id", peerID, "error", err)
+		return err
+	}
+	if blocked {
+		return ErrUserBlocked
+	}
+
+	shares, err := s.eventProvider.SharesEvent(ctx, userID, peerID)
+	if err != nil {
+		s.log.Error("failed to check shared events", "user_id", userID, "peer_id", peerID, "error", err)
+		return err
+	}
+	if shares {
+		return nil
+	}
+
+	for _, id := range []uuid.UUID{userID, peerID} {
+		open, err := s.userProvider.AllowsDirectMessages(ctx, id)
+		if err != nil {
+			s.log.Error("failed to get direct message settings", "user_id", id, "error", err)
+			return err
+		}
+		if !open {
+			return ErrDirectMessagesNotAllowed
+		}
+	}
+
+	return nil
+}
+
+// getActiveMessage проверяет доступ к чату и возвращает неудалённое сообщение.
+func (s *service) getActiveMessage(ctx context.Context, eventID, messageID, userID uuid.UUID) (*Message, error) {
+	if err := s.CheckAccess(ctx, eventID, userID); err != nil {
+		return nil, err
+	}
+
+	message, err := s.repo.GetByID(ctx, eventID, messageID)
+	if err != nil {
+		if !errors.Is(err, ErrMessageNotFound) {
+			s.log.Error("failed to get message", "message_id", messageID, "error", err)
+		}
+		return nil, err
+	}
+
+	if message.DeletedAt != nil {
+		return nil, ErrMessageDeleted
+	}
+
+	return message, nil
+}
+
+// isEmoji отсекает обычный текст: реакция не длиннее maxEmojiLength байт,
+// без пробелов и содержит хотя бы один символ вне ASCII.
+func isEmoji(value string) bool {
+	if value == "" || len(value) > maxEmojiLength {
+		return false
+	}
+
+	hasSymbol := false
+	for _, r := range value {
+		if unicode.IsSpace(r) {
+			return false
+		}
+		if r > unicode.MaxASCII {
+			hasSymbol = true
+		}
+	}
+
+	return hasSymbol
+}
+
+// hydrateOne готовит ответ для одного уже сохранённого сообщения. Изменение
+// в базе уже произошло, поэтому при ошибке ответ рассылается без автора и реакций.
+func (s *service) hydrateOne(ctx context.Context, message *Message) *GetMessageResponse {
+	items, err := s.hydrate(ctx, []Message{*message})
+	if err != nil {
+		return MessageToGetResponse(message, nil, nil)
+	}
+
+	return &items[0]
+}
+
+// hydrate подгружает авторов и реакции сообщений пакетными запросами.
+func (s *service) hydrate(ctx context.Context, messages []Message) ([]GetMessageResponse, error) {
+	userIDs := make([]uuid.UUID, 0, len(messages))
+	messageIDs := make([]uuid.UUID, 0, len(messages))
+	seen := make(map[uuid.UUID]struct{}, len(messages))
+	for _, message := range messages {
+		messageIDs = append(messageIDs, message.ID)
+		if _, ok := seen[message.UserID]; ok {
+			continue
+		}
+		seen[message.UserID] = struct{}{}
+		userIDs = append(userIDs, message.UserID)
+	}
+
+	authors, err := s.userProvider.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		s.log.Error("failed to get message authors", "count", len(userIDs), "error", err)
+		return nil, err
+	}
+
+	reactions, err := s.repo.GetReactionsByMessageIDs(ctx, messageIDs)
+	if err != nil {
+		s.log.Error("failed to get message reactions", "count", len(messageIDs), "error", err)
+		return nil, err
+	}
+
+	byMessage := make(map[uuid.UUID][]Reaction, len(messageIDs))
+	for _, reaction := range reactions {
+		byMessage[reaction.MessageID] = append(byMessage[reaction.MessageID], reaction)
+	}
+
+	return MessagesToGetResponse(messages, authors, byMessage), nil
+}
+
+func (s *service) hydrateDirect(ctx context.Context, messages []DirectMessage) ([]GetMessageResponse, error) {
+	userIDs := make([]uuid.UUID, 0, 2)
+	for _, message := range messages {
+		if !slices.Contains(userIDs, message.UserID) {
+			userIDs = append(userIDs, message.UserID)
+		}
+	}
+
+	authors, err := s.userProvider.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		s.log.Error("failed to get message authors", "count", len(userIDs), "error", err)
+		return nil, err
+	}
+
+	return DirectMessagesToGetResponse(messages, authors), nil
+}
+
+// notifyMentions уведомляет упомянутых в сообщении участников чата. Упоминания
+// тех, кто не участвует в событии, пропускаются.
+func (s *service) notifyMentions(ctx context.Context, message *Message, mentions []string) {
+	if s.notifier == nil || len(mentions) == 0 {
+		return
+	}
+
+	event, err := s.eventProvider.GetEventInfo(ctx, message.EventID)
+	if err != nil {
+		s.log.Error("failed to get event for mention notification", "event_id", message.EventID, "error", err)
+		return
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(mentions))
+	for _, value := range mentions {
+		userID, err := uuid.Parse(value)
+		if err != nil || userID == message.UserID {
+			continue
+		}
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+
+		isParticipant, err := s.eventProvider.IsParticipant(ctx, message.EventID, userID)
+		if err != nil {
+			s.log.Error("failed to check mentioned user", "event_id", message.EventID, "user_id", userID, "error", err)
+			continue
+		}
+		if !isParticipant {
+			continue
+		}
+
+		_ = s.notifier.Notify(ctx, userID, &providers.Notification{
+			Type:          providers.NotificationChatMention,
+			ActorID:       &message.UserID,
+			EventID:       &event.ID,
+			EventTitle:    event.Title,
+			EventStartsAt: event.StartsAt,
+			MessageID:     &message.ID,
+			Text:          mentionPreview(message.Text),
+		})
+	}
+}
+
+// mentionPreview обрезает текст сообщения до previewLength символов.
+func mentionPreview(text string) string {
+	runes := []rune(text)
+	if len(runes) > previewLength {
+		runes = append(runes[:previewLength], '…')
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,90 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrCalendarFeedNotFound = errors.New("календарь не найден")
+
+type CalendarFeedRepository interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*CalendarFeed, error)
+	GetByToken(ctx context.Context, token string) (*CalendarFeed, error)
+	Save(ctx context.Context, userID uuid.UUID, token string) (*CalendarFeed, error)
+}
+
+type calendarFeedRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCalendarFeedRepository(pool *pgxpool.Pool) CalendarFeedRepository {
+	return &calendarFeedRepository{pool}
+}
+
+func (r *calendarFeedRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*CalendarFeed, error) {
+	query := `
+		SELECT id, user_id, token, created_at
+		FROM calendar_feeds
+		WHERE user_id = $1
+	`
+
+	var feed CalendarFeed
+	if err := scanCalendarFeed(r.pool.QueryRow(ctx, query, userID), &feed); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCalendarFeedNotFound
+		}
+		return nil, fmt.Errorf("не удалось получить календарь: %w", err)
+	}
+
+	return &feed, nil
+}
+
+func (r *calendarFeedRepository) GetByToken(ctx context.Context, token string) (*CalendarFeed, error) {
+	query := `
+		SELECT id, user_id, token, created_at
+		FROM calendar_feeds
+		WHERE token = $1
+	`
+
+	var feed CalendarFeed
+	if err := scanCalendarFeed(r.pool.QueryRow(ctx, query, token), &feed); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCalendarFeedNotFound
+		}
+		return nil, fmt.Errorf("не удалось получить календарь: %w", err)
+	}
+
+	return &feed, nil
+}
+
+// Save создаёт календарь пользователя или заменяет токен существующего,
+// после чего старая ссылка перестаёт работать.
+func (r *calendarFeedRepository) Save(ctx context.Context, userID uuid.UUID, token string) (*CalendarFeed, error) {
+	query := `
+		INSERT INTO calendar_feeds (user_id, token)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = NOW()
+		RETURNING id, user_id, token, created_at
+	`
+
+	var feed CalendarFeed
+	if err := scanCalendarFeed(r.pool.QueryRow(ctx, query, userID, token), &feed); err != nil {
+		return nil, fmt.Errorf("не удалось сохранить календарь: %w", err)
+	}
+
+	return &feed, nil
+}
+
+func scanCalendarFeed(row pgx.Row, feed *CalendarFeed) error {
+	return row.Scan(
+		&feed.ID,
+		&feed.UserID,
+		&feed.Token,
+		&feed.CreatedAt,
+	)
+}
@@ -3,19 +3,28 @@ package event
 import "time"
 
 type GetEventResponse struct {
-	ID           string                   `json:"id"`
-	Title        string                   `json:"title"`
-	Description  string                   `json:"description"`
-	Latitude     float64                  `json:"latitude"`
-	Longitude    float64                  `json:"longitude"`
-	Address      *string                  `json:"address"`
-	StartsAt     *time.Time               `json:"starts_at"`
-	EndsAt       *time.Time               `json:"ends_at"`
-	IsPublic     bool                     `json:"is_public"`
-	CreatedAt    time.Time                `json:"created_at"`
-	Creator      GetParticipantResponse   `json:"creator"`
-	Category     GetCategoryResponse      `json:"category"`
-	Participants []GetParticipantResponse `json:"participants"`
+	ID                string                   `json:"id"`
+	Title             string                   `json:"title"`
+	Description       string                   `json:"description"`
+	Latitude          float64                  `json:"latitude"`
+	Longitude         float64                  `json:"longitude"`
+	Address           *string                  `json:"address"`
+	StartsAt          *time.Time               `json:"starts_at"`
+	EndsAt            *time.Time               `json:"ends_at"`
+	IsPublic          bool                     `json:"is_public"`
+	MaxParticipants   *int                     `json:"max_participants"`
+	JoinMode          string                   `json:"join_mode"`
+	Timezone          string                   `json:"timezone"`
+	Recurrence        *GetRecurrenceResponse   `json:"recurrence,omitempty"`
+	SeriesID          *string                  `json:"series_id,omitempty"`
+	CancelledAt       *time.Time               `json:"cancelled_at,omitempty"`
+	CoverUrl          *string                  `json:"cover_url"`
+	ParticipantsCount int                      `json:"participants_count"`
+	WaitlistCount     int                      `json:"waitlist_count"`
+	CreatedAt         time.Time                `json:"created_at"`
+	Creator           GetParticipantResponse   `json:"creator"`
+	Category          GetCategoryResponse      `json:"category"`
+	Participants      []GetParticipantResponse `json:"participants"`
 }
 
 type GetShortEventResponse struct {
@@ -26,21 +35,57 @@ type GetShortEventResponse struct {
 	StartsAt          *time.Time             `json:"starts_at"`
 	EndsAt            *time.Time             `json:"ends_at"`
 	IsPublic          bool                   `json:"is_public"`
+	MaxParticipants   *int                   `json:"max_participants"`
+	JoinMode          string                 `json:"join_mode"`
+	SeriesID          *string                `json:"series_id,omitempty"`
+	CoverUrl          *string                `json:"cover_url"`
 	ParticipantsCount int                    `json:"participants_count"`
+	WaitlistCount     int                    `json:"waitlist_count"`
 	Category          GetCategoryResponse    `json:"category"`
 	Creator           GetParticipantResponse `json:"creator"`
 }
 
 type CreateEventRequest struct {
-	CategoryID  string     `json:"category_id" validate:"required,uuid"`
-	Title       string     `json:"title" validate:"required"`
-	Description string     `json:"description" validate:"required"`
-	Latitude    float64    `json:"latitude" validate:"required"`
-	Longitude   float64    `json:"longitude" validate:"required"`
+	CategoryID      string             `json:"category_id" validate:"required,uuid"`
+	Title           string             `json:"title" validate:"required"`
+	Description     string             `json:"description" validate:"required"`
+	Latitude        float64            `json:"latitude" validate:"required"`
+	Longitude       float64            `json:"longitude" validate:"required"`
+	Address         *string            `json:"address"`
+	StartsAt        *time.Time         `json:"starts_at"`
+	EndsAt          *time.Time         `json:"ends_at"`
+	IsPublic        bool               `json:"is_public"`
+	MaxParticipants *int               `json:"max_participants" validate:"omitempty,min=1"`
+	JoinMode        string             `json:"join_mode" validate:"omitempty,oneof=open request"`
+	Timezone        string             `json:"timezone" validate:"omitempty,timezone"`
+	Recurrence      *RecurrenceRequest `json:"recurrence"`
+}
+
+// UpdateEventRequest — частичное изменение события: меняются только переданные поля.
+type UpdateEventRequest struct {
+	CategoryID  *string    `json:"category_id" validate:"omitempty,uuid"`
+	Title       *string    `json:"title" validate:"omitempty,min=1"`
+	Description *string    `json:"description" validate:"omitempty,min=1"`
+	Latitude    *float64   `json:"latitude"`
+	Longitude   *float64   `json:"longitude"`
 	Address     *string    `json:"address"`
 	StartsAt    *time.Time `json:"starts_at"`
 	EndsAt      *time.Time `json:"ends_at"`
-	IsPublic    bool       `json:"is_public"`
+}
+
+type RecurrenceRequest struct {
+	Rule    string      `json:"rule" validate:"required"`
+	Exdates []time.Time `json:"exdates"`
+}
+
+type GetRecurrenceResponse struct {
+	Rule    string      `json:"rule"`
+	Exdates []time.Time `json:"exdates"`
+}
+
+type GetShortEventsFilter struct {
+	From *time.Time
+	To   *time.Time
 }
 
 type GetCategoryResponse struct {
@@ -54,3 +99,87 @@ type GetParticipantResponse struct {
 	LastName  string `json:"last_name"`
 	AvatarUrl string `json:"avatar_url"`
 }
+
+type JoinEventRequest struct {
+	Message *string `json:"message" validate:"omitempty,max=500"`
+}
+
+type JoinEventResponse struct {
+	Status           string                  `json:"status"`
+	EventID          string                  `json:"event_id"`
+	WaitlistPosition *int                    `json:"waitlist_position,omitempty"`
+	JoinRequestID    *string                 `json:"join_request_id,omitempty"`
+	Participant      *GetParticipantResponse `json:"participant"`
+}
+
+type GetEventParticipantResponse struct {
+	GetParticipantResponse
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type GetParticipantsPageResponse struct {
+	Items []GetEventParticipantResponse `json:"items"`
+	Total int                           `json:"total"`
+	Page  int                           `json:"page"`
+	Limit int                           `json:"limit"`
+}
+
+type CreateInviteRequest struct {
+	ExpiresInHours *int `json:"expires_in_hours" validate:"omitempty,min=1,max=8760"`
+	MaxUses        *int `json:"max_uses" validate:"omitempty,min=1"`
+}
+
+type GetInviteResponse struct {
+	ID        string     `json:"id"`
+	Token     string     `json:"token"`
+	URL       string     `json:"url"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int       `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	Revoked   bool       `json:"revoked"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type GetJoinRequestResponse struct {
+	ID        string                 `json:"id"`
+	EventID   string                 `json:"event_id"`
+	Status    string                 `json:"status"`
+	Message   *string                `json:"message"`
+	CreatedAt time.Time              `json:"created_at"`
+	DecidedAt *time.Time             `json:"decided_at"`
+	User      GetParticipantResponse `json:"user"`
+}
+
+type GetCalendarFeedResponse struct {
+	URL       string    `json:"url"`
+	WebcalURL string    `json:"webcal_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Типы записей в потоке события (SSE).
+const (
+	StreamParticipantJoined = "participant.joined"
+	StreamParticipantLeft   = "participant.left"
+	StreamEventUpdated      = "event.updated"
+	StreamEventCancelled    = "event.cancelled"
+)
+
+// Причины появления и ухода участника в потоке события.
+const (
+	ParticipantReasonJoined   = "joined"
+	ParticipantReasonPromoted = "promoted"
+	ParticipantReasonLeft     = "left"
+	ParticipantReasonRemoved  = "removed"
+	ParticipantReasonBanned   = "banned"
+)
+
+type GetParticipantChangeResponse struct {
+	EventID     string                 `json:"event_id"`
+	Participant GetParticipantResponse `json:"participant"`
+	Reason      string                 `json:"reason"`
+}
+
+type GetEventCancelledResponse struct {
+	EventID     string    `json:"event_id"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
@@ -2,17 +2,52 @@ package event
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/jackc/pgx"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrEventNotFound  = errors.New("событие не найдено")
+	ErrEventCancelled = errors.New("событие отменено")
+)
+
+// AfterChange вызывается репозиторием с результатом изменения внутри его
+// транзакции, перед фиксацией. Через него сервис записывает в outbox письма и
+// доменные события, так что они уходят тогда и только тогда, когда изменение
+// сохранено.
+// Ошибка откатывает изменение.
+type AfterChange[T any] func(ctx context.Context, tx pgx.Tx, result T) error
+
+func (f AfterChange[T]) run(ctx context.Context, tx pgx.Tx, result T) error {
+	if f == nil {
+		return nil
+	}
+
+	return f(ctx, tx, result)
+}
+
 type EventRepository interface {
-	GetAll(ctx context.Context) ([]Event, error)
+	GetVisible(ctx context.Context, viewerID uuid.UUID, from, to *time.Time) ([]Event, error)
+	GetByMember(ctx context.Context, userID uuid.UUID, since time.Time) ([]Event, error)
+	GetJoined(ctx context.Context, userID uuid.UUID) ([]Event, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*Event, error)
-	Create(ctx context.Context, model *Event) (*Event, error)
+	Create(ctx context.Context, model *Event, exdates []time.Time, after AfterChange[*Event]) (*Event, error)
+	Update(ctx context.Context, model *Event) (*Event, error)
+	Cancel(ctx context.Context, id uuid.UUID, after AfterChange[[]Event]) ([]Event, error)
+	UpdateCover(ctx context.Context, id uuid.UUID, coverUrl string) (*string, error)
+	IsMember(ctx context.Context, eventID, userID uuid.UUID) (bool, error)
+
+	GetExceptions(ctx context.Context, seriesID uuid.UUID) ([]time.Time, error)
+	GetOccurrences(ctx context.Context, seriesID uuid.UUID, from, to time.Time) ([]Event, error)
+	GetOccurrence(ctx context.Context, seriesID uuid.UUID, startsAt time.Time) (*Event, error)
+	CreateOccurrence(ctx context.Context, series *Event, startsAt time.Time) (*Event, error)
+	CancelOccurrence(ctx context.Context, seriesID uuid.UUID, startsAt time.Time, after AfterChange[*Event]) (*Event, error)
 }
 
 type eventRepository struct {
@@ -23,83 +58,165 @@ func NewEventRepository(pool *pgxpool.Pool) EventRepository {
 	return &eventRepository{pool}
 }
 
-func (r *eventRepository) GetAll(ctx context.Context) ([]Event, error) {
-	query := `
-		SELECT id, creator_id, category_id, title, description,	latitude,
-			longitude, address, starts_at, ends_at, is_public, created_at
-		FROM events
-	`
+// Повторение без своей обложки показывает обложку серии.
+const eventColumns = `
+	e.id, e.creator_id, e.category_id, e.title, e.description, e.latitude,
+	e.longitude, e.address, e.starts_at, e.ends_at, e.is_public, e.max_participants,
+	e.join_mode, e.timezone, e.recurrence_rule, e.series_id, e.occurrence_starts_at,
+	e.cancelled_at,
+	COALESCE(e.cover_url, (SELECT s.cover_url FROM events s WHERE s.id = e.series_id)),
+	e.sequence, e.updated_at, e.created_at
+`
 
-	rows, err := r.pool.Query(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("не удалось получить события: %w", err)
-	}
+func scanEvent(row pgx.Row, event *Event) error {
+	return row.Scan(
+		&event.ID,
+		&event.CreatorID,
+		&event.CategoryID,
+		&event.Title,
+		&event.Description,
+		&event.Latitude,
+		&event.Longitude,
+		&event.Address,
+		&event.StartsAt,
+		&event.EndsAt,
+		&event.IsPublic,
+		&event.MaxParticipants,
+		&event.JoinMode,
+		&event.Timezone,
+		&event.RecurrenceRule,
+		&event.SeriesID,
+		&event.OccurrenceStart,
+		&event.CancelledAt,
+		&event.CoverUrl,
+		&event.Sequence,
+		&event.UpdatedAt,
+		&event.CreatedAt,
+	)
+}
+
+func scanEvents(rows pgx.Rows) ([]Event, error) {
 	defer rows.Close()
 
 	events := make([]Event, 0)
 	for rows.Next() {
 		var event Event
-		err := rows.Scan(
-			&event.ID,
-			&event.CreatorID,
-			&event.CategoryID,
-			&event.Title,
-			&event.Description,
-			&event.Latitude,
-			&event.Longitude,
-			&event.Address,
-			&event.StartsAt,
-			&event.EndsAt,
-			&event.IsPublic,
-			&event.CreatedAt,
-		)
-		if err != nil {
+		if err := scanEvent(rows, &event); err != nil {
 			return nil, fmt.Errorf("не удалось получить событие: %w", err)
 		}
 
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить события: %w", err)
 	}
 
 	return events, nil
 }
 
+// GetVisible возвращает публичные события, а также закрытые события,
+// которые пользователь создал или в которых участвует. Отдельные повторения
+// серий не возвращаются: сервис разворачивает их из правила серии.
+// Если задано окно, обычные события фильтруются по времени начала,
+// а серии возвращаются все, начавшиеся до конца окна.
+func (r *eventRepository) GetVisible(ctx context.Context, viewerID uuid.UUID, from, to *time.Time) ([]Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM events e
+		WHERE e.series_id IS NULL
+			AND (e.is_public
+				OR e.creator_id = $1
+				OR EXISTS (SELECT 1 FROM participants p WHERE p.event_id = e.id AND p.user_id = $1)
+				OR EXISTS (SELECT 1 FROM waitlist w WHERE w.event_id = e.id AND w.user_id = $1))
+			AND ($2::timestamptz IS NULL OR e.recurrence_rule IS NOT NULL OR e.starts_at >= $2)
+			AND ($3::timestamptz IS NULL OR e.starts_at < $3)
+		ORDER BY e.starts_at NULLS LAST, e.created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, viewerID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить события: %w", err)
+	}
+
+	return scanEvents(rows)
+}
+
+// GetByMember возвращает события с известным временем начала, которые
+// пользователь создал или в которых участвует, включая сохранённые повторения
+// серий. Обычные события, начавшиеся раньше since, не возвращаются.
+func (r *eventRepository) GetByMember(ctx context.Context, userID uuid.UUID, since time.Time) ([]Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM events e
+		WHERE e.starts_at IS NOT NULL
+			AND (e.creator_id = $1
+				OR EXISTS (SELECT 1 FROM participants p WHERE p.event_id = e.id AND p.user_id = $1))
+			AND (e.recurrence_rule IS NOT NULL OR e.starts_at >= $2)
+		ORDER BY e.starts_at, e.created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить события пользователя: %w", err)
+	}
+
+	return scanEvents(rows)
+}
+
+// GetJoined возвращает все события, которые пользователь создал или в которых
+// участвует, от новых к старым.
+func (r *eventRepository) GetJoined(ctx context.Context, userID uuid.UUID) ([]Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM events e
+		WHERE e.creator_id = $1
+			OR EXISTS (SELECT 1 FROM participants p WHERE p.event_id = e.id AND p.user_id = $1)
+		ORDER BY e.starts_at DESC NULLS LAST, e.created_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить события пользователя: %w", err)
+	}
+
+	return scanEvents(rows)
+}
+
 func (r *eventRepository) GetByID(ctx context.Context, id uuid.UUID) (*Event, error) {
 	query := `
-		SELECT id, creator_id, category_id, title, description,	latitude,
-			longitude, address, starts_at, ends_at, is_public, created_at
-		FROM events
-		WHERE id = $1
+		SELECT ` + eventColumns + `
+		FROM events e
+		WHERE e.id = $1
 	`
 
 	var event Event
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&event.ID,
-		&event.CreatorID,
-		&event.CategoryID,
-		&event.Title,
-		&event.Description,
-		&event.Latitude,
-		&event.Longitude,
-		&event.Address,
-		&event.StartsAt,
-		&event.EndsAt,
-		&event.IsPublic,
-		&event.CreatedAt,
-	)
-	if err != nil {
+	if err := scanEvent(r.pool.QueryRow(ctx, query, id), &event); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEventNotFound
+		}
 		return nil, fmt.Errorf("не удалось получить событие: %w", err)
 	}
 
 	return &event, nil
 }
 
-func (r *eventRepository) Create(ctx context.Context, model *Event) (*Event, error) {
+func (r *eventRepository) Create(ctx context.Context, model *Event, exdates []time.Time, after AfterChange[*Event]) (*Event, error) {
 	query := `
-		INSERT INTO events
-		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id
+		INSERT INTO events (creator_id, category_id, title, description, latitude,
+			longitude, address, starts_at, ends_at, is_public, max_participants, join_mode,
+			timezone, recurrence_rule)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, updated_at, created_at
 	`
 
-	err := r.pool.QueryRow(ctx, query,
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query,
 		model.CreatorID,
 		model.CategoryID,
 		model.Title,
@@ -110,8 +227,14 @@ func (r *eventRepository) Create(ctx context.Context, model *Event) (*Event, err
 		model.StartsAt,
 		model.EndsAt,
 		model.IsPublic,
+		model.MaxParticipants,
+		model.JoinMode,
+		model.Timezone,
+		model.RecurrenceRule,
 	).Scan(
 		&model.ID,
+		&model.UpdatedAt,
+		&model.CreatedAt,
 	)
 	if err != nil {
 		if isUniqueConstraintError(err) {
@@ -120,11 +243,326 @@ func (r *eventRepository) Create(ctx context.Context, model *Event) (*Event, err
 		return nil, fmt.Errorf("не удалось создать событие: %w", err)
 	}
 
+	for _, exdate := range exdates {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO event_recurrence_exceptions (event_id, occurs_at)
+			VALUES ($1, $2)
+			ON CONFLICT (event_id, occurs_at) DO NOTHING
+		`, model.ID, exdate)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось сохранить исключение повторения: %w", err)
+		}
+	}
+
+	if err := after.run(ctx, tx, model); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось создать событие: %w", err)
+	}
+
 	return model, nil
 }
 
+// Update сохраняет изменяемые поля события и увеличивает его версию (SEQUENCE),
+// чтобы подписанные календари подхватили изменение. Если изменилось время
+// начала, в той же транзакции сбрасываются отметки об отправленных
+// напоминаниях: они относились к прежнему времени.
+func (r *eventRepository) Update(ctx context.Context, model *Event) (*Event, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previousStartsAt *time.Time
+	err = tx.QueryRow(ctx, `SELECT starts_at FROM events WHERE id = $1 FOR UPDATE`, model.ID).Scan(&previousStartsAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("не удалось обновить событие: %w", err)
+	}
+
+	query := `
+		UPDATE events e
+		SET category_id = $2,
+			title = $3,
+			description = $4,
+			latitude = $5,
+			longitude = $6,
+			address = $7,
+			starts_at = $8,
+			ends_at = $9,
+			sequence = e.sequence + 1,
+			updated_at = NOW()
+		WHERE e.id = $1
+		RETURNING ` + eventColumns
+
+	var event Event
+	err = scanEvent(tx.QueryRow(ctx, query,
+		model.ID,
+		model.CategoryID,
+		model.Title,
+		model.Description,
+		model.Latitude,
+		model.Longitude,
+		model.Address,
+		model.StartsAt,
+		model.EndsAt,
+	), &event)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось обновить событие: %w", err)
+	}
+
+	if !sameTime(previousStartsAt, event.StartsAt) {
+		if _, err := tx.Exec(ctx, `DELETE FROM event_reminders WHERE event_id = $1`, model.ID); err != nil {
+			return nil, fmt.Errorf("не удалось сбросить напоминания: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось обновить событие: %w", err)
+	}
+
+	return &event, nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// Cancel помечает событие отменённым. Для серии отменяются и все сохранённые
+// повторения. Возвращает события, отменённые этим вызовом.
+func (r *eventRepository) Cancel(ctx context.Context, id uuid.UUID, after AfterChange[[]Event]) ([]Event, error) {
+	query := `
+		UPDATE events e
+		SET cancelled_at = NOW(),
+			sequence = e.sequence + 1,
+			updated_at = NOW()
+		WHERE (e.id = $1 OR e.series_id = $1) AND e.cancelled_at IS NULL
+		RETURNING ` + eventColumns
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось отменить событие: %w", err)
+	}
+
+	cancelled, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := after.run(ctx, tx, cancelled); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось отменить событие: %w", err)
+	}
+
+	return cancelled, nil
+}
+
+// UpdateCover сохраняет ссылку на обложку события и возвращает предыдущую.
+func (r *eventRepository) UpdateCover(ctx context.Context, id uuid.UUID, coverUrl string) (*string, error) {
+	query := `
+		UPDATE events e
+		SET cover_url = $2, updated_at = NOW()
+		FROM (SELECT id, cover_url FROM events WHERE id = $1 FOR UPDATE) prev
+		WHERE e.id = prev.id
+		RETURNING prev.cover_url
+	`
+
+	var previous *string
+	if err := r.pool.QueryRow(ctx, query, id, coverUrl).Scan(&previous); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("не удалось сохранить обложку события: %w", err)
+	}
+
+	return previous, nil
+}
+
+func (r *eventRepository) IsMember(ctx context.Context, eventID, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM participants WHERE event_id = $1 AND user_id = $2)
+			OR EXISTS (SELECT 1 FROM waitlist WHERE event_id = $1 AND user_id = $2)
+			OR EXISTS (SELECT 1 FROM join_requests WHERE event_id = $1 AND user_id = $2)
+	`
+
+	var isMember bool
+	if err := r.pool.QueryRow(ctx, query, eventID, userID).Scan(&isMember); err != nil {
+		return false, fmt.Errorf("не удалось проверить участие в событии: %w", err)
+	}
+
+	return isMember, nil
+}
+
+func (r *eventRepository) GetExceptions(ctx context.Context, seriesID uuid.UUID) ([]time.Time, error) {
+	query := `
+		SELECT occurs_at
+		FROM event_recurrence_exceptions
+		WHERE event_id = $1
+		ORDER BY occurs_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить исключения повторения: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]time.Time, 0)
+	for rows.Next() {
+		var occursAt time.Time
+		if err := rows.Scan(&occursAt); err != nil {
+			return nil, fmt.Errorf("не удалось получить исключение повторения: %w", err)
+		}
+
+		result = append(result, occursAt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить исключения повторения: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *eventRepository) GetOccurrences(ctx context.Context, seriesID uuid.UUID, from, to time.Time) ([]Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM events e
+		WHERE e.series_id = $1
+			AND e.occurrence_starts_at >= $2
+			AND e.occurrence_starts_at < $3
+	`
+
+	rows, err := r.pool.Query(ctx, query, seriesID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить повторения события: %w", err)
+	}
+
+	return scanEvents(rows)
+}
+
+func (r *eventRepository) GetOccurrence(ctx context.Context, seriesID uuid.UUID, startsAt time.Time) (*Event, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM events e
+		WHERE e.series_id = $1 AND e.occurrence_starts_at = $2
+	`
+
+	var event Event
+	if err := scanEvent(r.pool.QueryRow(ctx, query, seriesID, startsAt), &event); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("не удалось получить повторение события: %w", err)
+	}
+
+	return &event, nil
+}
+
+// CreateOccurrence сохраняет повторение серии как отдельное событие, чтобы
+// участие, лист ожидания и блокировки работали для него так же, как для
+// обычных событий. Повторный вызов возвращает уже созданное повторение.
+func (r *eventRepository) CreateOccurrence(ctx context.Context, series *Event, startsAt time.Time) (*Event, error) {
+	var endsAt *time.Time
+	if series.StartsAt != nil && series.EndsAt != nil {
+		end := startsAt.Add(series.EndsAt.Sub(*series.StartsAt))
+		endsAt = &end
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO events (creator_id, category_id, title, description, latitude,
+			longitude, address, starts_at, ends_at, is_public, max_participants, join_mode,
+			timezone, series_id, occurrence_starts_at)
+		SELECT creator_id, category_id, title, description, latitude,
+			longitude, address, $2, $3, is_public, max_participants, join_mode,
+			timezone, id, $2
+		FROM events
+		WHERE id = $1
+		ON CONFLICT (series_id, occurrence_starts_at) DO NOTHING
+	`, series.ID, startsAt, endsAt)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать повторение события: %w", err)
+	}
+
+	return r.GetOccurrence(ctx, series.ID, startsAt)
+}
+
+// CancelOccurrence добавляет исключение в серию и, если повторение уже
+// сохранено, помечает его отменённым. Версия (SEQUENCE) серии и повторения
+// увеличивается, чтобы подписанные календари подхватили изменение.
+// Возвращает сохранённое повторение или nil.
+func (r *eventRepository) CancelOccurrence(ctx context.Context, seriesID uuid.UUID, startsAt time.Time, after AfterChange[*Event]) (*Event, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO event_recurrence_exceptions (event_id, occurs_at)
+		VALUES ($1, $2)
+		ON CONFLICT (event_id, occurs_at) DO NOTHING
+	`, seriesID, startsAt)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось отменить повторение события: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE events
+		SET sequence = sequence + 1, updated_at = NOW()
+		WHERE id = $1
+	`, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось отменить повторение события: %w", err)
+	}
+
+	var occurrence *Event
+	var event Event
+	err = scanEvent(tx.QueryRow(ctx, `
+		UPDATE events e
+		SET cancelled_at = COALESCE(e.cancelled_at, NOW()),
+			sequence = e.sequence + 1,
+			updated_at = NOW()
+		WHERE e.series_id = $1 AND e.occurrence_starts_at = $2
+		RETURNING `+eventColumns, seriesID, startsAt), &event)
+	switch {
+	case err == nil:
+		occurrence = &event
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("не удалось отменить повторение события: %w", err)
+	}
+
+	if err := after.run(ctx, tx, occurrence); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось отменить повторение события: %w", err)
+	}
+
+	return occurrence, nil
+}
+
 func isUniqueConstraintError(err error) bool {
-	if pgErr, ok := err.(*pgx.PgError); ok {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
 		return pgErr.Code == "23505"
 	}
 	return false
@@ -1,9 +1,17 @@
 package event
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/eventstream"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/ical"
 	validation "github.com/RuLap/meetly-api/meetly/internal/pkg/validator"
 	"github.com/darahayes/go-boom"
 	"github.com/go-chi/chi/v5"
@@ -12,14 +20,27 @@ import (
 
 type Handler struct {
 	service Service
+	stream  *eventstream.Stream
 }
 
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service Service, stream *eventstream.Stream) *Handler {
+	return &Handler{service: service, stream: stream}
 }
 
 func (h *Handler) GetShortEvents(w http.ResponseWriter, r *http.Request) {
-	result, err := h.service.GetShortEvents(r.Context())
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	filter, err := parseShortEventsFilter(r)
+	if err != nil {
+		boom.BadRequest(w, err.Error())
+		return
+	}
+
+	result, err := h.service.GetShortEvents(r.Context(), userID, filter)
 	if err != nil {
 		boom.Internal(w, err)
 		return
@@ -41,9 +62,15 @@ func (h *Handler) GetEventWithDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.GetEventWithDetails(r.Context(), uid)
+	userID, err := currentUserID(r)
 	if err != nil {
-		boom.Internal(w, err)
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetEventWithDetails(r.Context(), uid, userID)
+	if err != nil {
+		h.sendError(w, err)
 		return
 	}
 
@@ -62,17 +89,121 @@ func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := r.Context().Value("user_id").(uuid.UUID)
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
 
 	result, err := h.service.CreateEvent(r.Context(), &req, userID)
 	if err != nil {
-		boom.Internal(w, err)
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	var req UpdateEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.UpdateEvent(r.Context(), eventID, userID, &req)
+	if err != nil {
+		h.sendError(w, err)
 		return
 	}
 
 	h.sendJSON(w, result, http.StatusOK)
 }
 
+func (h *Handler) CancelEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.CancelEvent(r.Context(), eventID, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Stream отдаёт участникам поток изменений события в формате Server-Sent Events.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	isParticipant, err := h.service.IsParticipant(r.Context(), eventID, userID)
+	if err != nil {
+		boom.Internal(w, err)
+		return
+	}
+	if !isParticipant {
+		boom.Forbidden(w, ErrNotParticipant.Error())
+		return
+	}
+
+	// Поток закрывается, когда пользователь перестаёт быть участником: он
+	// получает запись о своём выходе, а переподключиться уже не сможет.
+	h.stream.Serve(w, r, eventID, func(entry *eventstream.Entry) bool {
+		return isParticipantLeft(entry, userID)
+	})
+}
+
+// isParticipantLeft сообщает, что запись потока — выход, удаление или бан
+// пользователя userID.
+func isParticipantLeft(entry *eventstream.Entry, userID uuid.UUID) bool {
+	if entry.Type != StreamParticipantLeft {
+		return false
+	}
+
+	var change GetParticipantChangeResponse
+	if err := json.Unmarshal(entry.Data, &change); err != nil {
+		return false
+	}
+
+	return change.Participant.UserID == userID.String()
+}
+
 func (h *Handler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
 	result, err := h.service.GetAllCategories(r.Context())
 	if err != nil {
@@ -117,19 +248,597 @@ func (h *Handler) AddParticipant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := r.Context().Value("user_id").(uuid.UUID)
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	var req JoinEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	result, err := h.service.AddParticipant(r.Context(), uid, userID, &req)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
 
-	result, err := h.service.AddParticipant(r.Context(), uid, userID)
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) LeaveEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		boom.Internal(w, err)
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.LeaveEvent(r.Context(), eventID, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RemoveParticipant(w http.ResponseWriter, r *http.Request) {
+	eventID, userID, ok := h.parseParticipantParams(w, r)
+	if !ok {
+		return
+	}
+
+	organizerID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.RemoveParticipant(r.Context(), eventID, organizerID, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) BanParticipant(w http.ResponseWriter, r *http.Request) {
+	eventID, userID, ok := h.parseParticipantParams(w, r)
+	if !ok {
+		return
+	}
+
+	organizerID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.BanParticipant(r.Context(), eventID, organizerID, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetParticipants(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	page, limit, err := parsePagination(r)
+	if err != nil {
+		boom.BadRequest(w, err.Error())
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetParticipants(r.Context(), eventID, userID, page, limit)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) JoinOccurrence(w http.ResponseWriter, r *http.Request) {
+	seriesID, startsAt, ok := h.parseOccurrenceParams(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	var req JoinEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	result, err := h.service.JoinOccurrence(r.Context(), seriesID, startsAt, userID, &req)
+	if err != nil {
+		h.sendError(w, err)
 		return
 	}
 
 	h.sendJSON(w, result, http.StatusOK)
 }
 
+func (h *Handler) LeaveOccurrence(w http.ResponseWriter, r *http.Request) {
+	seriesID, startsAt, ok := h.parseOccurrenceParams(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.LeaveOccurrence(r.Context(), seriesID, startsAt, userID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) CancelOccurrence(w http.ResponseWriter, r *http.Request) {
+	seriesID, startsAt, ok := h.parseOccurrenceParams(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.CancelOccurrence(r.Context(), seriesID, userID, startsAt); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	var req CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.CreateInvite(r.Context(), eventID, userID, &req)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusCreated)
+}
+
+func (h *Handler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetInvites(r.Context(), eventID, userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	inviteID, err := uuid.Parse(chi.URLParam(r, "inviteID"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID приглашения")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.RevokeInvite(r.Context(), eventID, userID, inviteID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetInvitePreview(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	if token == "" {
+		boom.BadRequest(w, "токен обязателен")
+		return
+	}
+
+	result, err := h.service.GetInvitePreview(r.Context(), token)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	if token == "" {
+		boom.BadRequest(w, "токен обязателен")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.AcceptInvite(r.Context(), token, userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetJoinRequests(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetJoinRequests(r.Context(), eventID, userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	h.decideJoinRequest(w, r, h.service.ApproveJoinRequest)
+}
+
+func (h *Handler) RejectJoinRequest(w http.ResponseWriter, r *http.Request) {
+	h.decideJoinRequest(w, r, h.service.RejectJoinRequest)
+}
+
+func (h *Handler) decideJoinRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	decide func(ctx context.Context, eventID, organizerID, requestID uuid.UUID) (*GetJoinRequestResponse, error),
+) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	requestID, err := uuid.Parse(chi.URLParam(r, "requestID"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID заявки")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := decide(r.Context(), eventID, userID, requestID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) ExportEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.ExportEvent(r.Context(), eventID, userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendCalendar(w, result, "event-"+eventID.String()+".ics")
+}
+
+func (h *Handler) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetCalendarFeed(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) RotateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.RotateCalendarFeed(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+// ExportCalendarFeed отдаёт личный календарь по секретному токену из ссылки:
+// календарные приложения не умеют передавать заголовок авторизации.
+func (h *Handler) ExportCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	if token == "" {
+		boom.BadRequest(w, "токен необходим")
+		return
+	}
+
+	result, err := h.service.ExportCalendarFeed(r.Context(), token)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendCalendar(w, result, "meetly.ics")
+}
+
+func (h *Handler) parseParticipantParams(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID пользователя")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return eventID, userID, true
+}
+
+// occurrenceLayout — формат даты повторения в URL, как у RECURRENCE-ID в iCalendar.
+const occurrenceLayout = "20060102T150405Z"
+
+func (h *Handler) parseOccurrenceParams(w http.ResponseWriter, r *http.Request) (uuid.UUID, time.Time, bool) {
+	seriesID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return uuid.Nil, time.Time{}, false
+	}
+
+	startsAt, err := time.Parse(occurrenceLayout, chi.URLParam(r, "startsAt"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат даты повторения")
+		return uuid.Nil, time.Time{}, false
+	}
+
+	return seriesID, startsAt, true
+}
+
+const maxEventsWindow = 366 * 24 * time.Hour
+
+func parseShortEventsFilter(r *http.Request) (*GetShortEventsFilter, error) {
+	filter := &GetShortEventsFilter{}
+
+	if value := r.URL.Query().Get("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New("неверный формат from")
+		}
+		filter.From = &from
+	}
+
+	if value := r.URL.Query().Get("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New("неверный формат to")
+		}
+		filter.To = &to
+	}
+
+	if filter.From != nil && filter.To != nil {
+		if !filter.To.After(*filter.From) {
+			return nil, errors.New("to должен быть позже from")
+		}
+		if filter.To.Sub(*filter.From) > maxEventsWindow {
+			return nil, errors.New("окно поиска не может превышать год")
+		}
+	}
+
+	return filter, nil
+}
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+func parsePagination(r *http.Request) (int, int, error) {
+	page, limit := 1, defaultPageLimit
+
+	if value := r.URL.Query().Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, errors.New("неверное значение page")
+		}
+		page = parsed
+	}
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageLimit {
+			return 0, 0, errors.New("неверное значение limit")
+		}
+		limit = parsed
+	}
+
+	return page, limit, nil
+}
+
+func (h *Handler) sendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrEventNotFound),
+		errors.Is(err, ErrOccurrenceNotFound),
+		errors.Is(err, ErrInviteNotFound),
+		errors.Is(err, ErrJoinRequestNotFound),
+		errors.Is(err, ErrCalendarFeedNotFound):
+		boom.NotFound(w, err.Error())
+	case errors.Is(err, ErrAlreadyParticipant),
+		errors.Is(err, ErrAlreadyWaitlisted),
+		errors.Is(err, ErrJoinRequestExists):
+		boom.Conflict(w, err.Error())
+	case errors.Is(err, ErrInviteInvalid),
+		errors.Is(err, ErrEventCancelled):
+		boom.ResourceGone(w, err.Error())
+	case errors.Is(err, ErrInvalidRecurrence),
+		errors.Is(err, ErrRecurrenceStart),
+		errors.Is(err, ErrNotRecurring),
+		errors.Is(err, ErrOccurrenceRequired),
+		errors.Is(err, ErrEventNotScheduled),
+		errors.Is(err, ErrSeriesStartChange),
+		errors.Is(err, ErrSeriesInvite):
+		boom.BadRequest(w, err.Error())
+	case errors.Is(err, ErrNotParticipant),
+		errors.Is(err, ErrOrganizerIsRequired):
+		boom.BadRequest(w, err.Error())
+	case errors.Is(err, ErrNotOrganizer),
+		errors.Is(err, ErrUserBanned),
+		errors.Is(err, ErrInviteRequired),
+		errors.Is(err, ErrJoinRequestRejected):
+		boom.Forbidden(w, err.Error())
+	default:
+		boom.Internal(w, err)
+	}
+}
+
+func currentUserID(r *http.Request) (uuid.UUID, error) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user_id not found in context")
+	}
+
+	return uuid.Parse(userID)
+}
+
 func (h *Handler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(data)
 }
+
+func (h *Handler) sendCalendar(w http.ResponseWriter, data []byte, filename string) {
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
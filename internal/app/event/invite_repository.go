@@ -0,0 +1,190 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrInviteNotFound = errors.New("приглашение не найдено")
+	ErrInviteInvalid  = errors.New("приглашение истекло или больше недействительно")
+)
+
+type InviteRepository interface {
+	Create(ctx context.Context, model *Invite) (*Invite, error)
+	GetByToken(ctx context.Context, token string) (*Invite, error)
+	GetAllByEventID(ctx context.Context, eventID uuid.UUID) ([]Invite, error)
+	Revoke(ctx context.Context, eventID, inviteID uuid.UUID) error
+	Accept(ctx context.Context, token string, userID uuid.UUID, after AfterChange[*JoinResult]) (*Invite, *JoinResult, error)
+}
+
+type inviteRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewInviteRepository(pool *pgxpool.Pool) InviteRepository {
+	return &inviteRepository{pool}
+}
+
+func (r *inviteRepository) Create(ctx context.Context, model *Invite) (*Invite, error) {
+	query := `
+		INSERT INTO event_invites (event_id, created_by, token, expires_at, max_uses)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		model.EventID,
+		model.CreatedBy,
+		model.Token,
+		model.ExpiresAt,
+		model.MaxUses,
+	).Scan(
+		&model.ID,
+		&model.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать приглашение: %w", err)
+	}
+
+	return model, nil
+}
+
+func (r *inviteRepository) GetByToken(ctx context.Context, token string) (*Invite, error) {
+	query := `
+		SELECT id, event_id, created_by, token, expires_at, max_uses, uses, revoked, created_at
+		FROM event_invites
+		WHERE token = $1
+	`
+
+	var invite Invite
+	if err := scanInvite(r.pool.QueryRow(ctx, query, token), &invite); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInviteNotFound
+		}
+		return nil, fmt.Errorf("не удалось получить приглашение: %w", err)
+	}
+
+	return &invite, nil
+}
+
+func (r *inviteRepository) GetAllByEventID(ctx context.Context, eventID uuid.UUID) ([]Invite, error) {
+	query := `
+		SELECT id, event_id, created_by, token, expires_at, max_uses, uses, revoked, created_at
+		FROM event_invites
+		WHERE event_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить приглашения: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Invite, 0)
+	for rows.Next() {
+		var invite Invite
+		if err := scanInvite(rows, &invite); err != nil {
+			return nil, fmt.Errorf("не удалось получить приглашение: %w", err)
+		}
+
+		result = append(result, invite)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить приглашения: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *inviteRepository) Revoke(ctx context.Context, eventID, inviteID uuid.UUID) error {
+	query := `
+		UPDATE event_invites
+		SET revoked = TRUE
+		WHERE id = $1 AND event_id = $2
+	`
+
+	result, err := r.pool.Exec(ctx, query, inviteID, eventID)
+	if err != nil {
+		return fmt.Errorf("не удалось отозвать приглашение: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrInviteNotFound
+	}
+
+	return nil
+}
+
+// Accept засчитывает использование приглашения и добавляет пользователя в событие
+// в одной транзакции, поэтому неудачная попытка вступления не расходует приглашение.
+func (r *inviteRepository) Accept(ctx context.Context, token string, userID uuid.UUID, after AfterChange[*JoinResult]) (*Invite, *JoinResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var invite Invite
+	err = scanInvite(tx.QueryRow(ctx, `
+		UPDATE event_invites
+		SET uses = uses + 1
+		WHERE token = $1
+			AND NOT revoked
+			AND (expires_at IS NULL OR expires_at > NOW())
+			AND (max_uses IS NULL OR uses < max_uses)
+		RETURNING id, event_id, created_by, token, expires_at, max_uses, uses, revoked, created_at
+	`, token), &invite)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrInviteInvalid
+		}
+		return nil, nil, fmt.Errorf("не удалось использовать приглашение: %w", err)
+	}
+
+	// Приглашения на серию, созданные до запрета, не добавляют в саму серию.
+	var recurring bool
+	err = tx.QueryRow(ctx, `SELECT recurrence_rule IS NOT NULL FROM events WHERE id = $1`, invite.EventID).Scan(&recurring)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось использовать приглашение: %w", err)
+	}
+	if recurring {
+		return nil, nil, ErrOccurrenceRequired
+	}
+
+	result, err := joinEventTx(ctx, tx, invite.EventID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := after.run(ctx, tx, result); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("не удалось сохранить участие в событии: %w", err)
+	}
+
+	return &invite, result, nil
+}
+
+func scanInvite(row pgx.Row, invite *Invite) error {
+	return row.Scan(
+		&invite.ID,
+		&invite.EventID,
+		&invite.CreatedBy,
+		&invite.Token,
+		&invite.ExpiresAt,
+		&invite.MaxUses,
+		&invite.Uses,
+		&invite.Revoked,
+		&invite.CreatedAt,
+	)
+}
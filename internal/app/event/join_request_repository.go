@@ -0,0 +1,238 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrJoinRequestNotFound = errors.New("заявка на участие не найдена")
+	ErrJoinRequestExists   = errors.New("заявка на участие уже отправлена")
+	ErrJoinRequestRejected = errors.New("заявка на участие была отклонена организатором")
+)
+
+type JoinRequestRepository interface {
+	Create(ctx context.Context, model *JoinRequest, after AfterChange[*JoinRequest]) (*JoinRequest, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*JoinRequest, error)
+	GetPendingByEventID(ctx context.Context, eventID uuid.UUID) ([]JoinRequest, error)
+	Approve(ctx context.Context, eventID, requestID uuid.UUID, after AfterChange[*JoinResult]) (*JoinRequest, *JoinResult, error)
+	Reject(ctx context.Context, eventID, requestID uuid.UUID, after AfterChange[*JoinRequest]) (*JoinRequest, error)
+}
+
+type joinRequestRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewJoinRequestRepository(pool *pgxpool.Pool) JoinRequestRepository {
+	return &joinRequestRepository{pool}
+}
+
+// Create создаёт заявку или повторно открывает одобренную ранее заявку
+// пользователя, который успел покинуть событие.
+func (r *joinRequestRepository) Create(ctx context.Context, model *JoinRequest, after AfterChange[*JoinRequest]) (*JoinRequest, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, cancelledAt, err := lockEvent(ctx, tx, model.EventID)
+	if err != nil {
+		return nil, err
+	}
+	if cancelledAt != nil {
+		return nil, ErrEventCancelled
+	}
+
+	var banned, member bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM event_bans WHERE event_id = $1 AND user_id = $2),
+			EXISTS (SELECT 1 FROM participants WHERE event_id = $1 AND user_id = $2)
+				OR EXISTS (SELECT 1 FROM waitlist WHERE event_id = $1 AND user_id = $2)
+	`, model.EventID, model.UserID).Scan(&banned, &member)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить участие в событии: %w", err)
+	}
+	if banned {
+		return nil, ErrUserBanned
+	}
+	if member {
+		return nil, ErrAlreadyParticipant
+	}
+
+	var existing JoinRequestStatus
+	err = tx.QueryRow(ctx, `
+		SELECT status FROM join_requests WHERE event_id = $1 AND user_id = $2
+	`, model.EventID, model.UserID).Scan(&existing)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("не удалось получить заявку на участие: %w", err)
+	}
+
+	switch existing {
+	case JoinRequestPending:
+		return nil, ErrJoinRequestExists
+	case JoinRequestRejected:
+		return nil, ErrJoinRequestRejected
+	}
+
+	err = scanJoinRequest(tx.QueryRow(ctx, `
+		INSERT INTO join_requests (event_id, user_id, status, message)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id, user_id) DO UPDATE
+		SET status = EXCLUDED.status,
+			message = EXCLUDED.message,
+			created_at = NOW(),
+			decided_at = NULL
+		RETURNING id, event_id, user_id, status, message, created_at, decided_at
+	`, model.EventID, model.UserID, JoinRequestPending, model.Message), model)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать заявку на участие: %w", err)
+	}
+
+	if err := after.run(ctx, tx, model); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось сохранить заявку на участие: %w", err)
+	}
+
+	return model, nil
+}
+
+func (r *joinRequestRepository) GetByID(ctx context.Context, id uuid.UUID) (*JoinRequest, error) {
+	query := `
+		SELECT id, event_id, user_id, status, message, created_at, decided_at
+		FROM join_requests
+		WHERE id = $1
+	`
+
+	var request JoinRequest
+	if err := scanJoinRequest(r.pool.QueryRow(ctx, query, id), &request); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrJoinRequestNotFound
+		}
+		return nil, fmt.Errorf("не удалось получить заявку на участие: %w", err)
+	}
+
+	return &request, nil
+}
+
+func (r *joinRequestRepository) GetPendingByEventID(ctx context.Context, eventID uuid.UUID) ([]JoinRequest, error) {
+	query := `
+		SELECT id, event_id, user_id, status, message, created_at, decided_at
+		FROM join_requests
+		WHERE event_id = $1 AND status = $2
+		ORDER BY created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, eventID, JoinRequestPending)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить заявки на участие: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]JoinRequest, 0)
+	for rows.Next() {
+		var request JoinRequest
+		if err := scanJoinRequest(rows, &request); err != nil {
+			return nil, fmt.Errorf("не удалось получить заявку на участие: %w", err)
+		}
+
+		result = append(result, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить заявки на участие: %w", err)
+	}
+
+	return result, nil
+}
+
+// Approve одобряет заявку и добавляет пользователя в событие в той же транзакции.
+// Если мест нет, пользователь попадает в лист ожидания.
+func (r *joinRequestRepository) Approve(ctx context.Context, eventID, requestID uuid.UUID, after AfterChange[*JoinResult]) (*JoinRequest, *JoinResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	request, err := decideJoinRequestTx(ctx, tx, eventID, requestID, JoinRequestApproved)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := joinEventTx(ctx, tx, eventID, request.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := after.run(ctx, tx, result); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("не удалось сохранить решение по заявке: %w", err)
+	}
+
+	return request, result, nil
+}
+
+func (r *joinRequestRepository) Reject(ctx context.Context, eventID, requestID uuid.UUID, after AfterChange[*JoinRequest]) (*JoinRequest, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	request, err := decideJoinRequestTx(ctx, tx, eventID, requestID, JoinRequestRejected)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := after.run(ctx, tx, request); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось сохранить решение по заявке: %w", err)
+	}
+
+	return request, nil
+}
+
+func decideJoinRequestTx(ctx context.Context, tx pgx.Tx, eventID, requestID uuid.UUID, status JoinRequestStatus) (*JoinRequest, error) {
+	var request JoinRequest
+	err := scanJoinRequest(tx.QueryRow(ctx, `
+		UPDATE join_requests
+		SET status = $3, decided_at = NOW()
+		WHERE id = $1 AND event_id = $2 AND status = $4
+		RETURNING id, event_id, user_id, status, message, created_at, decided_at
+	`, requestID, eventID, status, JoinRequestPending), &request)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrJoinRequestNotFound
+		}
+		return nil, fmt.Errorf("не удалось обновить заявку на участие: %w", err)
+	}
+
+	return &request, nil
+}
+
+func scanJoinRequest(row pgx.Row, request *JoinRequest) error {
+	return row.Scan(
+		&request.ID,
+		&request.EventID,
+		&request.UserID,
+		&request.Status,
+		&request.Message,
+		&request.CreatedAt,
+		&request.DecidedAt,
+	)
+}
@@ -1,11 +1,15 @@
 package event
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/ical"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
 	"github.com/google/uuid"
 )
 
-func EventToGetShortResponse(model *Event, participantsCount int, creator *GetParticipantResponse, category *GetCategoryResponse) *GetShortEventResponse {
+func EventToGetShortResponse(model *Event, participantsCount, waitlistCount int, creator *GetParticipantResponse, category *GetCategoryResponse) *GetShortEventResponse {
 	return &GetShortEventResponse{
 		ID:                model.ID.String(),
 		Title:             model.Title,
@@ -14,41 +18,70 @@ func EventToGetShortResponse(model *Event, participantsCount int, creator *GetPa
 		StartsAt:          model.StartsAt,
 		EndsAt:            model.EndsAt,
 		IsPublic:          model.IsPublic,
+		MaxParticipants:   model.MaxParticipants,
+		JoinMode:          string(model.JoinMode),
+		SeriesID:          uuidToStringPtr(model.SeriesID),
+		CoverUrl:          model.CoverUrl,
 		ParticipantsCount: participantsCount,
+		WaitlistCount:     waitlistCount,
 		Category:          *category,
 		Creator:           *creator,
 	}
 }
 
-func EventToGetResponse(model *Event, creator *GetParticipantResponse, category *GetCategoryResponse, participants []GetParticipantResponse) *GetEventResponse {
+func EventToGetResponse(model *Event, waitlistCount int, creator *GetParticipantResponse, category *GetCategoryResponse, participants []GetParticipantResponse) *GetEventResponse {
 	return &GetEventResponse{
-		ID:           model.ID.String(),
-		Title:        model.Title,
-		Description:  model.Description,
-		Latitude:     model.Latitude,
-		Longitude:    model.Longitude,
-		Address:      model.Address,
-		StartsAt:     model.StartsAt,
-		EndsAt:       model.EndsAt,
-		IsPublic:     model.IsPublic,
-		CreatedAt:    model.CreatedAt,
-		Creator:      *creator,
-		Category:     *category,
-		Participants: participants,
+		ID:                model.ID.String(),
+		Title:             model.Title,
+		Description:       model.Description,
+		Latitude:          model.Latitude,
+		Longitude:         model.Longitude,
+		Address:           model.Address,
+		StartsAt:          model.StartsAt,
+		EndsAt:            model.EndsAt,
+		IsPublic:          model.IsPublic,
+		MaxParticipants:   model.MaxParticipants,
+		JoinMode:          string(model.JoinMode),
+		Timezone:          model.Timezone,
+		SeriesID:          uuidToStringPtr(model.SeriesID),
+		CancelledAt:       model.CancelledAt,
+		CoverUrl:          model.CoverUrl,
+		ParticipantsCount: len(participants),
+		WaitlistCount:     waitlistCount,
+		CreatedAt:         model.CreatedAt,
+		Creator:           *creator,
+		Category:          *category,
+		Participants:      participants,
 	}
 }
 
 func CreateEventRequestToModel(dto *CreateEventRequest, creatorID uuid.UUID) (*Event, error) {
 	model := &Event{
-		CreatorID:   creatorID,
-		Title:       dto.Title,
-		Description: dto.Description,
-		Latitude:    dto.Latitude,
-		Longitude:   dto.Longitude,
-		Address:     dto.Address,
-		StartsAt:    dto.StartsAt,
-		EndsAt:      dto.EndsAt,
-		IsPublic:    dto.IsPublic,
+		CreatorID:       creatorID,
+		Title:           dto.Title,
+		Description:     dto.Description,
+		Latitude:        dto.Latitude,
+		Longitude:       dto.Longitude,
+		Address:         dto.Address,
+		StartsAt:        dto.StartsAt,
+		EndsAt:          dto.EndsAt,
+		IsPublic:        dto.IsPublic,
+		MaxParticipants: dto.MaxParticipants,
+		JoinMode:        JoinModeOpen,
+		Timezone:        "UTC",
+	}
+
+	if dto.JoinMode != "" {
+		model.JoinMode = JoinMode(dto.JoinMode)
+	}
+
+	if dto.Timezone != "" {
+		model.Timezone = dto.Timezone
+	}
+
+	if dto.Recurrence != nil {
+		rule := dto.Recurrence.Rule
+		model.RecurrenceRule = &rule
 	}
 
 	categoryID, err := uuid.Parse(dto.CategoryID)
@@ -61,6 +94,40 @@ func CreateEventRequestToModel(dto *CreateEventRequest, creatorID uuid.UUID) (*E
 	return model, nil
 }
 
+// ApplyUpdateEventRequest переносит в модель переданные поля запроса.
+func ApplyUpdateEventRequest(model *Event, dto *UpdateEventRequest) error {
+	if dto.CategoryID != nil {
+		categoryID, err := uuid.Parse(*dto.CategoryID)
+		if err != nil {
+			return err
+		}
+		model.CategoryID = categoryID
+	}
+	if dto.Title != nil {
+		model.Title = *dto.Title
+	}
+	if dto.Description != nil {
+		model.Description = *dto.Description
+	}
+	if dto.Latitude != nil {
+		model.Latitude = *dto.Latitude
+	}
+	if dto.Longitude != nil {
+		model.Longitude = *dto.Longitude
+	}
+	if dto.Address != nil {
+		model.Address = dto.Address
+	}
+	if dto.StartsAt != nil {
+		model.StartsAt = dto.StartsAt
+	}
+	if dto.EndsAt != nil {
+		model.EndsAt = dto.EndsAt
+	}
+
+	return nil
+}
+
 func CategoryToGetResponse(model *Category) *GetCategoryResponse {
 	return &GetCategoryResponse{
 		ID:   model.ID.String(),
@@ -76,3 +143,165 @@ func UserInfoToGetParticipantResponse(user *providers.UserInfo) *GetParticipantR
 		AvatarUrl: user.AvatarUrl,
 	}
 }
+
+func JoinResultToResponse(result *JoinResult, participant *GetParticipantResponse) *JoinEventResponse {
+	response := &JoinEventResponse{
+		Status:      string(result.Status),
+		Participant: participant,
+	}
+
+	if result.Status == JoinStatusWaitlisted {
+		position := result.Position
+		response.WaitlistPosition = &position
+	}
+
+	return response
+}
+
+func ParticipantToGetEventParticipantResponse(model *Participant, user *GetParticipantResponse) *GetEventParticipantResponse {
+	return &GetEventParticipantResponse{
+		GetParticipantResponse: *user,
+		JoinedAt:               model.JoinedAt,
+	}
+}
+
+// ParticipantsToGetEventParticipantResponses сопоставляет участников с данными
+// пользователей. Если пользователь не найден, в ответе остаётся только его ID.
+func ParticipantsToGetEventParticipantResponses(models []Participant, users map[string]providers.UserInfo) []GetEventParticipantResponse {
+	result := make([]GetEventParticipantResponse, 0, len(models))
+	for _, model := range models {
+		user := &GetParticipantResponse{UserID: model.UserID.String()}
+		if info, ok := users[model.UserID.String()]; ok {
+			user = UserInfoToGetParticipantResponse(&info)
+		}
+
+		result = append(result, *ParticipantToGetEventParticipantResponse(&model, user))
+	}
+
+	return result
+}
+
+func JoinRequestToJoinEventResponse(model *JoinRequest, participant *GetParticipantResponse) *JoinEventResponse {
+	requestID := model.ID.String()
+
+	return &JoinEventResponse{
+		Status:        string(JoinStatusRequested),
+		JoinRequestID: &requestID,
+		Participant:   participant,
+	}
+}
+
+func CreateInviteRequestToModel(dto *CreateInviteRequest, eventID, createdBy uuid.UUID, token string) *Invite {
+	model := &Invite{
+		EventID:   eventID,
+		CreatedBy: createdBy,
+		Token:     token,
+		MaxUses:   dto.MaxUses,
+	}
+
+	if dto.ExpiresInHours != nil {
+		expiresAt := time.Now().Add(time.Duration(*dto.ExpiresInHours) * time.Hour)
+		model.ExpiresAt = &expiresAt
+	}
+
+	return model
+}
+
+func InviteToGetResponse(model *Invite) *GetInviteResponse {
+	return &GetInviteResponse{
+		ID:        model.ID.String(),
+		Token:     model.Token,
+		URL:       fmt.Sprintf("https://meetlyplus.ru/invite?token=%s", model.Token),
+		ExpiresAt: model.ExpiresAt,
+		MaxUses:   model.MaxUses,
+		Uses:      model.Uses,
+		Revoked:   model.Revoked,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+func JoinRequestToGetResponse(model *JoinRequest, user *GetParticipantResponse) *GetJoinRequestResponse {
+	return &GetJoinRequestResponse{
+		ID:        model.ID.String(),
+		EventID:   model.EventID.String(),
+		Status:    string(model.Status),
+		Message:   model.Message,
+		CreatedAt: model.CreatedAt,
+		DecidedAt: model.DecidedAt,
+		User:      *user,
+	}
+}
+
+func RecurrenceToGetResponse(rule string, exdates []time.Time) *GetRecurrenceResponse {
+	return &GetRecurrenceResponse{
+		Rule:    rule,
+		Exdates: exdates,
+	}
+}
+
+func CalendarFeedToGetResponse(model *CalendarFeed) *GetCalendarFeedResponse {
+	path := fmt.Sprintf("meetlyplus.ru/v1/calendar/%s.ics", model.Token)
+
+	return &GetCalendarFeedResponse{
+		URL:       "https://" + path,
+		WebcalURL: "webcal://" + path,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+// EventToICalEvent переводит событие в VEVENT. Повторения серии получают UID
+// серии и RECURRENCE-ID, чтобы календарь заменил ими экземпляры из RRULE.
+// Время начала события должно быть задано.
+func EventToICalEvent(model *Event, exdates []time.Time) ical.Event {
+	uid := model.ID
+	if model.SeriesID != nil {
+		uid = *model.SeriesID
+	}
+
+	result := ical.Event{
+		UID:          fmt.Sprintf("%s@meetlyplus.ru", uid),
+		Sequence:     model.Sequence,
+		Summary:      model.Title,
+		Description:  model.Description,
+		Latitude:     &model.Latitude,
+		Longitude:    &model.Longitude,
+		URL:          fmt.Sprintf("https://meetlyplus.ru/events/%s", uid),
+		Start:        *model.StartsAt,
+		End:          model.EndsAt,
+		TimeZone:     model.Location(),
+		ExDates:      exdates,
+		RecurrenceID: model.OccurrenceStart,
+		Status:       ical.StatusConfirmed,
+		Created:      model.CreatedAt,
+		LastModified: model.UpdatedAt,
+	}
+
+	if model.Address != nil {
+		result.Location = *model.Address
+	}
+	if model.RecurrenceRule != nil {
+		result.RRule = *model.RecurrenceRule
+	}
+	if model.CancelledAt != nil {
+		result.Status = ical.StatusCancelled
+	}
+
+	return result
+}
+
+func uuidToStringPtr(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+
+	value := id.String()
+	return &value
+}
+
+func ParticipantChangeToResponse(eventID uuid.UUID, participant *GetParticipantResponse, reason string) *GetParticipantChangeResponse {
+	return &GetParticipantChangeResponse{
+		EventID:     eventID.String(),
+		Participant: *participant,
+		Reason:      reason,
+	}
+}
@@ -7,20 +7,49 @@ import (
 )
 
 type Event struct {
-	ID          uuid.UUID  `db:"id"`
-	CreatorID   uuid.UUID  `db:"creator_id"`
-	CategoryID  uuid.UUID  `db:"category_id"`
-	Title       string     `db:"title"`
-	Description string     `db:"description"`
-	Latitude    float64    `db:"latitude"`
-	Longitude   float64    `db:"longitude"`
-	Address     *string    `db:"address"`
-	StartsAt    *time.Time `db:"starts_at"`
-	EndsAt      *time.Time `db:"ends_at"`
-	IsPublic    bool       `db:"is_public"`
-	CreatedAt   time.Time  `db:"created_at"`
+	ID              uuid.UUID  `db:"id"`
+	CreatorID       uuid.UUID  `db:"creator_id"`
+	CategoryID      uuid.UUID  `db:"category_id"`
+	Title           string     `db:"title"`
+	Description     string     `db:"description"`
+	Latitude        float64    `db:"latitude"`
+	Longitude       float64    `db:"longitude"`
+	Address         *string    `db:"address"`
+	StartsAt        *time.Time `db:"starts_at"`
+	EndsAt          *time.Time `db:"ends_at"`
+	IsPublic        bool       `db:"is_public"`
+	MaxParticipants *int       `db:"max_participants"`
+	JoinMode        JoinMode   `db:"join_mode"`
+	Timezone        string     `db:"timezone"`
+	RecurrenceRule  *string    `db:"recurrence_rule"`
+	SeriesID        *uuid.UUID `db:"series_id"`
+	OccurrenceStart *time.Time `db:"occurrence_starts_at"`
+	CancelledAt     *time.Time `db:"cancelled_at"`
+	CoverUrl        *string    `db:"cover_url"`
+	Sequence        int        `db:"sequence"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	CreatedAt       time.Time  `db:"created_at"`
 }
 
+func (e *Event) IsRecurring() bool {
+	return e.RecurrenceRule != nil
+}
+
+func (e *Event) Location() *time.Location {
+	loc, err := time.LoadLocation(e.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+type JoinMode string
+
+const (
+	JoinModeOpen    JoinMode = "open"
+	JoinModeRequest JoinMode = "request"
+)
+
 type Category struct {
 	ID   uuid.UUID `db:"id"`
 	Name string    `db:"name"`
@@ -31,3 +60,75 @@ type Participant struct {
 	EventID  uuid.UUID `db:"event_id"`
 	JoinedAt time.Time `db:"joined_at"`
 }
+
+type WaitlistEntry struct {
+	ID        uuid.UUID `db:"id"`
+	UserID    uuid.UUID `db:"user_id"`
+	EventID   uuid.UUID `db:"event_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type JoinStatus string
+
+const (
+	JoinStatusJoined     JoinStatus = "joined"
+	JoinStatusWaitlisted JoinStatus = "waitlisted"
+	JoinStatusRequested  JoinStatus = "requested"
+)
+
+type JoinResult struct {
+	EventID  uuid.UUID
+	UserID   uuid.UUID
+	Status   JoinStatus
+	Position int
+}
+
+type Invite struct {
+	ID        uuid.UUID  `db:"id"`
+	EventID   uuid.UUID  `db:"event_id"`
+	CreatedBy uuid.UUID  `db:"created_by"`
+	Token     string     `db:"token"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	MaxUses   *int       `db:"max_uses"`
+	Uses      int        `db:"uses"`
+	Revoked   bool       `db:"revoked"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+type JoinRequestStatus string
+
+const (
+	JoinRequestPending  JoinRequestStatus = "pending"
+	JoinRequestApproved JoinRequestStatus = "approved"
+	JoinRequestRejected JoinRequestStatus = "rejected"
+)
+
+type JoinRequest struct {
+	ID        uuid.UUID         `db:"id"`
+	EventID   uuid.UUID         `db:"event_id"`
+	UserID    uuid.UUID         `db:"user_id"`
+	Status    JoinRequestStatus `db:"status"`
+	Message   *string           `db:"message"`
+	CreatedAt time.Time         `db:"created_at"`
+	DecidedAt *time.Time        `db:"decided_at"`
+}
+
+type CalendarFeed struct {
+	ID        uuid.UUID `db:"id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Token     string    `db:"token"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type ReminderKind string
+
+const (
+	Reminder24h ReminderKind = "24h"
+	Reminder1h  ReminderKind = "1h"
+)
+
+type Reminder struct {
+	EventID uuid.UUID    `db:"event_id"`
+	UserID  uuid.UUID    `db:"user_id"`
+	Kind    ReminderKind `db:"kind"`
+}
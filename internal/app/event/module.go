@@ -3,6 +3,7 @@ package event
 import (
 	"log/slog"
 
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/eventstream"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -11,22 +12,41 @@ type Module struct {
 	EventRepo       EventRepository
 	CategoryRepo    CategoryRepository
 	ParticipantRepo ParticipantRepository
+	InviteRepo      InviteRepository
+	JoinRequestRepo JoinRequestRepository
+	CalendarRepo    CalendarFeedRepository
+	ReminderRepo    ReminderRepository
 	Service         Service
 	Handler         Handler
 }
 
-func NewModule(log *slog.Logger, pool *pgxpool.Pool, userProvider providers.UserProvider) *Module {
+func NewModule(
+	log *slog.Logger,
+	pool *pgxpool.Pool,
+	userProvider providers.UserProvider,
+	stream *eventstream.Stream,
+	notifier providers.NotificationProvider,
+	chat providers.ChatProvider,
+) *Module {
 	eventRepo := NewEventRepository(pool)
 	categoryRepo := NewCategoryRepository(pool)
 	participantRepo := NewParticipantRepository(pool)
+	inviteRepo := NewInviteRepository(pool)
+	joinRequestRepo := NewJoinRequestRepository(pool)
+	calendarRepo := NewCalendarFeedRepository(pool)
+	reminderRepo := NewReminderRepository(pool)
 
-	service := NewService(log, eventRepo, categoryRepo, participantRepo, userProvider)
-	handler := NewHandler(service)
+	service := NewService(log, eventRepo, categoryRepo, participantRepo, inviteRepo, joinRequestRepo, calendarRepo, reminderRepo, userProvider, stream, notifier, chat)
+	handler := NewHandler(service, stream)
 
 	return &Module{
 		EventRepo:       eventRepo,
 		CategoryRepo:    categoryRepo,
 		ParticipantRepo: participantRepo,
+		InviteRepo:      inviteRepo,
+		JoinRequestRepo: joinRequestRepo,
+		CalendarRepo:    calendarRepo,
+		ReminderRepo:    reminderRepo,
 		Service:         service,
 		Handler:         *handler,
 	}
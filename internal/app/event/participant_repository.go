@@ -2,16 +2,34 @@ package event
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/google/uuid"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrAlreadyParticipant = errors.New("пользователь уже участвует в событии")
+	ErrAlreadyWaitlisted  = errors.New("пользователь уже в листе ожидания")
+	ErrNotParticipant     = errors.New("пользователь не участвует в событии")
+	ErrUserBanned         = errors.New("пользователь заблокирован в этом событии")
+)
+
 type ParticipantRepository interface {
 	GetByUserID(ctx context.Context, userID uuid.UUID) (*Participant, error)
 	GetAllByEventID(ctx context.Context, eventID uuid.UUID) ([]Participant, error)
+	GetPageByEventID(ctx context.Context, eventID uuid.UUID, limit, offset int) ([]Participant, error)
+	CountByEventID(ctx context.Context, eventID uuid.UUID) (int, error)
+	IsParticipant(ctx context.Context, eventID, userID uuid.UUID) (bool, error)
+	SharesEvent(ctx context.Context, userA, userB uuid.UUID) (bool, error)
+	CountWaitlistByEventID(ctx context.Context, eventID uuid.UUID) (int, error)
 	Create(ctx context.Context, model *Participant) error
+	Join(ctx context.Context, eventID, userID uuid.UUID, after AfterChange[*JoinResult]) (*JoinResult, error)
+	Leave(ctx context.Context, eventID, userID uuid.UUID, after AfterChange[*uuid.UUID]) (*uuid.UUID, bool, error)
+	Ban(ctx context.Context, eventID, userID, bannedBy uuid.UUID, after AfterChange[*uuid.UUID]) (*uuid.UUID, bool, error)
 }
 
 type participantRepository struct {
@@ -40,40 +58,437 @@ func (r *participantRepository) GetByUserID(ctx context.Context, userID uuid.UUI
 
 func (r *participantRepository) GetAllByEventID(ctx context.Context, eventID uuid.UUID) ([]Participant, error) {
 	query := `
-		SELECT user_id, event_id
+		SELECT user_id, event_id, joined_at
 		FROM participants
 		WHERE event_id = $1
+		ORDER BY joined_at
 	`
 
 	rows, err := r.pool.Query(ctx, query, eventID)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось получить участников события: %w", err)
 	}
+	defer rows.Close()
 
 	result := make([]Participant, 0)
 	for rows.Next() {
 		var participant Participant
-		err := rows.Scan(&participant.UserID, &participant.EventID)
+		err := rows.Scan(&participant.UserID, &participant.EventID, &participant.JoinedAt)
 		if err != nil {
 			return nil, fmt.Errorf("не удалось получить участника события по UserID: %w", err)
 		}
+
+		result = append(result, participant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить участников события: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *participantRepository) GetPageByEventID(ctx context.Context, eventID uuid.UUID, limit, offset int) ([]Participant, error) {
+	query := `
+		SELECT user_id, event_id, joined_at
+		FROM participants
+		WHERE event_id = $1
+		ORDER BY joined_at, user_id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.pool.Query(ctx, query, eventID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить участников события: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Participant, 0, limit)
+	for rows.Next() {
+		var participant Participant
+		err := rows.Scan(&participant.UserID, &participant.EventID, &participant.JoinedAt)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось получить участника события: %w", err)
+		}
+
+		result = append(result, participant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить участников события: %w", err)
 	}
 
 	return result, nil
 }
 
+func (r *participantRepository) CountByEventID(ctx context.Context, eventID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM participants
+		WHERE event_id = $1
+	`
+
+	var count int
+	if err := r.pool.QueryRow(ctx, query, eventID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("не удалось получить количество участников события: %w", err)
+	}
+
+	return count, nil
+}
+
+// IsParticipant сообщает, участвует ли пользователь в событии.
+// Организатор считается участником своего события.
+func (r *participantRepository) IsParticipant(ctx context.Context, eventID, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM participants WHERE event_id = $1 AND user_id = $2)
+			OR EXISTS (SELECT 1 FROM events WHERE id = $1 AND creator_id = $2)
+	`
+
+	var isParticipant bool
+	if err := r.pool.QueryRow(ctx, query, eventID, userID).Scan(&isParticipant); err != nil {
+		return false, fmt.Errorf("не удалось проверить участие в событии: %w", err)
+	}
+
+	return isParticipant, nil
+}
+
+// SharesEvent сообщает, есть ли событие, в котором участвуют оба пользователя.
+// Организатор считается участником своего события.
+func (r *participantRepository) SharesEvent(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	query := `
+		WITH members AS (
+			SELECT event_id, user_id FROM participants WHERE user_id IN ($1, $2)
+			UNION
+			SELECT id, creator_id FROM events WHERE creator_id IN ($1, $2)
+		)
+		SELECT EXISTS (
+			SELECT 1
+			FROM members a
+			JOIN members b ON b.event_id = a.event_id
+			WHERE a.user_id = $1 AND b.user_id = $2
+		)
+	`
+
+	var shares bool
+	if err := r.pool.QueryRow(ctx, query, userA, userB).Scan(&shares); err != nil {
+		return false, fmt.Errorf("не удалось проверить общие события: %w", err)
+	}
+
+	return shares, nil
+}
+
+func (r *participantRepository) CountWaitlistByEventID(ctx context.Context, eventID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM waitlist
+		WHERE event_id = $1
+	`
+
+	var count int
+	if err := r.pool.QueryRow(ctx, query, eventID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("не удалось получить размер листа ожидания: %w", err)
+	}
+
+	return count, nil
+}
+
 func (r *participantRepository) Create(ctx context.Context, model *Participant) error {
 	query := `
-		INSERT INTO participants
-		values ($1, $2)
+		INSERT INTO participants (user_id, event_id)
+		VALUES ($1, $2)
 	`
 	_, err := r.pool.Exec(ctx, query, model.UserID, model.EventID)
 	if err != nil {
 		if isUniqueConstraintError(err) {
-			return fmt.Errorf("пользователь уже учавствует в событии: %w", err)
+			return ErrAlreadyParticipant
 		}
 		return fmt.Errorf("не удалось добавить участника к событию: %w", err)
 	}
 
 	return nil
 }
+
+// Join добавляет пользователя в участники или, если мест нет, в конец листа ожидания.
+func (r *participantRepository) Join(ctx context.Context, eventID, userID uuid.UUID, after AfterChange[*JoinResult]) (*JoinResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := joinEventTx(ctx, tx, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := after.run(ctx, tx, result); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось сохранить участие в событии: %w", err)
+	}
+
+	return result, nil
+}
+
+// Leave удаляет пользователя из участников или листа ожидания. Если освободилось
+// место, первый пользователь из листа ожидания переводится в участники,
+// и его ID возвращается вызывающему. Второе значение сообщает, был ли
+// пользователь участником, а не только стоял в листе ожидания.
+func (r *participantRepository) Leave(ctx context.Context, eventID, userID uuid.UUID, after AfterChange[*uuid.UUID]) (*uuid.UUID, bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	maxParticipants, _, err := lockEvent(ctx, tx, eventID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	wasParticipant, err := removeFromEvent(ctx, tx, eventID, userID, true)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var promoted *uuid.UUID
+	if wasParticipant {
+		promoted, err = promoteFromWaitlist(ctx, tx, eventID, maxParticipants)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := after.run(ctx, tx, promoted); err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("не удалось сохранить выход из события: %w", err)
+	}
+
+	return promoted, wasParticipant, nil
+}
+
+// Ban удаляет пользователя из события и запрещает повторное вступление.
+// Как и Leave, возвращает ID пользователя, переведённого из листа ожидания,
+// и признак того, что заблокированный был участником.
+func (r *participantRepository) Ban(ctx context.Context, eventID, userID, bannedBy uuid.UUID, after AfterChange[*uuid.UUID]) (*uuid.UUID, bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	maxParticipants, _, err := lockEvent(ctx, tx, eventID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO event_bans (event_id, user_id, banned_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, user_id) DO NOTHING
+	`, eventID, userID, bannedBy)
+	if err != nil {
+		return nil, false, fmt.Errorf("не удалось заблокировать пользователя: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE join_requests
+		SET status = $3, decided_at = NOW()
+		WHERE event_id = $1 AND user_id = $2 AND status = $4
+	`, eventID, userID, JoinRequestRejected, JoinRequestPending)
+	if err != nil {
+		return nil, false, fmt.Errorf("не удалось отклонить заявку пользователя: %w", err)
+	}
+
+	wasParticipant, err := removeFromEvent(ctx, tx, eventID, userID, false)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var promoted *uuid.UUID
+	if wasParticipant {
+		promoted, err = promoteFromWaitlist(ctx, tx, eventID, maxParticipants)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := after.run(ctx, tx, promoted); err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("не удалось сохранить блокировку пользователя: %w", err)
+	}
+
+	return promoted, wasParticipant, nil
+}
+
+func removeFromEvent(ctx context.Context, tx pgx.Tx, eventID, userID uuid.UUID, mustExist bool) (bool, error) {
+	result, err := tx.Exec(ctx, `
+		DELETE FROM participants WHERE event_id = $1 AND user_id = $2
+	`, eventID, userID)
+	if err != nil {
+		return false, fmt.Errorf("не удалось удалить участника события: %w", err)
+	}
+	if result.RowsAffected() > 0 {
+		return true, nil
+	}
+
+	result, err = tx.Exec(ctx, `
+		DELETE FROM waitlist WHERE event_id = $1 AND user_id = $2
+	`, eventID, userID)
+	if err != nil {
+		return false, fmt.Errorf("не удалось удалить пользователя из листа ожидания: %w", err)
+	}
+	if mustExist && result.RowsAffected() == 0 {
+		return false, ErrNotParticipant
+	}
+
+	return false, nil
+}
+
+// joinEventTx блокирует строку события на время транзакции, поэтому параллельные
+// запросы на одно событие выполняются последовательно и не превышают лимит.
+func joinEventTx(ctx context.Context, tx pgx.Tx, eventID, userID uuid.UUID) (*JoinResult, error) {
+	maxParticipants, cancelledAt, err := lockEvent(ctx, tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if cancelledAt != nil {
+		return nil, ErrEventCancelled
+	}
+
+	var exists bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM event_bans WHERE event_id = $1 AND user_id = $2)
+	`, eventID, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить блокировку пользователя: %w", err)
+	}
+	if exists {
+		return nil, ErrUserBanned
+	}
+
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM participants WHERE event_id = $1 AND user_id = $2)
+	`, eventID, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить участие в событии: %w", err)
+	}
+	if exists {
+		return nil, ErrAlreadyParticipant
+	}
+
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM waitlist WHERE event_id = $1 AND user_id = $2)
+	`, eventID, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить лист ожидания: %w", err)
+	}
+	if exists {
+		return nil, ErrAlreadyWaitlisted
+	}
+
+	var count int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM participants WHERE event_id = $1
+	`, eventID).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить количество участников события: %w", err)
+	}
+
+	result := &JoinResult{EventID: eventID, UserID: userID, Status: JoinStatusJoined}
+	if maxParticipants != nil && count >= *maxParticipants {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO waitlist (user_id, event_id)
+			VALUES ($1, $2)
+		`, userID, eventID)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось добавить пользователя в лист ожидания: %w", err)
+		}
+
+		err = tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM waitlist WHERE event_id = $1
+		`, eventID).Scan(&result.Position)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось получить позицию в листе ожидания: %w", err)
+		}
+
+		result.Status = JoinStatusWaitlisted
+	} else {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO participants (user_id, event_id)
+			VALUES ($1, $2)
+		`, userID, eventID)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось добавить участника к событию: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+func lockEvent(ctx context.Context, tx pgx.Tx, eventID uuid.UUID) (*int, *time.Time, error) {
+	var maxParticipants *int
+	var cancelledAt *time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT max_participants, cancelled_at FROM events WHERE id = $1 FOR UPDATE
+	`, eventID).Scan(&maxParticipants, &cancelledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrEventNotFound
+		}
+		return nil, nil, fmt.Errorf("не удалось получить событие: %w", err)
+	}
+
+	return maxParticipants, cancelledAt, nil
+}
+
+func promoteFromWaitlist(ctx context.Context, tx pgx.Tx, eventID uuid.UUID, maxParticipants *int) (*uuid.UUID, error) {
+	if maxParticipants != nil {
+		var count int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM participants WHERE event_id = $1
+		`, eventID).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось получить количество участников события: %w", err)
+		}
+		if count >= *maxParticipants {
+			return nil, nil
+		}
+	}
+
+	var userID uuid.UUID
+	err := tx.QueryRow(ctx, `
+		DELETE FROM waitlist
+		WHERE id = (
+			SELECT id FROM waitlist
+			WHERE event_id = $1
+			ORDER BY created_at, id
+			LIMIT 1
+		)
+		RETURNING user_id
+	`, eventID).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("не удалось получить пользователя из листа ожидания: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO participants (user_id, event_id)
+		VALUES ($1, $2)
+	`, userID, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось перевести пользователя из листа ожидания: %w", err)
+	}
+
+	return &userID, nil
+}
@@ -0,0 +1,66 @@
+package event
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+)
+
+type eventProvider struct {
+	service Service
+}
+
+func NewEventProvider(service Service) providers.EventProvider {
+	return &eventProvider{service: service}
+}
+
+func (p *eventProvider) IsParticipant(ctx context.Context, eventID, userID uuid.UUID) (bool, error) {
+	return p.service.IsParticipant(ctx, eventID, userID)
+}
+
+func (p *eventProvider) IsOrganizer(ctx context.Context, eventID, userID uuid.UUID) (bool, error) {
+	return p.service.IsOrganizer(ctx, eventID, userID)
+}
+
+func (p *eventProvider) SetCover(ctx context.Context, eventID uuid.UUID, coverUrl string) (*string, error) {
+	return p.service.SetCover(ctx, eventID, coverUrl)
+}
+
+func (p *eventProvider) GetJoinedEvents(ctx context.Context, userID uuid.UUID) ([]providers.EventInfo, error) {
+	events, err := p.service.GetJoinedEvents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]providers.EventInfo, 0, len(events))
+	for _, event := range events {
+		result = append(result, providers.EventInfo{
+			ID:          event.ID,
+			Title:       event.Title,
+			StartsAt:    event.StartsAt,
+			CancelledAt: event.CancelledAt,
+		})
+	}
+
+	return result, nil
+}
+
+func (p *eventProvider) GetEventInfo(ctx context.Context, eventID uuid.UUID) (*providers.EventInfo, error) {
+	event, err := p.service.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &providers.EventInfo{
+		ID:          event.ID,
+		Title:       event.Title,
+		StartsAt:    event.StartsAt,
+		CancelledAt: event.CancelledAt,
+	}, nil
+}
+
+func (p *eventProvider) SharesEvent(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	return p.service.SharesEvent(ctx, userA, userB)
+}
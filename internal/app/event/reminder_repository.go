@@ -0,0 +1,102 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/outbox"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReminderRepository interface {
+	GetDue(ctx context.Context, kind ReminderKind, from, to time.Time, limit int) ([]Reminder, error)
+	MarkSent(ctx context.Context, reminder *Reminder, email *events.EmailEvent) (bool, error)
+}
+
+type reminderRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewReminderRepository(pool *pgxpool.Pool) ReminderRepository {
+	return &reminderRepository{pool}
+}
+
+// GetDue возвращает участников неотменённых событий, начинающихся в интервале
+// (from, to], которым ещё не отправлялось напоминание данного вида.
+// Серии пропускаются: участники есть только у сохранённых повторений.
+func (r *reminderRepository) GetDue(ctx context.Context, kind ReminderKind, from, to time.Time, limit int) ([]Reminder, error) {
+	query := `
+		SELECT p.event_id, p.user_id
+		FROM participants p
+		JOIN events e ON e.id = p.event_id
+		WHERE e.starts_at > $2
+			AND e.starts_at <= $3
+			AND e.cancelled_at IS NULL
+			AND e.recurrence_rule IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM event_reminders er
+				WHERE er.event_id = p.event_id AND er.user_id = p.user_id AND er.kind = $1
+			)
+		ORDER BY e.starts_at, p.event_id
+		LIMIT $4
+	`
+
+	rows, err := r.pool.Query(ctx, query, kind, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить напоминания: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Reminder, 0)
+	for rows.Next() {
+		reminder := Reminder{Kind: kind}
+		if err := rows.Scan(&reminder.EventID, &reminder.UserID); err != nil {
+			return nil, fmt.Errorf("не удалось получить напоминание: %w", err)
+		}
+
+		result = append(result, reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить напоминания: %w", err)
+	}
+
+	return result, nil
+}
+
+// MarkSent отмечает напоминание отправленным и в той же транзакции записывает
+// письмо в outbox. Возвращает false, если отметка уже существует: значит,
+// напоминание отправил другой экземпляр или прошлый запуск.
+func (r *reminderRepository) MarkSent(ctx context.Context, reminder *Reminder, email *events.EmailEvent) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO event_reminders (event_id, user_id, kind)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, user_id, kind) DO NOTHING
+	`
+
+	tag, err := tx.Exec(ctx, query, reminder.EventID, reminder.UserID, reminder.Kind)
+	if err != nil {
+		return false, fmt.Errorf("не удалось отметить напоминание: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := outbox.AddEmail(ctx, tx, email); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("не удалось зафиксировать транзакцию: %w", err)
+	}
+
+	return true, nil
+}
@@ -2,22 +2,105 @@ package event
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"time"
 
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/bus"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/eventstream"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/ical"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/outbox"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/rrule"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type Service interface {
-	GetShortEvents(ctx context.Context) ([]GetShortEventResponse, error)
-	GetEventWithDetails(ctx context.Context, id uuid.UUID) (*GetEventResponse, error)
+	GetShortEvents(ctx context.Context, viewerID uuid.UUID, filter *GetShortEventsFilter) ([]GetShortEventResponse, error)
+	GetEventWithDetails(ctx context.Context, id, viewerID uuid.UUID) (*GetEventResponse, error)
 	CreateEvent(ctx context.Context, req *CreateEventRequest, creatorID uuid.UUID) (*GetEventResponse, error)
+	UpdateEvent(ctx context.Context, id, organizerID uuid.UUID, req *UpdateEventRequest) (*GetEventResponse, error)
+	CancelEvent(ctx context.Context, id, organizerID uuid.UUID) error
 
 	GetAllCategories(ctx context.Context) ([]*GetCategoryResponse, error)
 	GetCategoryByID(ctx context.Context, id uuid.UUID) (*GetCategoryResponse, error)
 
-	AddParticipant(ctx context.Context, userID, eventID uuid.UUID) (*GetParticipantResponse, error)
+	AddParticipant(ctx context.Context, eventID, userID uuid.UUID, req *JoinEventRequest) (*JoinEventResponse, error)
+	LeaveEvent(ctx context.Context, eventID, userID uuid.UUID) error
+	RemoveParticipant(ctx context.Context, eventID, organizerID, userID uuid.UUID) error
+	BanParticipant(ctx context.Context, eventID, organizerID, userID uuid.UUID) error
+	GetParticipants(ctx context.Context, eventID, viewerID uuid.UUID, page, limit int) (*GetParticipantsPageResponse, error)
+	IsParticipant(ctx context.Context, eventID, userID uuid.UUID) (bool, error)
+	IsOrganizer(ctx context.Context, eventID, userID uuid.UUID) (bool, error)
+	SetCover(ctx context.Context, eventID uuid.UUID, coverUrl string) (*string, error)
+	GetJoinedEvents(ctx context.Context, userID uuid.UUID) ([]Event, error)
+	GetEvent(ctx context.Context, id uuid.UUID) (*Event, error)
+	SharesEvent(ctx context.Context, userA, userB uuid.UUID) (bool, error)
+
+	JoinOccurrence(ctx context.Context, seriesID uuid.UUID, startsAt time.Time, userID uuid.UUID, req *JoinEventRequest) (*JoinEventResponse, error)
+	LeaveOccurrence(ctx context.Context, seriesID uuid.UUID, startsAt time.Time, userID uuid.UUID) error
+	CancelOccurrence(ctx context.Context, seriesID, organizerID uuid.UUID, startsAt time.Time) error
+
+	CreateInvite(ctx context.Context, eventID, organizerID uuid.UUID, req *CreateInviteRequest) (*GetInviteResponse, error)
+	GetInvites(ctx context.Context, eventID, organizerID uuid.UUID) ([]GetInviteResponse, error)
+	RevokeInvite(ctx context.Context, eventID, organizerID, inviteID uuid.UUID) error
+	GetInvitePreview(ctx context.Context, token string) (*GetShortEventResponse, error)
+	AcceptInvite(ctx context.Context, token string, userID uuid.UUID) (*JoinEventResponse, error)
+
+	GetJoinRequests(ctx context.Context, eventID, organizerID uuid.UUID) ([]GetJoinRequestResponse, error)
+	ApproveJoinRequest(ctx context.Context, eventID, organizerID, requestID uuid.UUID) (*GetJoinRequestResponse, error)
+	RejectJoinRequest(ctx context.Context, eventID, organizerID, requestID uuid.UUID) (*GetJoinRequestResponse, error)
+
+	ExportEvent(ctx context.Context, id, viewerID uuid.UUID) ([]byte, error)
+	GetCalendarFeed(ctx context.Context, userID uuid.UUID) (*GetCalendarFeedResponse, error)
+	RotateCalendarFeed(ctx context.Context, userID uuid.UUID) (*GetCalendarFeedResponse, error)
+	ExportCalendarFeed(ctx context.Context, token string) ([]byte, error)
+
+	SendDueReminders(ctx context.Context) error
+}
+
+var (
+	ErrNotOrganizer        = errors.New("только организатор может управлять участниками")
+	ErrOrganizerIsRequired = errors.New("организатор не может покинуть собственное событие")
+	ErrInviteRequired      = errors.New("в закрытое событие можно вступить только по приглашению")
+	ErrInvalidRecurrence   = errors.New("неверное правило повторения")
+	ErrRecurrenceStart     = errors.New("для повторяющегося события необходимо время начала")
+	ErrNotRecurring        = errors.New("событие не является повторяющимся")
+	ErrOccurrenceRequired  = errors.New("для повторяющегося события необходимо выбрать дату")
+	ErrOccurrenceNotFound  = errors.New("повторение события не найдено")
+	ErrEventNotScheduled   = errors.New("у события не указано время начала")
+	ErrSeriesStartChange   = errors.New("время начала повторяющегося события изменить нельзя: отмените серию и создайте новую")
+	ErrSeriesInvite        = errors.New("приглашение нельзя создать для повторяющегося события")
+)
+
+const defaultOccurrenceWindow = 30 * 24 * time.Hour
+
+const (
+	calendarProdID          = "-//Meetly//Meetly API//RU"
+	calendarRefreshInterval = time.Hour
+	// calendarFeedHistory — за какой период в прошлом события попадают в календарь.
+	calendarFeedHistory = 90 * 24 * time.Hour
+)
+
+const reminderBatchSize = 500
+
+// reminderWindows описывает напоминания: lead — за сколько до начала
+// отправляется напоминание, skip — если до начала осталось не больше skip,
+// напоминание пропускается, потому что его заменяет более позднее.
+var reminderWindows = []struct {
+	kind    ReminderKind
+	lead    time.Duration
+	skip    time.Duration
+	subject string
+}{
+	{kind: Reminder1h, lead: time.Hour, subject: "Событие начнётся через час"},
+	{kind: Reminder24h, lead: 24 * time.Hour, skip: time.Hour, subject: "Событие начнётся через 24 часа"},
 }
 
 type service struct {
@@ -25,7 +108,14 @@ type service struct {
 	eventRepo       EventRepository
 	categoryRepo    CategoryRepository
 	participantRepo ParticipantRepository
+	inviteRepo      InviteRepository
+	joinRequestRepo JoinRequestRepository
+	calendarRepo    CalendarFeedRepository
+	reminderRepo    ReminderRepository
 	userProvider    providers.UserProvider
+	stream          eventstream.Publisher
+	notifier        providers.NotificationProvider
+	chat            providers.ChatProvider
 }
 
 func NewService(
@@ -33,58 +123,179 @@ func NewService(
 	eventRepo EventRepository,
 	categoryRepo CategoryRepository,
 	participantRepo ParticipantRepository,
+	inviteRepo InviteRepository,
+	joinRequestRepo JoinRequestRepository,
+	calendarRepo CalendarFeedRepository,
+	reminderRepo ReminderRepository,
 	userProvider providers.UserProvider,
+	stream eventstream.Publisher,
+	notifier providers.NotificationProvider,
+	chat providers.ChatProvider,
 ) Service {
 	return &service{
 		log:             log,
 		eventRepo:       eventRepo,
 		categoryRepo:    categoryRepo,
 		participantRepo: participantRepo,
+		inviteRepo:      inviteRepo,
+		joinRequestRepo: joinRequestRepo,
+		calendarRepo:    calendarRepo,
+		reminderRepo:    reminderRepo,
 		userProvider:    userProvider,
+		stream:          stream,
+		notifier:        notifier,
+		chat:            chat,
 	}
 }
 
-func (s *service) GetShortEvents(ctx context.Context) ([]GetShortEventResponse, error) {
-	events, err := s.eventRepo.GetAll(ctx)
+func (s *service) GetShortEvents(ctx context.Context, viewerID uuid.UUID, filter *GetShortEventsFilter) ([]GetShortEventResponse, error) {
+	events, err := s.eventRepo.GetVisible(ctx, viewerID, filter.From, filter.To)
 	if err != nil {
-		s.log.Error("failed to get all events", "error", err)
+		s.log.Error("failed to get visible events", "error", err)
 		return nil, err
 	}
 
+	from := time.Now()
+	if filter.From != nil {
+		from = *filter.From
+	}
+	to := from.Add(defaultOccurrenceWindow)
+	if filter.To != nil {
+		to = *filter.To
+	}
+
 	result := make([]GetShortEventResponse, 0)
 	for _, event := range events {
-		creator, err := s.getParticipantByUserID(ctx, event.CreatorID)
-		if err != nil {
-			return nil, err
+		if !event.IsRecurring() {
+			dto, err := s.getShortEvent(ctx, &event)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, *dto)
+			continue
 		}
 
-		category, err := s.GetCategoryByID(ctx, event.CategoryID)
+		occurrences, err := s.expandOccurrences(ctx, &event, from, to)
 		if err != nil {
 			return nil, err
 		}
 
-		participants, err := s.getParticipantsByEventID(ctx, event.ID)
-		if err != nil {
-			s.log.Error("failed to get event participants", "id", event.CategoryID, "error", err)
-			return nil, err
+		for _, occurrence := range occurrences {
+			dto, err := s.getShortEvent(ctx, &occurrence)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, *dto)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].StartsAt == nil || result[j].StartsAt == nil {
+			return result[j].StartsAt == nil && result[i].StartsAt != nil
 		}
-		participantsCount := len(participants)
+		return result[i].StartsAt.Before(*result[j].StartsAt)
+	})
 
-		dto := EventToGetShortResponse(&event, participantsCount, creator, category)
+	return result, nil
+}
 
-		result = append(result, *dto)
+// expandOccurrences разворачивает серию в повторения внутри окна. Повторения,
+// к которым уже присоединялись, берутся из базы, остальные строятся из серии.
+func (s *service) expandOccurrences(ctx context.Context, series *Event, from, to time.Time) ([]Event, error) {
+	rule, err := rrule.Parse(*series.RecurrenceRule)
+	if err != nil || series.StartsAt == nil {
+		s.log.Error("invalid recurrence rule", "event_id", series.ID, "rule", *series.RecurrenceRule, "error", err)
+		return nil, nil
+	}
+
+	exdates, err := s.eventRepo.GetExceptions(ctx, series.ID)
+	if err != nil {
+		s.log.Error("failed to get recurrence exceptions", "event_id", series.ID, "error", err)
+		return nil, err
+	}
+
+	stored, err := s.eventRepo.GetOccurrences(ctx, series.ID, from, to)
+	if err != nil {
+		s.log.Error("failed to get stored occurrences", "event_id", series.ID, "error", err)
+		return nil, err
+	}
+
+	storedByStart := make(map[int64]Event, len(stored))
+	for _, occurrence := range stored {
+		storedByStart[occurrence.OccurrenceStart.Unix()] = occurrence
+	}
+
+	starts := rule.Between(series.StartsAt.In(series.Location()), from, to, exdates)
+
+	result := make([]Event, 0, len(starts))
+	for _, start := range starts {
+		if occurrence, ok := storedByStart[start.Unix()]; ok {
+			if occurrence.CancelledAt == nil {
+				result = append(result, occurrence)
+			}
+			continue
+		}
+
+		result = append(result, virtualOccurrence(series, start))
 	}
 
 	return result, nil
 }
 
-func (s *service) GetEventWithDetails(ctx context.Context, id uuid.UUID) (*GetEventResponse, error) {
+func (s *service) getShortEvent(ctx context.Context, event *Event) (*GetShortEventResponse, error) {
+	creator, err := s.getParticipantByUserID(ctx, event.CreatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := s.GetCategoryByID(ctx, event.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if isVirtualOccurrence(event) {
+		return EventToGetShortResponse(event, 0, 0, creator, category), nil
+	}
+
+	participantsCount, err := s.participantRepo.CountByEventID(ctx, event.ID)
+	if err != nil {
+		s.log.Error("failed to count event participants", "id", event.ID, "error", err)
+		return nil, err
+	}
+
+	waitlistCount, err := s.participantRepo.CountWaitlistByEventID(ctx, event.ID)
+	if err != nil {
+		s.log.Error("failed to count event waitlist", "id", event.ID, "error", err)
+		return nil, err
+	}
+
+	return EventToGetShortResponse(event, participantsCount, waitlistCount, creator, category), nil
+}
+
+func (s *service) GetEventWithDetails(ctx context.Context, id, viewerID uuid.UUID) (*GetEventResponse, error) {
 	event, err := s.eventRepo.GetByID(ctx, id)
 	if err != nil {
 		s.log.Error("failed to get event by id", "id", id, "error", err)
 		return nil, err
 	}
 
+	canView, err := s.canView(ctx, event, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		s.log.Warn("private event requested by non-member", "id", id, "user_id", viewerID)
+		return nil, ErrEventNotFound
+	}
+
+	return s.getEventResponse(ctx, event)
+}
+
+// getEventResponse собирает полное представление события: организатора,
+// категорию, участников, очередь и правило повторения.
+func (s *service) getEventResponse(ctx context.Context, event *Event) (*GetEventResponse, error) {
 	creator, err := s.getParticipantByUserID(ctx, event.CreatorID)
 	if err != nil {
 		return nil, err
@@ -100,15 +311,62 @@ func (s *service) GetEventWithDetails(ctx context.Context, id uuid.UUID) (*GetEv
 		return nil, err
 	}
 
-	result := EventToGetResponse(event, creator, category, participants)
+	waitlistCount, err := s.participantRepo.CountWaitlistByEventID(ctx, event.ID)
+	if err != nil {
+		s.log.Error("failed to count event waitlist", "id", event.ID, "error", err)
+		return nil, err
+	}
+
+	result := EventToGetResponse(event, waitlistCount, creator, category, participants)
+
+	if event.IsRecurring() {
+		exdates, err := s.eventRepo.GetExceptions(ctx, event.ID)
+		if err != nil {
+			s.log.Error("failed to get recurrence exceptions", "event_id", event.ID, "error", err)
+			return nil, err
+		}
+
+		result.Recurrence = RecurrenceToGetResponse(*event.RecurrenceRule, exdates)
+	}
 
 	return result, nil
 }
 
 func (s *service) CreateEvent(ctx context.Context, req *CreateEventRequest, creatorID uuid.UUID) (*GetEventResponse, error) {
 	model, err := CreateEventRequestToModel(req, creatorID)
+	if err != nil {
+		s.log.Error("failed to map create event request", "error", err)
+		return nil, fmt.Errorf("произошла ошибка")
+	}
+
+	var exdates []time.Time
+	if req.Recurrence != nil {
+		if model.StartsAt == nil {
+			return nil, ErrRecurrenceStart
+		}
+
+		rule, err := rrule.Parse(req.Recurrence.Rule)
+		if err != nil {
+			s.log.Warn("invalid recurrence rule", "rule", req.Recurrence.Rule, "error", err)
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+		}
+
+		normalized := rule.String()
+		model.RecurrenceRule = &normalized
+		exdates = req.Recurrence.Exdates
+	}
 
-	event, err := s.eventRepo.Create(ctx, model)
+	event, err := s.eventRepo.Create(ctx, model, exdates, func(ctx context.Context, tx pgx.Tx, event *Event) error {
+		return s.addDomainEvent(ctx, tx, events.EventCreated{
+			EventID:    event.ID,
+			CreatorID:  event.CreatorID,
+			CategoryID: event.CategoryID,
+			Title:      event.Title,
+			StartsAt:   event.StartsAt,
+			IsPublic:   event.IsPublic,
+			Recurring:  event.IsRecurring(),
+		})
+	})
 	if err != nil {
 		s.log.Error("failed to create event", "error", err)
 		return nil, err
@@ -129,11 +387,98 @@ func (s *service) CreateEvent(ctx context.Context, req *CreateEventRequest, crea
 		return nil, err
 	}
 
-	result := EventToGetResponse(event, creator, category, participants)
+	waitlistCount, err := s.participantRepo.CountWaitlistByEventID(ctx, event.ID)
+	if err != nil {
+		s.log.Error("failed to count event waitlist", "id", event.ID, "error", err)
+		return nil, err
+	}
+
+	result := EventToGetResponse(event, waitlistCount, creator, category, participants)
+
+	return result, nil
+}
+
+func (s *service) UpdateEvent(ctx context.Context, id, organizerID uuid.UUID, req *UpdateEventRequest) (*GetEventResponse, error) {
+	event, err := s.requireOrganizer(ctx, id, organizerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if event.CancelledAt != nil {
+		return nil, ErrEventCancelled
+	}
+
+	// Сохранённые повторения серии привязаны к прежнему времени начала и после
+	// переноса потеряли бы участников и отмены.
+	if event.IsRecurring() && req.StartsAt != nil && (event.StartsAt == nil || !req.StartsAt.Equal(*event.StartsAt)) {
+		return nil, ErrSeriesStartChange
+	}
+
+	if err := ApplyUpdateEventRequest(event, req); err != nil {
+		s.log.Error("failed to map update event request", "error", err)
+		return nil, fmt.Errorf("произошла ошибка")
+	}
+
+	if event.IsRecurring() && event.StartsAt == nil {
+		return nil, ErrRecurrenceStart
+	}
+
+	if req.CategoryID != nil {
+		if _, err := s.GetCategoryByID(ctx, event.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.eventRepo.Update(ctx, event)
+	if err != nil {
+		s.log.Error("failed to update event", "id", id, "error", err)
+		return nil, err
+	}
+
+	s.log.Info("event updated", "event_id", id, "organizer_id", organizerID)
+
+	result, err := s.getEventResponse(ctx, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishStream(ctx, updated.ID, StreamEventUpdated, result)
 
 	return result, nil
 }
 
+func (s *service) CancelEvent(ctx context.Context, id, organizerID uuid.UUID) error {
+	event, err := s.requireOrganizer(ctx, id, organizerID)
+	if err != nil {
+		return err
+	}
+
+	if event.CancelledAt != nil {
+		return ErrEventCancelled
+	}
+
+	cancelled, err := s.eventRepo.Cancel(ctx, id, func(ctx context.Context, tx pgx.Tx, cancelled []Event) error {
+		for _, event := range cancelled {
+			if err := s.addCancelled(ctx, tx, &event, "event_cancelled"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.log.Error("failed to cancel event", "id", id, "error", err)
+		return err
+	}
+
+	s.log.Info("event cancelled", "event_id", id, "organizer_id", organizerID, "events", len(cancelled))
+
+	for _, event := range cancelled {
+		s.notifyCancelled(ctx, &event, "event_cancelled")
+	}
+
+	return nil
+}
+
 func (s *service) GetAllCategories(ctx context.Context) ([]*GetCategoryResponse, error) {
 	categories, err := s.categoryRepo.GetAll(ctx)
 	if err != nil {
@@ -162,53 +507,1140 @@ func (s *service) GetCategoryByID(ctx context.Context, id uuid.UUID) (*GetCatego
 	return result, nil
 }
 
-func (s *service) AddParticipant(ctx context.Context, userID, eventID uuid.UUID) (*GetParticipantResponse, error) {
-	participant := &Participant{
-		UserID:  userID,
-		EventID: eventID,
+func (s *service) AddParticipant(ctx context.Context, eventID, userID uuid.UUID, req *JoinEventRequest) (*JoinEventResponse, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to get event by id", "id", eventID, "error", err)
+		return nil, err
+	}
+
+	if event.IsRecurring() {
+		return nil, ErrOccurrenceRequired
 	}
 
-	err := s.participantRepo.Create(ctx, participant)
+	if !event.IsPublic && event.CreatorID != userID {
+		s.log.Warn("attempt to join private event without invite", "event_id", eventID, "user_id", userID)
+		return nil, ErrInviteRequired
+	}
+
+	return s.join(ctx, event, userID, req)
+}
+
+func (s *service) join(ctx context.Context, event *Event, userID uuid.UUID, req *JoinEventRequest) (*JoinEventResponse, error) {
+	participant, err := s.getParticipantByUserID(ctx, userID)
 	if err != nil {
+		return nil, err
+	}
+
+	if event.JoinMode == JoinModeRequest && event.CreatorID != userID {
+		request, err := s.joinRequestRepo.Create(ctx, &JoinRequest{
+			EventID: event.ID,
+			UserID:  userID,
+			Message: req.Message,
+		}, func(ctx context.Context, tx pgx.Tx, _ *JoinRequest) error {
+			return s.addEventEmail(ctx, tx, event.CreatorID, event, "join_request_created", "Новая заявка на участие")
+		})
+		if err != nil {
+			s.log.Error("failed to create join request", "event_id", event.ID, "user_id", userID, "error", err)
+			return nil, err
+		}
 
+		s.log.Info("join request created", "event_id", event.ID, "user_id", userID, "request_id", request.ID)
+
+		result := JoinRequestToJoinEventResponse(request, participant)
+		result.EventID = event.ID.String()
+		return result, nil
 	}
 
-	result, err := s.getParticipantByUserID(ctx, userID)
+	joinResult, err := s.participantRepo.Join(ctx, event.ID, userID, s.onJoined(event))
 	if err != nil {
-		s.log.Error("failed to get user participant by userID", "user_id", userID)
+		s.log.Error("failed to join event", "event_id", event.ID, "user_id", userID, "error", err)
+		return nil, err
 	}
 
+	s.log.Info("user joined event", "event_id", event.ID, "user_id", userID, "status", joinResult.Status)
+	if joinResult.Status == JoinStatusJoined {
+		s.notifyJoined(ctx, event, userID)
+		s.publishParticipant(ctx, event.ID, StreamParticipantJoined, participant, ParticipantReasonJoined)
+	}
+
+	result := JoinResultToResponse(joinResult, participant)
+	result.EventID = event.ID.String()
 	return result, nil
 }
 
-func (s *service) getParticipantsByEventID(ctx context.Context, eventID uuid.UUID) ([]GetParticipantResponse, error) {
-	participants, err := s.participantRepo.GetAllByEventID(ctx, eventID)
+func (s *service) JoinOccurrence(ctx context.Context, seriesID uuid.UUID, startsAt time.Time, userID uuid.UUID, req *JoinEventRequest) (*JoinEventResponse, error) {
+	series, err := s.getSeries(ctx, seriesID, startsAt)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]GetParticipantResponse, 0, len(participants))
-	for _, participant := range participants {
-		dto, err := s.getParticipantByUserID(ctx, participant.UserID)
-		if err != nil {
-			s.log.Error("failed to get user participant by userID", "user_id", participant.UserID)
-			return nil, err
+	canView, err := s.canView(ctx, series, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		s.log.Warn("attempt to join private occurrence without invite", "event_id", seriesID, "user_id", userID)
+		return nil, ErrInviteRequired
+	}
+
+	occurrence, err := s.eventRepo.CreateOccurrence(ctx, series, startsAt)
+	if err != nil {
+		s.log.Error("failed to create occurrence", "event_id", seriesID, "starts_at", startsAt, "error", err)
+		return nil, err
+	}
+
+	return s.join(ctx, occurrence, userID, req)
+}
+
+func (s *service) LeaveOccurrence(ctx context.Context, seriesID uuid.UUID, startsAt time.Time, userID uuid.UUID) error {
+	occurrence, err := s.eventRepo.GetOccurrence(ctx, seriesID, startsAt)
+	if err != nil {
+		if errors.Is(err, ErrEventNotFound) {
+			return ErrNotParticipant
 		}
+		s.log.Error("failed to get occurrence", "event_id", seriesID, "starts_at", startsAt, "error", err)
+		return err
+	}
 
-		result = append(result, *dto)
+	return s.LeaveEvent(ctx, occurrence.ID, userID)
+}
+
+func (s *service) CancelOccurrence(ctx context.Context, seriesID, organizerID uuid.UUID, startsAt time.Time) error {
+	if _, err := s.requireOrganizer(ctx, seriesID, organizerID); err != nil {
+		return err
 	}
 
-	return result, nil
+	if _, err := s.getSeries(ctx, seriesID, startsAt); err != nil {
+		return err
+	}
+
+	occurrence, err := s.eventRepo.CancelOccurrence(ctx, seriesID, startsAt, func(ctx context.Context, tx pgx.Tx, occurrence *Event) error {
+		if occurrence == nil {
+			return nil
+		}
+		return s.addCancelled(ctx, tx, occurrence, "occurrence_cancelled")
+	})
+	if err != nil {
+		s.log.Error("failed to cancel occurrence", "event_id", seriesID, "starts_at", startsAt, "error", err)
+		return err
+	}
+
+	s.log.Info("occurrence cancelled", "event_id", seriesID, "starts_at", startsAt)
+
+	if occurrence != nil {
+		s.notifyCancelled(ctx, occurrence, "occurrence_cancelled")
+	}
+
+	return nil
 }
 
-func (s *service) getParticipantByUserID(ctx context.Context, userID uuid.UUID) (*GetParticipantResponse, error) {
-	user, err := s.userProvider.GetUserByID(ctx, userID)
+// getSeries возвращает серию и проверяет, что startsAt — одно из её действующих повторений.
+func (s *service) getSeries(ctx context.Context, seriesID uuid.UUID, startsAt time.Time) (*Event, error) {
+	series, err := s.eventRepo.GetByID(ctx, seriesID)
 	if err != nil {
-		s.log.Error("failed to fetch user from user provider", "user_id", userID)
-		return nil, fmt.Errorf("не удалось получить участника: %w", err)
+		s.log.Error("failed to get event by id", "id", seriesID, "error", err)
+		return nil, err
 	}
 
-	result := UserInfoToGetParticipantResponse(user)
+	if !series.IsRecurring() || series.StartsAt == nil {
+		return nil, ErrNotRecurring
+	}
 
-	return result, nil
+	if series.CancelledAt != nil {
+		return nil, ErrEventCancelled
+	}
+
+	rule, err := rrule.Parse(*series.RecurrenceRule)
+	if err != nil {
+		s.log.Error("invalid recurrence rule", "event_id", series.ID, "rule", *series.RecurrenceRule, "error", err)
+		return nil, ErrInvalidRecurrence
+	}
+
+	if !rule.Contains(series.StartsAt.In(series.Location()), startsAt.In(series.Location())) {
+		return nil, ErrOccurrenceNotFound
+	}
+
+	exdates, err := s.eventRepo.GetExceptions(ctx, series.ID)
+	if err != nil {
+		s.log.Error("failed to get recurrence exceptions", "event_id", series.ID, "error", err)
+		return nil, err
+	}
+
+	for _, exdate := range exdates {
+		if exdate.Equal(startsAt) {
+			return nil, ErrEventCancelled
+		}
+	}
+
+	return series, nil
+}
+
+func (s *service) LeaveEvent(ctx context.Context, eventID, userID uuid.UUID) error {
+	promotedUserID, wasParticipant, err := s.participantRepo.Leave(ctx, eventID, userID, s.onPromoted(eventID))
+	if err != nil {
+		s.log.Error("failed to leave event", "event_id", eventID, "user_id", userID, "error", err)
+		return err
+	}
+
+	s.log.Info("user left event", "event_id", eventID, "user_id", userID)
+	if wasParticipant {
+		s.publishParticipantByID(ctx, eventID, StreamParticipantLeft, userID, ParticipantReasonLeft)
+		s.revokeChat(ctx, eventID, userID)
+	}
+
+	if promotedUserID != nil {
+		s.log.Info("user promoted from waitlist", "event_id", eventID, "user_id", *promotedUserID)
+		s.notifyWaitlistPromotion(ctx, eventID, *promotedUserID)
+	}
+
+	return nil
+}
+
+func (s *service) RemoveParticipant(ctx context.Context, eventID, organizerID, userID uuid.UUID) error {
+	if err := s.checkOrganizer(ctx, eventID, organizerID, userID); err != nil {
+		return err
+	}
+
+	promotedUserID, wasParticipant, err := s.participantRepo.Leave(ctx, eventID, userID, s.onPromoted(eventID))
+	if err != nil {
+		s.log.Error("failed to remove participant", "event_id", eventID, "user_id", userID, "error", err)
+		return err
+	}
+
+	s.log.Info("participant removed by organizer", "event_id", eventID, "user_id", userID, "organizer_id", organizerID)
+	if wasParticipant {
+		s.publishParticipantByID(ctx, eventID, StreamParticipantLeft, userID, ParticipantReasonRemoved)
+		s.revokeChat(ctx, eventID, userID)
+	}
+
+	if promotedUserID != nil {
+		s.log.Info("user promoted from waitlist", "event_id", eventID, "user_id", *promotedUserID)
+		s.notifyWaitlistPromotion(ctx, eventID, *promotedUserID)
+	}
+
+	return nil
+}
+
+func (s *service) BanParticipant(ctx context.Context, eventID, organizerID, userID uuid.UUID) error {
+	if err := s.checkOrganizer(ctx, eventID, organizerID, userID); err != nil {
+		return err
+	}
+
+	promotedUserID, wasParticipant, err := s.participantRepo.Ban(ctx, eventID, userID, organizerID, s.onPromoted(eventID))
+	if err != nil {
+		s.log.Error("failed to ban participant", "event_id", eventID, "user_id", userID, "error", err)
+		return err
+	}
+
+	s.log.Info("participant banned by organizer", "event_id", eventID, "user_id", userID, "organizer_id", organizerID)
+	if wasParticipant {
+		s.publishParticipantByID(ctx, eventID, StreamParticipantLeft, userID, ParticipantReasonBanned)
+		s.revokeChat(ctx, eventID, userID)
+	}
+
+	if promotedUserID != nil {
+		s.log.Info("user promoted from waitlist", "event_id", eventID, "user_id", *promotedUserID)
+		s.notifyWaitlistPromotion(ctx, eventID, *promotedUserID)
+	}
+
+	return nil
+}
+
+func (s *service) GetParticipants(ctx context.Context, eventID, viewerID uuid.UUID, page, limit int) (*GetParticipantsPageResponse, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to get event by id", "id", eventID, "error", err)
+		return nil, err
+	}
+
+	canView, err := s.canView(ctx, event, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		s.log.Warn("private event participants requested by non-member", "id", eventID, "user_id", viewerID)
+		return nil, ErrEventNotFound
+	}
+
+	total, err := s.participantRepo.CountByEventID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to count event participants", "id", eventID, "error", err)
+		return nil, err
+	}
+
+	participants, err := s.participantRepo.GetPageByEventID(ctx, eventID, limit, (page-1)*limit)
+	if err != nil {
+		s.log.Error("failed to get event participants page", "id", eventID, "error", err)
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(participants))
+	for _, participant := range participants {
+		userIDs = append(userIDs, participant.UserID)
+	}
+
+	users, err := s.userProvider.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		s.log.Error("failed to get participant users", "id", eventID, "count", len(userIDs), "error", err)
+		return nil, fmt.Errorf("не удалось получить участников: %w", err)
+	}
+
+	return &GetParticipantsPageResponse{
+		Items: ParticipantsToGetEventParticipantResponses(participants, users),
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}, nil
+}
+
+func (s *service) IsParticipant(ctx context.Context, eventID, userID uuid.UUID) (bool, error) {
+	isParticipant, err := s.participantRepo.IsParticipant(ctx, eventID, userID)
+	if err != nil {
+		s.log.Error("failed to check event participation", "event_id", eventID, "user_id", userID, "error", err)
+		return false, err
+	}
+
+	return isParticipant, nil
+}
+
+func (s *service) IsOrganizer(ctx context.Context, eventID, userID uuid.UUID) (bool, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to get event by id", "id", eventID, "error", err)
+		return false, err
+	}
+
+	return event.CreatorID == userID, nil
+}
+
+func (s *service) SetCover(ctx context.Context, eventID uuid.UUID, coverUrl string) (*string, error) {
+	previous, err := s.eventRepo.UpdateCover(ctx, eventID, coverUrl)
+	if err != nil {
+		s.log.Error("failed to update event cover", "event_id", eventID, "error", err)
+		return nil, err
+	}
+
+	s.log.Info("event cover updated", "event_id", eventID)
+	s.publishUpdated(ctx, eventID)
+
+	return previous, nil
+}
+
+func (s *service) SharesEvent(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	shares, err := s.participantRepo.SharesEvent(ctx, userA, userB)
+	if err != nil {
+		s.log.Error("failed to check shared events", "user_a", userA, "user_b", userB, "error", err)
+		return false, err
+	}
+
+	return shares, nil
+}
+
+func (s *service) GetJoinedEvents(ctx context.Context, userID uuid.UUID) ([]Event, error) {
+	events, err := s.eventRepo.GetJoined(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get joined events", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *service) GetEvent(ctx context.Context, id uuid.UUID) (*Event, error) {
+	event, err := s.eventRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get event", "event_id", id, "error", err)
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (s *service) CreateInvite(ctx context.Context, eventID, organizerID uuid.UUID, req *CreateInviteRequest) (*GetInviteResponse, error) {
+	event, err := s.requireOrganizer(ctx, eventID, organizerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Участники записываются на конкретные повторения, а приглашение
+	// добавило бы пользователя в саму серию.
+	if event.IsRecurring() {
+		return nil, ErrSeriesInvite
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		s.log.Error("failed to generate invite token", "error", err)
+		return nil, fmt.Errorf("произошла ошибка")
+	}
+
+	invite, err := s.inviteRepo.Create(ctx, CreateInviteRequestToModel(req, eventID, organizerID, token))
+	if err != nil {
+		s.log.Error("failed to create invite", "event_id", eventID, "error", err)
+		return nil, err
+	}
+
+	s.log.Info("invite created", "event_id", eventID, "invite_id", invite.ID)
+
+	return InviteToGetResponse(invite), nil
+}
+
+func (s *service) GetInvites(ctx context.Context, eventID, organizerID uuid.UUID) ([]GetInviteResponse, error) {
+	if _, err := s.requireOrganizer(ctx, eventID, organizerID); err != nil {
+		return nil, err
+	}
+
+	invites, err := s.inviteRepo.GetAllByEventID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to get invites", "event_id", eventID, "error", err)
+		return nil, err
+	}
+
+	result := make([]GetInviteResponse, 0, len(invites))
+	for _, invite := range invites {
+		result = append(result, *InviteToGetResponse(&invite))
+	}
+
+	return result, nil
+}
+
+func (s *service) RevokeInvite(ctx context.Context, eventID, organizerID, inviteID uuid.UUID) error {
+	if _, err := s.requireOrganizer(ctx, eventID, organizerID); err != nil {
+		return err
+	}
+
+	if err := s.inviteRepo.Revoke(ctx, eventID, inviteID); err != nil {
+		s.log.Error("failed to revoke invite", "event_id", eventID, "invite_id", inviteID, "error", err)
+		return err
+	}
+
+	s.log.Info("invite revoked", "event_id", eventID, "invite_id", inviteID)
+	return nil
+}
+
+func (s *service) GetInvitePreview(ctx context.Context, token string) (*GetShortEventResponse, error) {
+	invite, err := s.inviteRepo.GetByToken(ctx, token)
+	if err != nil {
+		s.log.Warn("invite not found", "error", err)
+		return nil, err
+	}
+
+	if !isInviteUsable(invite) {
+		return nil, ErrInviteInvalid
+	}
+
+	event, err := s.eventRepo.GetByID(ctx, invite.EventID)
+	if err != nil {
+		s.log.Error("failed to get event by id", "id", invite.EventID, "error", err)
+		return nil, err
+	}
+
+	return s.getShortEvent(ctx, event)
+}
+
+func (s *service) AcceptInvite(ctx context.Context, token string, userID uuid.UUID) (*JoinEventResponse, error) {
+	var event *Event
+	invite, joinResult, err := s.inviteRepo.Accept(ctx, token, userID, func(ctx context.Context, tx pgx.Tx, result *JoinResult) error {
+		if result.Status != JoinStatusJoined {
+			return nil
+		}
+
+		var err error
+		event, err = s.eventRepo.GetByID(ctx, result.EventID)
+		if err != nil {
+			s.log.Error("failed to get event for join notification", "event_id", result.EventID, "error", err)
+			return err
+		}
+
+		return s.onJoined(event)(ctx, tx, result)
+	})
+	if err != nil {
+		s.log.Warn("failed to accept invite", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	participant, err := s.getParticipantByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("user joined event by invite",
+		"event_id", invite.EventID,
+		"invite_id", invite.ID,
+		"user_id", userID,
+		"status", joinResult.Status,
+	)
+
+	if joinResult.Status == JoinStatusJoined {
+		s.notifyJoined(ctx, event, userID)
+		s.publishParticipant(ctx, invite.EventID, StreamParticipantJoined, participant, ParticipantReasonJoined)
+	}
+
+	return JoinResultToResponse(joinResult, participant), nil
+}
+
+func (s *service) GetJoinRequests(ctx context.Context, eventID, organizerID uuid.UUID) ([]GetJoinRequestResponse, error) {
+	if _, err := s.requireOrganizer(ctx, eventID, organizerID); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.joinRequestRepo.GetPendingByEventID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to get join requests", "event_id", eventID, "error", err)
+		return nil, err
+	}
+
+	result := make([]GetJoinRequestResponse, 0, len(requests))
+	for _, request := range requests {
+		user, err := s.getParticipantByUserID(ctx, request.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, *JoinRequestToGetResponse(&request, user))
+	}
+
+	return result, nil
+}
+
+func (s *service) ApproveJoinRequest(ctx context.Context, eventID, organizerID, requestID uuid.UUID) (*GetJoinRequestResponse, error) {
+	event, err := s.requireOrganizer(ctx, eventID, organizerID)
+	if err != nil {
+		return nil, err
+	}
+
+	request, joinResult, err := s.joinRequestRepo.Approve(ctx, eventID, requestID, func(ctx context.Context, tx pgx.Tx, result *JoinResult) error {
+		var attachments []events.Attachment
+		if result.Status == JoinStatusJoined {
+			if err := s.addJoined(ctx, tx, result.EventID, result.UserID, ParticipantReasonJoined); err != nil {
+				return err
+			}
+			attachments = s.calendarAttachment(ctx, event)
+		}
+		return s.addEventEmail(ctx, tx, result.UserID, event, "join_request_approved", "Заявка на участие одобрена", attachments...)
+	})
+	if err != nil {
+		s.log.Error("failed to approve join request", "event_id", eventID, "request_id", requestID, "error", err)
+		return nil, err
+	}
+
+	s.log.Info("join request approved",
+		"event_id", eventID,
+		"request_id", requestID,
+		"user_id", request.UserID,
+		"status", joinResult.Status,
+	)
+
+	user, err := s.getParticipantByUserID(ctx, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if joinResult.Status == JoinStatusJoined {
+		s.publishParticipant(ctx, eventID, StreamParticipantJoined, user, ParticipantReasonJoined)
+	}
+
+	return JoinRequestToGetResponse(request, user), nil
+}
+
+func (s *service) RejectJoinRequest(ctx context.Context, eventID, organizerID, requestID uuid.UUID) (*GetJoinRequestResponse, error) {
+	event, err := s.requireOrganizer(ctx, eventID, organizerID)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := s.joinRequestRepo.Reject(ctx, eventID, requestID, func(ctx context.Context, tx pgx.Tx, request *JoinRequest) error {
+		return s.addEventEmail(ctx, tx, request.UserID, event, "join_request_rejected", "Заявка на участие отклонена")
+	})
+	if err != nil {
+		s.log.Error("failed to reject join request", "event_id", eventID, "request_id", requestID, "error", err)
+		return nil, err
+	}
+
+	s.log.Info("join request rejected", "event_id", eventID, "request_id", requestID, "user_id", request.UserID)
+
+	user, err := s.getParticipantByUserID(ctx, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return JoinRequestToGetResponse(request, user), nil
+}
+
+func (s *service) ExportEvent(ctx context.Context, id, viewerID uuid.UUID) ([]byte, error) {
+	event, err := s.eventRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get event by id", "id", id, "error", err)
+		return nil, err
+	}
+
+	canView, err := s.canView(ctx, event, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		s.log.Warn("private event export requested by non-member", "id", id, "user_id", viewerID)
+		return nil, ErrEventNotFound
+	}
+
+	if event.StartsAt == nil {
+		return nil, ErrEventNotScheduled
+	}
+
+	icalEvent, err := s.eventToICal(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCalendar(event.Title, icalEvent).Bytes(), nil
+}
+
+func (s *service) GetCalendarFeed(ctx context.Context, userID uuid.UUID) (*GetCalendarFeedResponse, error) {
+	feed, err := s.calendarRepo.GetByUserID(ctx, userID)
+	if err == nil {
+		return CalendarFeedToGetResponse(feed), nil
+	}
+	if !errors.Is(err, ErrCalendarFeedNotFound) {
+		s.log.Error("failed to get calendar feed", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	return s.RotateCalendarFeed(ctx, userID)
+}
+
+func (s *service) RotateCalendarFeed(ctx context.Context, userID uuid.UUID) (*GetCalendarFeedResponse, error) {
+	token, err := generateInviteToken()
+	if err != nil {
+		s.log.Error("failed to generate calendar token", "error", err)
+		return nil, fmt.Errorf("произошла ошибка")
+	}
+
+	feed, err := s.calendarRepo.Save(ctx, userID, token)
+	if err != nil {
+		s.log.Error("failed to save calendar feed", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	s.log.Info("calendar feed token issued", "user_id", userID)
+
+	return CalendarFeedToGetResponse(feed), nil
+}
+
+// ExportCalendarFeed собирает календарь пользователя из созданных им событий
+// и событий, в которых он участвует. Повторения серии, которую пользователь
+// создал, не выгружаются отдельно: они уже описаны RRULE и EXDATE серии.
+func (s *service) ExportCalendarFeed(ctx context.Context, token string) ([]byte, error) {
+	feed, err := s.calendarRepo.GetByToken(ctx, token)
+	if err != nil {
+		s.log.Warn("calendar feed requested with unknown token", "error", err)
+		return nil, err
+	}
+
+	events, err := s.eventRepo.GetByMember(ctx, feed.UserID, time.Now().Add(-calendarFeedHistory))
+	if err != nil {
+		s.log.Error("failed to get user events for calendar", "user_id", feed.UserID, "error", err)
+		return nil, err
+	}
+
+	series := make(map[uuid.UUID]struct{})
+	for _, event := range events {
+		if event.IsRecurring() {
+			series[event.ID] = struct{}{}
+		}
+	}
+
+	icalEvents := make([]ical.Event, 0, len(events))
+	for _, event := range events {
+		if event.SeriesID != nil {
+			if _, ok := series[*event.SeriesID]; ok {
+				continue
+			}
+		}
+
+		icalEvent, err := s.eventToICal(ctx, &event)
+		if err != nil {
+			return nil, err
+		}
+
+		icalEvents = append(icalEvents, icalEvent)
+	}
+
+	return newCalendar("Meetly", icalEvents...).Bytes(), nil
+}
+
+// SendDueReminders отправляет участникам напоминания о скором начале событий.
+// Отметка о напоминании и письмо в outbox записываются одной транзакцией,
+// поэтому повторный запуск или другой экземпляр напоминание не продублирует,
+// а при сбое не потеряет.
+func (s *service) SendDueReminders(ctx context.Context) error {
+	now := time.Now()
+	eventsByID := make(map[uuid.UUID]*Event)
+	failed := 0
+
+	for _, window := range reminderWindows {
+		due, err := s.reminderRepo.GetDue(ctx, window.kind, now.Add(window.skip), now.Add(window.lead), reminderBatchSize)
+		if err != nil {
+			s.log.Error("failed to get due reminders", "kind", window.kind, "error", err)
+			return err
+		}
+
+		for _, reminder := range due {
+			event, ok := eventsByID[reminder.EventID]
+			if !ok {
+				event, err = s.eventRepo.GetByID(ctx, reminder.EventID)
+				if err != nil {
+					s.log.Error("failed to get event for reminder", "event_id", reminder.EventID, "error", err)
+					failed++
+					continue
+				}
+				eventsByID[reminder.EventID] = event
+			}
+
+			if err := s.sendReminder(ctx, &reminder, event, window.subject); err != nil {
+				failed++
+			}
+		}
+
+		if len(due) > 0 {
+			s.log.Info("event reminders processed", "kind", window.kind, "count", len(due))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("не удалось отправить напоминаний: %d", failed)
+	}
+
+	return nil
+}
+
+func (s *service) sendReminder(ctx context.Context, reminder *Reminder, event *Event, subject string) error {
+	email, err := s.eventEmail(ctx, reminder.UserID, event, "event_reminder", subject)
+	if err != nil {
+		s.log.Error("failed to prepare reminder", "event_id", reminder.EventID, "user_id", reminder.UserID, "kind", reminder.Kind, "error", err)
+		return err
+	}
+
+	marked, err := s.reminderRepo.MarkSent(ctx, reminder, email)
+	if err != nil {
+		s.log.Error("failed to mark reminder", "event_id", reminder.EventID, "user_id", reminder.UserID, "kind", reminder.Kind, "error", err)
+		return err
+	}
+	if !marked {
+		return nil
+	}
+
+	notification := eventNotification(providers.NotificationEventReminder, nil, event)
+	notification.Text = subject
+	s.notify(ctx, reminder.UserID, notification)
+
+	return nil
+}
+
+func (s *service) eventToICal(ctx context.Context, event *Event) (ical.Event, error) {
+	if !event.IsRecurring() {
+		return EventToICalEvent(event, nil), nil
+	}
+
+	exdates, err := s.eventRepo.GetExceptions(ctx, event.ID)
+	if err != nil {
+		s.log.Error("failed to get recurrence exceptions", "event_id", event.ID, "error", err)
+		return ical.Event{}, err
+	}
+
+	return EventToICalEvent(event, exdates), nil
+}
+
+func newCalendar(name string, events ...ical.Event) *ical.Calendar {
+	return &ical.Calendar{
+		ProdID:          calendarProdID,
+		Name:            name,
+		Method:          ical.MethodPublish,
+		RefreshInterval: calendarRefreshInterval,
+		Events:          events,
+	}
+}
+
+func (s *service) checkOrganizer(ctx context.Context, eventID, organizerID, userID uuid.UUID) error {
+	if _, err := s.requireOrganizer(ctx, eventID, organizerID); err != nil {
+		return err
+	}
+
+	if userID == organizerID {
+		return ErrOrganizerIsRequired
+	}
+
+	return nil
+}
+
+func (s *service) requireOrganizer(ctx context.Context, eventID, organizerID uuid.UUID) (*Event, error) {
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to get event by id", "id", eventID, "error", err)
+		return nil, err
+	}
+
+	if event.CreatorID != organizerID {
+		s.log.Warn("non-organizer tried to manage event", "event_id", eventID, "user_id", organizerID)
+		return nil, ErrNotOrganizer
+	}
+
+	return event, nil
+}
+
+func (s *service) canView(ctx context.Context, event *Event, viewerID uuid.UUID) (bool, error) {
+	if event.IsPublic || event.CreatorID == viewerID {
+		return true, nil
+	}
+
+	isMember, err := s.eventRepo.IsMember(ctx, event.ID, viewerID)
+	if err != nil {
+		s.log.Error("failed to check event membership", "event_id", event.ID, "user_id", viewerID, "error", err)
+		return false, err
+	}
+
+	return isMember, nil
+}
+
+func (s *service) notifyWaitlistPromotion(ctx context.Context, eventID, userID uuid.UUID) {
+	s.publishParticipantByID(ctx, eventID, StreamParticipantJoined, userID, ParticipantReasonPromoted)
+
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to get event for waitlist notification", "event_id", eventID, "error", err)
+		return
+	}
+
+	s.notify(ctx, userID, eventNotification(providers.NotificationWaitlistPromoted, nil, event))
+}
+
+func (s *service) notifyJoined(ctx context.Context, event *Event, userID uuid.UUID) {
+	s.notify(ctx, event.CreatorID, eventNotification(providers.NotificationParticipantJoined, &userID, event))
+}
+
+// onJoined записывает событие о вступлении и письмо о записи, если
+// пользователь стал участником, а не попал в лист ожидания.
+func (s *service) onJoined(event *Event) AfterChange[*JoinResult] {
+	return func(ctx context.Context, tx pgx.Tx, result *JoinResult) error {
+		if result.Status != JoinStatusJoined {
+			return nil
+		}
+		if err := s.addJoined(ctx, tx, result.EventID, result.UserID, ParticipantReasonJoined); err != nil {
+			return err
+		}
+		return s.addEventEmail(ctx, tx, result.UserID, event, "event_joined", "Вы записаны на событие", s.calendarAttachment(ctx, event)...)
+	}
+}
+
+// onPromoted записывает событие о вступлении и письмо пользователю,
+// переведённому из листа ожидания на освободившееся место.
+func (s *service) onPromoted(eventID uuid.UUID) AfterChange[*uuid.UUID] {
+	return func(ctx context.Context, tx pgx.Tx, promotedUserID *uuid.UUID) error {
+		if promotedUserID == nil {
+			return nil
+		}
+
+		if err := s.addJoined(ctx, tx, eventID, *promotedUserID, ParticipantReasonPromoted); err != nil {
+			return err
+		}
+
+		event, err := s.eventRepo.GetByID(ctx, eventID)
+		if err != nil {
+			s.log.Error("failed to get event for waitlist notification", "event_id", eventID, "error", err)
+			return err
+		}
+
+		return s.addEventEmail(ctx, tx, *promotedUserID, event, "waitlist_promoted", "Для вас освободилось место", s.calendarAttachment(ctx, event)...)
+	}
+}
+
+// addCancelled записывает событие об отмене и письмо об отмене каждому
+// участнику события.
+func (s *service) addCancelled(ctx context.Context, tx pgx.Tx, event *Event, template string) error {
+	if event.CancelledAt != nil {
+		err := s.addDomainEvent(ctx, tx, events.EventCancelled{
+			EventID:         event.ID,
+			CreatorID:       event.CreatorID,
+			SeriesID:        event.SeriesID,
+			OccurrenceStart: event.OccurrenceStart,
+			CancelledAt:     *event.CancelledAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	participants, err := s.participantRepo.GetAllByEventID(ctx, event.ID)
+	if err != nil {
+		s.log.Error("failed to get participants for cancellation email", "event_id", event.ID, "error", err)
+		return err
+	}
+
+	for _, participant := range participants {
+		if err := s.addEventEmail(ctx, tx, participant.UserID, event, template, "Событие отменено"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notifyCancelled сообщает об отмене в поток события и уведомлением каждому
+// участнику. Письма и доменное событие записываются в транзакции отмены.
+func (s *service) notifyCancelled(ctx context.Context, event *Event, template string) {
+	if event.CancelledAt != nil {
+		s.publishStream(ctx, event.ID, StreamEventCancelled, &GetEventCancelledResponse{
+			EventID:     event.ID.String(),
+			CancelledAt: *event.CancelledAt,
+		})
+	}
+
+	participants, err := s.participantRepo.GetAllByEventID(ctx, event.ID)
+	if err != nil {
+		s.log.Error("failed to get participants for cancellation notification", "event_id", event.ID, "error", err)
+		return
+	}
+
+	for _, participant := range participants {
+		s.notify(ctx, participant.UserID, eventNotification(providers.NotificationEventCancelled, &event.CreatorID, event))
+	}
+}
+
+// notify создаёт уведомление в приложении. Ошибка логируется провайдером
+// и не прерывает основное действие.
+func (s *service) notify(ctx context.Context, userID uuid.UUID, notification *providers.Notification) {
+	if s.notifier == nil {
+		return
+	}
+
+	_ = s.notifier.Notify(ctx, userID, notification)
+}
+
+// revokeChat отключает пользователя, переставшего быть участником, от чата
+// события. Ошибка только логируется: переподключиться к чату он уже не сможет.
+func (s *service) revokeChat(ctx context.Context, eventID, userID uuid.UUID) {
+	if s.chat == nil {
+		return
+	}
+
+	if err := s.chat.RevokeAccess(ctx, eventID, userID); err != nil {
+		s.log.Error("failed to revoke chat access", "event_id", eventID, "user_id", userID, "error", err)
+	}
+}
+
+func eventNotification(typ string, actorID *uuid.UUID, event *Event) *providers.Notification {
+	return &providers.Notification{
+		Type:          typ,
+		ActorID:       actorID,
+		EventID:       &event.ID,
+		EventTitle:    event.Title,
+		EventStartsAt: event.StartsAt,
+	}
+}
+
+func (s *service) publishParticipantByID(ctx context.Context, eventID uuid.UUID, typ string, userID uuid.UUID, reason string) {
+	if s.stream == nil {
+		return
+	}
+
+	participant, err := s.getParticipantByUserID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get participant for event stream", "event_id", eventID, "user_id", userID, "error", err)
+		return
+	}
+
+	s.publishParticipant(ctx, eventID, typ, participant, reason)
+}
+
+func (s *service) publishParticipant(ctx context.Context, eventID uuid.UUID, typ string, participant *GetParticipantResponse, reason string) {
+	s.publishStream(ctx, eventID, typ, ParticipantChangeToResponse(eventID, participant, reason))
+}
+
+// publishUpdated отправляет в поток актуальное представление события.
+func (s *service) publishUpdated(ctx context.Context, eventID uuid.UUID) {
+	if s.stream == nil {
+		return
+	}
+
+	event, err := s.eventRepo.GetByID(ctx, eventID)
+	if err != nil {
+		s.log.Error("failed to get event for event stream", "event_id", eventID, "error", err)
+		return
+	}
+
+	result, err := s.getEventResponse(ctx, event)
+	if err != nil {
+		return
+	}
+
+	s.publishStream(ctx, eventID, StreamEventUpdated, result)
+}
+
+func (s *service) addJoined(ctx context.Context, tx pgx.Tx, eventID, userID uuid.UUID, reason string) error {
+	return s.addDomainEvent(ctx, tx, events.ParticipantJoined{
+		EventID: eventID,
+		UserID:  userID,
+		Reason:  reason,
+	})
+}
+
+// addDomainEvent записывает доменное событие в outbox в транзакции изменения,
+// о котором оно сообщает. Ошибка откатывает изменение.
+func (s *service) addDomainEvent(ctx context.Context, tx pgx.Tx, event events.Event) error {
+	envelope, err := bus.NewEnvelope(event)
+	if err == nil {
+		err = outbox.AddEvent(ctx, tx, envelope)
+	}
+	if err != nil {
+		s.log.Error("failed to add domain event", "event", event.EventName(), "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// publishStream отправляет запись в поток события. Ошибка только логируется:
+// изменение уже сохранено, а клиенты получат его при следующем чтении.
+func (s *service) publishStream(ctx context.Context, eventID uuid.UUID, typ string, data any) {
+	if s.stream == nil {
+		return
+	}
+
+	if err := s.stream.Publish(ctx, eventID, typ, data); err != nil {
+		s.log.Error("failed to publish to event stream", "event_id", eventID, "type", typ, "error", err)
+	}
+}
+
+// calendarAttachment возвращает событие в формате iCalendar для вложения в письмо.
+// Если событие нельзя выгрузить, письмо отправляется без вложения.
+func (s *service) calendarAttachment(ctx context.Context, event *Event) []events.Attachment {
+	if event.StartsAt == nil {
+		return nil
+	}
+
+	icalEvent, err := s.eventToICal(ctx, event)
+	if err != nil {
+		return nil
+	}
+
+	return []events.Attachment{{
+		Filename:    "event.ics",
+		ContentType: ical.ContentType + "; method=" + ical.MethodPublish,
+		Data:        newCalendar(event.Title, icalEvent).Bytes(),
+	}}
+}
+
+// addEventEmail записывает письмо о событии в outbox в транзакции изменения.
+// Ошибка откатывает изменение: иначе пользователь не узнал бы о нём.
+func (s *service) addEventEmail(ctx context.Context, tx pgx.Tx, userID uuid.UUID, event *Event, template, subject string, attachments ...events.Attachment) error {
+	email, err := s.eventEmail(ctx, userID, event, template, subject, attachments...)
+	if err == nil {
+		err = outbox.AddEmail(ctx, tx, email)
+	}
+	if err != nil {
+		s.log.Error("failed to enqueue event email", "user_id", userID, "template", template, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) eventEmail(ctx context.Context, userID uuid.UUID, event *Event, template, subject string, attachments ...events.Attachment) (*events.EmailEvent, error) {
+	email, err := s.userProvider.GetUserEmail(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить email пользователя: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"user_email":  email,
+		"event_id":    event.ID.String(),
+		"event_title": event.Title,
+	}
+	if event.StartsAt != nil {
+		data["event_starts_at"] = event.StartsAt.In(event.Location()).Format("02.01.2006 15:04")
+	}
+
+	return &events.EmailEvent{
+		To:          email,
+		Template:    template,
+		Subject:     subject,
+		Data:        data,
+		Attachments: attachments,
+	}, nil
+}
+
+func (s *service) getParticipantsByEventID(ctx context.Context, eventID uuid.UUID) ([]GetParticipantResponse, error) {
+	participants, err := s.participantRepo.GetAllByEventID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]GetParticipantResponse, 0, len(participants))
+	for _, participant := range participants {
+		dto, err := s.getParticipantByUserID(ctx, participant.UserID)
+		if err != nil {
+			s.log.Error("failed to get user participant by userID", "user_id", participant.UserID)
+			return nil, err
+		}
+
+		result = append(result, *dto)
+	}
+
+	return result, nil
+}
+
+func (s *service) getParticipantByUserID(ctx context.Context, userID uuid.UUID) (*GetParticipantResponse, error) {
+	user, err := s.userProvider.GetUserByID(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to fetch user from user provider", "user_id", userID)
+		return nil, fmt.Errorf("не удалось получить участника: %w", err)
+	}
+
+	result := UserInfoToGetParticipantResponse(user)
+
+	return result, nil
+}
+
+func generateInviteToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func isInviteUsable(invite *Invite) bool {
+	if invite.Revoked {
+		return false
+	}
+	if invite.ExpiresAt != nil && !invite.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	if invite.MaxUses != nil && invite.Uses >= *invite.MaxUses {
+		return false
+	}
+
+	return true
+}
+
+func virtualOccurrence(series *Event, startsAt time.Time) Event {
+	occurrence := *series
+	seriesID := series.ID
+
+	occurrence.StartsAt = &startsAt
+	occurrence.OccurrenceStart = &startsAt
+	occurrence.SeriesID = &seriesID
+	occurrence.RecurrenceRule = nil
+
+	if series.StartsAt != nil && series.EndsAt != nil {
+		endsAt := startsAt.Add(series.EndsAt.Sub(*series.StartsAt))
+		occurrence.EndsAt = &endsAt
+	}
+
+	return occurrence
+}
+
+func isVirtualOccurrence(event *Event) bool {
+	return event.SeriesID != nil && *event.SeriesID == event.ID
 }
@@ -1,18 +1,37 @@
 package mailer
 
 import (
-	"bytes"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"net/smtp"
-	"path/filepath"
-	"text/template"
+	"time"
 )
 
+// ErrTemplate — шаблон письма не найден или не собирается: повторная
+// отправка того же письма не поможет.
+var ErrTemplate = errors.New("email template error")
+
 type MailMessage struct {
-	Email   string
+	Email string
+	// Subject — тема на случай, если шаблон не задаёт её сам.
 	Subject string
 	Type    string
-	Params  map[string]interface{}
+	// Locale — язык письма. Если для него нет шаблонов, письмо уходит на
+	// языке по умолчанию.
+	Locale      string
+	Params      map[string]interface{}
+	Attachments []Attachment
+	// UnsubscribeURL — ссылка для отписки в один клик (RFC 8058). Если задана,
+	// письмо получает заголовки List-Unsubscribe и List-Unsubscribe-Post.
+	UnsubscribeURL string
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
 }
 
 type Mailer struct {
@@ -22,10 +41,10 @@ type Mailer struct {
 	Password    string
 	FromName    string
 	FromAddress string
-	TemplateDir string
+	Templates   *Templates
 }
 
-func NewMailer(host, port, user, password, fromName, fromAddress string) *Mailer {
+func NewMailer(host, port, user, password, fromName, fromAddress string, templates *Templates) *Mailer {
 	return &Mailer{
 		SMTPHost:    host,
 		SMTPPort:    port,
@@ -33,36 +52,44 @@ func NewMailer(host, port, user, password, fromName, fromAddress string) *Mailer
 		Password:    password,
 		FromName:    fromName,
 		FromAddress: fromAddress,
-		TemplateDir: "internal/app/mail/mailer/templates/",
+		Templates:   templates,
 	}
 }
 
 func (m *Mailer) Send(msg MailMessage) error {
-	templatePath := filepath.Join(m.TemplateDir, msg.Type+".html")
-
-	template, err := template.ParseFiles(templatePath)
+	subject, html, err := m.Templates.Render(&msg)
 	if err != nil {
-		return fmt.Errorf("failed to parse template %s: %w", templatePath, err)
+		return err
 	}
-
-	msgHeader := []byte("From: " + m.FromName + " <" + m.FromAddress + ">\r\n" +
-		"To: " + msg.Email + "\r\n" +
-		"Subject: " + msg.Subject + "\r\n" +
-		"MIME-version: 1.0;\r\nContent-Type: text/html; charset=\"UTF-8\";\r\n\r\n")
-
-	var body bytes.Buffer
-	if err := template.Execute(&body, msg.Params); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	if subject != "" {
+		msg.Subject = subject
 	}
 
-	bodyWithHeader := append(msgHeader, body.Bytes()...)
+	message, err := m.buildMessage(msg, html, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
 
 	auth := smtp.PlainAuth("", m.User, m.Password, m.SMTPHost)
 	addr := m.SMTPHost + ":" + m.SMTPPort
 
-	if err := smtp.SendMail(addr, auth, m.FromAddress, []string{msg.Email}, bodyWithHeader); err != nil {
+	if err := smtp.SendMail(addr, auth, m.FromAddress, []string{msg.Email}, message); err != nil {
 		return fmt.Errorf("failed to send email via SMTP: %w", err)
 	}
 
 	return nil
 }
+
+// writeBase64 пишет данные в base64 строками по 76 символов, как требует RFC 2045.
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := w.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+
+	_, err := w.Write([]byte(encoded + "\r\n"))
+	return err
+}
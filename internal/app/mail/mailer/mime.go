@@ -0,0 +1,335 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// header — заголовок письма. Заголовки пишутся в порядке добавления.
+type header struct {
+	name  string
+	value string
+}
+
+// buildMessage собирает письмо целиком: заголовки в кодировке RFC 2047,
+// тело multipart/alternative с текстовой и HTML-версией и, если есть
+// вложения, внешний multipart/mixed.
+func (m *Mailer) buildMessage(msg MailMessage, htmlBody []byte, now time.Time) ([]byte, error) {
+	messageID, err := newMessageID(m.FromAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	headers := []header{
+		{"From", (&mail.Address{Name: m.FromName, Address: m.FromAddress}).String()},
+		{"To", (&mail.Address{Address: msg.Email}).String()},
+		{"Subject", encodeHeader(msg.Subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+	}
+	if msg.UnsubscribeURL != "" {
+		// Почтовый клиент отправляет POST на ссылку из List-Unsubscribe,
+		// не открывая браузер (RFC 8058).
+		headers = append(headers,
+			header{"List-Unsubscribe", "<" + msg.UnsubscribeURL + ">"},
+			header{"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"},
+		)
+	}
+	headers = append(headers, header{"MIME-Version", "1.0"})
+
+	alternativeType, alternative, err := buildAlternative(htmlToText(htmlBody), htmlBody)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, body := alternativeType, alternative
+	if len(msg.Attachments) > 0 {
+		contentType, body, err = buildMixed(alternativeType, alternative, msg.Attachments)
+		if err != nil {
+			return nil, err
+		}
+	}
+	headers = append(headers, header{"Content-Type", contentType})
+
+	var buf bytes.Buffer
+	for _, h := range headers {
+		buf.WriteString(h.name + ": " + h.value + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	return buf.Bytes(), nil
+}
+
+// buildAlternative собирает multipart/alternative: сначала текстовая версия,
+// затем HTML — клиент показывает последнюю из поддерживаемых.
+func buildAlternative(text string, htmlBody []byte) (string, []byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writeQuotedPrintable(writer, "text/plain; charset=UTF-8", []byte(text)); err != nil {
+		return "", nil, err
+	}
+	if err := writeQuotedPrintable(writer, "text/html; charset=UTF-8", htmlBody); err != nil {
+		return "", nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return "", nil, err
+	}
+
+	return multipartType("alternative", writer.Boundary()), body.Bytes(), nil
+}
+
+// buildMixed оборачивает тело письма в multipart/mixed и добавляет вложения
+// в base64.
+func buildMixed(alternativeType string, alternative []byte, attachments []Attachment) (string, []byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {alternativeType}})
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := part.Write(alternative); err != nil {
+		return "", nil, err
+	}
+
+	for _, attachment := range attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachmentType(attachment)},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		if err := writeBase64(part, attachment.Data); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", nil, err
+	}
+
+	return multipartType("mixed", writer.Boundary()), body.Bytes(), nil
+}
+
+func writeQuotedPrintable(writer *multipart.Writer, contentType string, data []byte) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write(data); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func multipartType(subtype, boundary string) string {
+	return mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": boundary})
+}
+
+// attachmentType дополняет тип вложения параметром name: по нему часть
+// клиентов показывает имя файла. Нераспознанный тип заменяется на
+// application/octet-stream.
+func attachmentType(attachment Attachment) string {
+	mediaType, params, err := mime.ParseMediaType(attachment.ContentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	if _, ok := params["name"]; !ok && attachment.Filename != "" {
+		params["name"] = attachment.Filename
+	}
+
+	return mime.FormatMediaType(mediaType, params)
+}
+
+// encodeHeader кодирует значение заголовка по RFC 2047, если в нём есть
+// символы вне ASCII или управляющие символы. Длинное значение разбивается
+// на несколько encoded-word, каждое на своей строке.
+func encodeHeader(value string) string {
+	encoded := mime.BEncoding.Encode("UTF-8", value)
+	if encoded == value {
+		return value
+	}
+
+	return strings.ReplaceAll(encoded, "?= =?", "?=\r\n =?")
+}
+
+// newMessageID возвращает уникальный Message-ID в домене отправителя.
+func newMessageID(fromAddress string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(fromAddress, "@"); at >= 0 && at < len(fromAddress)-1 {
+		domain = fromAddress[at+1:]
+	}
+
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">", nil
+}
+
+// htmlToText строит текстовую версию письма из HTML: содержимое head, style
+// и script отбрасывается, блочные теги превращаются в переносы строк, а
+// ссылки — в «текст (адрес)».
+func htmlToText(src []byte) string {
+	var out strings.Builder
+	s := string(src)
+	skip := 0
+	var href, linkText string
+	inLink := false
+
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			lt = len(s)
+		}
+		if lt > 0 {
+			if skip == 0 {
+				text := collapseSpaces(html.UnescapeString(s[:lt]))
+				out.WriteString(text)
+				if inLink {
+					linkText += text
+				}
+			}
+			s = s[lt:]
+			continue
+		}
+
+		gt := strings.IndexByte(s, '>')
+		if gt < 0 {
+			break
+		}
+		tag := s[1:gt]
+		s = s[gt+1:]
+
+		if strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		closing := strings.HasPrefix(tag, "/")
+		tag = strings.TrimPrefix(tag, "/")
+		fields := strings.Fields(tag)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimRight(fields[0], "/"))
+
+		switch name {
+		case "head", "style", "script", "title":
+			if closing {
+				skip = max(skip-1, 0)
+			} else {
+				skip++
+			}
+		case "br":
+			out.WriteString("\n")
+		case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "table", "tr", "ul", "ol":
+			out.WriteString("\n\n")
+		case "li":
+			if !closing {
+				out.WriteString("\n- ")
+			}
+		case "a":
+			if !closing {
+				href, linkText, inLink = attribute(tag, "href"), "", true
+				continue
+			}
+			if inLink && href != "" && strings.TrimSpace(linkText) != href {
+				out.WriteString(" (" + href + ")")
+			}
+			inLink = false
+		}
+	}
+
+	return tidyLines(out.String())
+}
+
+// attribute возвращает значение атрибута тега в кавычках. Перед именем
+// атрибута может стоять любой пробельный символ, в том числе перенос строки.
+func attribute(tag, name string) string {
+	lower := strings.ToLower(tag)
+	for from := 0; ; {
+		i := strings.Index(lower[from:], name+"=")
+		if i < 0 {
+			return ""
+		}
+		i += from
+		from = i + 1
+		if i == 0 || !strings.ContainsRune(" \t\r\n\f", rune(lower[i-1])) {
+			continue
+		}
+
+		rest := tag[i+len(name)+1:]
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			return ""
+		}
+		end := strings.IndexByte(rest[1:], rest[0])
+		if end < 0 {
+			return ""
+		}
+
+		return html.UnescapeString(rest[1 : end+1])
+	}
+}
+
+// collapseSpaces заменяет любую последовательность пробельных символов
+// одним пробелом, как это делает браузер.
+func collapseSpaces(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+
+	result := strings.Join(fields, " ")
+	if strings.TrimLeft(s[:1], " \t\r\n") == "" {
+		result = " " + result
+	}
+	if strings.TrimLeft(s[len(s)-1:], " \t\r\n") == "" {
+		result += " "
+	}
+	return result
+}
+
+// tidyLines схлопывает пробелы внутри строк, обрезает их по краям и оставляет не больше одной
+// пустой строки подряд.
+func tidyLines(s string) string {
+	lines := strings.Split(s, "\n")
+	result := make([]string, 0, len(lines))
+	blank := true
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank {
+				result = append(result, "")
+			}
+			blank = true
+			continue
+		}
+		result = append(result, line)
+		blank = false
+	}
+
+	return strings.TrimSpace(strings.Join(result, "\n")) + "\n"
+}
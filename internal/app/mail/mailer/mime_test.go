@@ -0,0 +1,276 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeHeader(t *testing.T) {
+	t.Run("ascii is unchanged", func(t *testing.T) {
+		if got := encodeHeader("Welcome to Meetly"); got != "Welcome to Meetly" {
+			t.Errorf("encodeHeader = %q", got)
+		}
+	})
+
+	t.Run("long cyrillic subject is folded", func(t *testing.T) {
+		subject := "Напоминание: встреча «Утренняя пробежка в парке Горького» начнётся завтра в 8:00"
+
+		got := encodeHeader(subject)
+
+		lines := strings.Split(got, "\r\n")
+		if len(lines) < 2 {
+			t.Fatalf("encodeHeader = %q, want several lines", got)
+		}
+		for i, line := range lines {
+			if i > 0 && !strings.HasPrefix(line, " ") {
+				t.Errorf("line %d = %q, want leading space", i, line)
+			}
+			if len(line) > 76 {
+				t.Errorf("line %d is %d bytes long, want at most 76", i, len(line))
+			}
+			if word := strings.TrimSpace(line); !strings.HasPrefix(word, "=?UTF-8?b?") || !strings.HasSuffix(word, "?=") {
+				t.Errorf("line %d = %q, want one encoded-word", i, line)
+			}
+		}
+
+		decoded, err := new(mime.WordDecoder).DecodeHeader(strings.ReplaceAll(got, "\r\n", ""))
+		if err != nil {
+			t.Fatalf("DecodeHeader: %v", err)
+		}
+		if decoded != subject {
+			t.Errorf("decoded = %q, want %q", decoded, subject)
+		}
+	})
+}
+
+func TestAttribute(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{`a href="https://meetly.app/e/1"`, "https://meetly.app/e/1"},
+		{`a href='https://meetly.app/e/1'`, "https://meetly.app/e/1"},
+		{"a\nhref=\"https://meetly.app/e/1\"", "https://meetly.app/e/1"},
+		{"a\tclass=\"button\"\r\n\tHREF=\"https://meetly.app/e/1\"", "https://meetly.app/e/1"},
+		{`a data-href="https://other.example" href="https://meetly.app/e/1"`, "https://meetly.app/e/1"},
+		{`a href="https://meetly.app/?a=1&amp;b=2"`, "https://meetly.app/?a=1&b=2"},
+		{`a data-href="https://other.example"`, ""},
+		{`a href=https://meetly.app`, ""},
+		{`a href="https://meetly.app`, ""},
+		{`a name="top"`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := attribute(tt.tag, "href"); got != tt.want {
+			t.Errorf("attribute(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "head and style are skipped",
+			html: `<html><head><title>Meetly</title><style>p { color: red; }</style></head><body><p>Привет!</p></body></html>`,
+			want: "Привет!\n",
+		},
+		{
+			name: "paragraphs and line breaks",
+			html: "<p>Первая   строка<br>вторая</p>\n<p>Третья</p>",
+			want: "Первая строка\nвторая\n\nТретья\n",
+		},
+		{
+			name: "lists",
+			html: "<ul>\n<li>Москва</li>\n<li>Берлин</li>\n</ul>",
+			want: "- Москва\n- Берлин\n",
+		},
+		{
+			name: "link text and address",
+			html: `<p>Откройте <a href="https://meetly.app/e/1?a=1&amp;b=2">встречу</a>.</p>`,
+			want: "Откройте встречу (https://meetly.app/e/1?a=1&b=2).\n",
+		},
+		{
+			name: "attribute on the next line",
+			html: "<a class=\"button\"\n   href=\"https://meetly.app/confirm\">Подтвердить</a>",
+			want: "Подтвердить (https://meetly.app/confirm)\n",
+		},
+		{
+			name: "link text equal to address",
+			html: `<a href="https://meetly.app">https://meetly.app</a>`,
+			want: "https://meetly.app\n",
+		},
+		{
+			name: "entities and comments",
+			html: "<!-- footer --><p>&laquo;Meetly&raquo; &amp; друзья</p>",
+			want: "«Meetly» & друзья\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText([]byte(tt.html)); got != tt.want {
+				t.Errorf("htmlToText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// readParts разбирает тело multipart и возвращает его части.
+func readParts(t *testing.T, contentType string, body io.Reader) ([]*multipart.Part, [][]byte) {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		t.Fatalf("Content-Type = %q, want multipart", contentType)
+	}
+
+	var parts []*multipart.Part
+	var bodies [][]byte
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextRawPart: %v", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		parts = append(parts, part)
+		bodies = append(bodies, data)
+	}
+	return parts, bodies
+}
+
+func TestBuildMessage(t *testing.T) {
+	m := &Mailer{FromName: "Meetly", FromAddress: "noreply@meetly.app"}
+	htmlBody := []byte(`<p>Встреча начнётся в <a href="https://meetly.app/e/1">19:00</a></p>`)
+	now := time.Date(2025, time.March, 3, 10, 0, 0, 0, time.UTC)
+
+	checkAlternative := func(t *testing.T, contentType string, body []byte) {
+		t.Helper()
+
+		parts, bodies := readParts(t, contentType, bytes.NewReader(body))
+		if len(parts) != 2 {
+			t.Fatalf("got %d alternative parts, want 2", len(parts))
+		}
+
+		wantTypes := []string{"text/plain; charset=UTF-8", "text/html; charset=UTF-8"}
+		wantBodies := []string{"Встреча начнётся в 19:00 (https://meetly.app/e/1)\r\n", string(htmlBody)}
+		for i, part := range parts {
+			if got := part.Header.Get("Content-Type"); got != wantTypes[i] {
+				t.Errorf("part %d Content-Type = %q, want %q", i, got, wantTypes[i])
+			}
+			if got := part.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+				t.Errorf("part %d Content-Transfer-Encoding = %q", i, got)
+			}
+			decoded, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(bodies[i])))
+			if err != nil {
+				t.Fatalf("part %d: %v", i, err)
+			}
+			if string(decoded) != wantBodies[i] {
+				t.Errorf("part %d body = %q, want %q", i, decoded, wantBodies[i])
+			}
+		}
+	}
+
+	t.Run("alternative", func(t *testing.T) {
+		raw, err := m.buildMessage(MailMessage{
+			Email:          "anna@example.com",
+			Subject:        "Напоминание о встрече",
+			UnsubscribeURL: "https://meetly.app/api/notifications/unsubscribe?token=abc",
+		}, htmlBody, now)
+		if err != nil {
+			t.Fatalf("buildMessage: %v", err)
+		}
+
+		msg, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil || subject != "Напоминание о встрече" {
+			t.Errorf("Subject = %q (%v)", subject, err)
+		}
+		if got := msg.Header.Get("From"); got != `"Meetly" <noreply@meetly.app>` {
+			t.Errorf("From = %q", got)
+		}
+		if got := msg.Header.Get("List-Unsubscribe"); got != "<https://meetly.app/api/notifications/unsubscribe?token=abc>" {
+			t.Errorf("List-Unsubscribe = %q", got)
+		}
+		if got := msg.Header.Get("List-Unsubscribe-Post"); got != "List-Unsubscribe=One-Click" {
+			t.Errorf("List-Unsubscribe-Post = %q", got)
+		}
+
+		body, err := io.ReadAll(msg.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mediaType, _, _ := mime.ParseMediaType(msg.Header.Get("Content-Type")); mediaType != "multipart/alternative" {
+			t.Fatalf("Content-Type = %q, want multipart/alternative", mediaType)
+		}
+		checkAlternative(t, msg.Header.Get("Content-Type"), body)
+	})
+
+	t.Run("mixed with attachment", func(t *testing.T) {
+		ics := []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")
+
+		raw, err := m.buildMessage(MailMessage{
+			Email:       "anna@example.com",
+			Subject:     "Вы записаны",
+			Attachments: []Attachment{{Filename: "встреча.ics", ContentType: "text/calendar; method=REQUEST", Data: ics}},
+		}, htmlBody, now)
+		if err != nil {
+			t.Fatalf("buildMessage: %v", err)
+		}
+
+		msg, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("ReadMessage: %v", err)
+		}
+		if msg.Header.Get("List-Unsubscribe") != "" {
+			t.Errorf("List-Unsubscribe = %q, want none", msg.Header.Get("List-Unsubscribe"))
+		}
+		if mediaType, _, _ := mime.ParseMediaType(msg.Header.Get("Content-Type")); mediaType != "multipart/mixed" {
+			t.Fatalf("Content-Type = %q, want multipart/mixed", mediaType)
+		}
+
+		parts, bodies := readParts(t, msg.Header.Get("Content-Type"), msg.Body)
+		if len(parts) != 2 {
+			t.Fatalf("got %d mixed parts, want 2", len(parts))
+		}
+		checkAlternative(t, parts[0].Header.Get("Content-Type"), bodies[0])
+
+		attachment := parts[1]
+		mediaType, params, err := mime.ParseMediaType(attachment.Header.Get("Content-Type"))
+		if err != nil || mediaType != "text/calendar" || params["method"] != "REQUEST" || params["name"] != "встреча.ics" {
+			t.Errorf("attachment Content-Type = %q", attachment.Header.Get("Content-Type"))
+		}
+		if _, params, err := mime.ParseMediaType(attachment.Header.Get("Content-Disposition")); err != nil || params["filename"] != "встреча.ics" {
+			t.Errorf("Content-Disposition = %q", attachment.Header.Get("Content-Disposition"))
+		}
+		if got := attachment.Header.Get("Content-Transfer-Encoding"); got != "base64" {
+			t.Errorf("Content-Transfer-Encoding = %q, want base64", got)
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(bodies[1]), "\r\n", ""))
+		if err != nil || !bytes.Equal(data, ics) {
+			t.Errorf("attachment = %q (%v), want %q", data, err, ics)
+		}
+	})
+}
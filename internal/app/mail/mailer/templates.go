@@ -0,0 +1,142 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"strings"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// DefaultLocale — язык писем, если язык получателя неизвестен или для него
+// нет шаблонов.
+const DefaultLocale = "ru"
+
+// Locales — языки, на которых есть все шаблоны писем.
+var Locales = []string{"ru", "en"}
+
+// TemplateNames — все письма, которые умеет отправлять сервис. Для каждого
+// языка должен быть файл templates/<язык>/<имя>.html.
+var TemplateNames = []string{
+	"email_confirmation",
+	"password_reset",
+	"welcome",
+	"waitlist_promoted",
+	"join_request_created",
+	"join_request_approved",
+	"join_request_rejected",
+	"occurrence_cancelled",
+	"event_cancelled",
+	"event_joined",
+	"event_reminder",
+}
+
+// requiredBlocks — блоки, которые определяет каждая страница письма.
+var requiredBlocks = []string{"subject", "content", "footer"}
+
+// Templates — разобранные шаблоны писем по языкам. Страница письма
+// подставляется в общий макет templates/layouts/base.html, общие фрагменты
+// языка лежат в templates/<язык>/partials.
+type Templates struct {
+	byLocale map[string]map[string]*template.Template
+}
+
+// LoadTemplates разбирает встроенные шаблоны и проверяет, что для каждого
+// языка есть все письма из TemplateNames.
+func LoadTemplates() (*Templates, error) {
+	return ParseTemplates(templateFS)
+}
+
+// ParseTemplates разбирает шаблоны из fsys с той же структурой каталогов,
+// что и встроенные.
+func ParseTemplates(fsys fs.FS) (*Templates, error) {
+	result := &Templates{byLocale: make(map[string]map[string]*template.Template, len(Locales))}
+
+	var errs []error
+	for _, locale := range Locales {
+		base, err := template.ParseFS(fsys, "templates/layouts/*.html", "templates/"+locale+"/partials/*.html")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", locale, err))
+			continue
+		}
+
+		pages := make(map[string]*template.Template, len(TemplateNames))
+		for _, name := range TemplateNames {
+			page, err := parsePage(fsys, base, locale, name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			pages[name] = page
+		}
+		result.byLocale[locale] = pages
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+
+	return result, nil
+}
+
+func parsePage(fsys fs.FS, base *template.Template, locale, name string) (*template.Template, error) {
+	path := "templates/" + locale + "/" + name + ".html"
+
+	page, err := base.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if _, err := page.ParseFS(fsys, path); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, block := range requiredBlocks {
+		if page.Lookup(block) == nil {
+			return nil, fmt.Errorf("%s: block %q is not defined", path, block)
+		}
+	}
+
+	return page, nil
+}
+
+// Render собирает тему и HTML письма на языке msg.Locale. Если для языка нет
+// шаблонов, используется DefaultLocale.
+func (t *Templates) Render(msg *MailMessage) (string, []byte, error) {
+	locale := msg.Locale
+	pages, ok := t.byLocale[locale]
+	if !ok {
+		locale, pages = DefaultLocale, t.byLocale[DefaultLocale]
+	}
+
+	page, ok := pages[msg.Type]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: unknown template %s", ErrTemplate, msg.Type)
+	}
+
+	params := make(map[string]interface{}, len(msg.Params)+2)
+	for key, value := range msg.Params {
+		params[key] = value
+	}
+	params["Locale"] = locale
+	params["UnsubscribeURL"] = msg.UnsubscribeURL
+
+	var subject bytes.Buffer
+	if err := page.ExecuteTemplate(&subject, "subject", params); err != nil {
+		return "", nil, fmt.Errorf("%w: failed to execute subject of %s/%s: %w", ErrTemplate, locale, msg.Type, err)
+	}
+
+	var body bytes.Buffer
+	if err := page.ExecuteTemplate(&body, "base", params); err != nil {
+		return "", nil, fmt.Errorf("%w: failed to execute %s/%s: %w", ErrTemplate, locale, msg.Type, err)
+	}
+
+	// Тема — не HTML: экранирование html/template в ней лишнее.
+	return html.UnescapeString(strings.TrimSpace(subject.String())), body.Bytes(), nil
+}
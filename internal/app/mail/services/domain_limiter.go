@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLimitedDomains — сколько доменов limiter помнит, прежде чем забыть те,
+// у которых лимит уже полностью восстановился.
+const maxLimitedDomains = 10000
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// domainLimiter ограничивает отправку писем на один домен получателя, чтобы
+// почтовые провайдеры не начали отклонять письма за слишком частую
+// отправку. Лимит восстанавливается равномерно: limit писем за interval,
+// не больше limit подряд. Ограничение действует в пределах процесса.
+type domainLimiter struct {
+	limit    int
+	interval time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// newDomainLimiter возвращает nil, если лимит не задан: nil-limiter ничего не ограничивает.
+func newDomainLimiter(limit int, interval time.Duration) *domainLimiter {
+	if limit <= 0 || interval <= 0 {
+		return nil
+	}
+
+	return &domainLimiter{
+		limit:    limit,
+		interval: interval,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Wait ждёт, пока на домен адреса to можно будет отправить письмо, или отмены ctx.
+func (l *domainLimiter) Wait(ctx context.Context, to string) error {
+	if l == nil {
+		return nil
+	}
+
+	domain := recipientDomain(to)
+	for {
+		delay := l.reserve(domain, time.Now())
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve занимает место для письма на домен и возвращает 0 или время, через
+// которое место освободится.
+func (l *domainLimiter) reserve(domain string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	perSecond := float64(l.limit) / l.interval.Seconds()
+
+	b, ok := l.buckets[domain]
+	if !ok {
+		if len(l.buckets) >= maxLimitedDomains {
+			l.forgetIdle(now, perSecond)
+		}
+		b = &bucket{tokens: float64(l.limit), updated: now}
+		l.buckets[domain] = b
+	}
+
+	b.tokens = min(float64(l.limit), b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+}
+
+func (l *domainLimiter) forgetIdle(now time.Time, perSecond float64) {
+	for domain, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*perSecond >= float64(l.limit) {
+			delete(l.buckets, domain)
+		}
+	}
+}
+
+func recipientDomain(to string) string {
+	at := strings.LastIndex(to, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(to[at+1:], ">"))
+}
@@ -2,22 +2,62 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/RuLap/meetly-api/meetly/internal/app/mail/mailer"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/config"
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
 	"log/slog"
+	"net/textproto"
+	"net/url"
+	"time"
 
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
 )
 
+// preferencesTimeout ограничивает проверку настроек получателя перед отправкой.
+const preferencesTimeout = 5 * time.Second
+
+// templateTypes сопоставляет шаблоны писем с типами уведомлений. Шаблоны без
+// типа — служебные письма, которые отправляются независимо от настроек.
+var templateTypes = map[string]string{
+	"waitlist_promoted":     providers.NotificationWaitlistPromoted,
+	"join_request_created":  providers.NotificationJoinRequestUpdated,
+	"join_request_approved": providers.NotificationJoinRequestUpdated,
+	"join_request_rejected": providers.NotificationJoinRequestUpdated,
+	"occurrence_cancelled":  providers.NotificationEventCancelled,
+	"event_cancelled":       providers.NotificationEventCancelled,
+	"event_joined":          providers.NotificationEventJoined,
+	"event_reminder":        providers.NotificationEventReminder,
+}
+
 type MailService struct {
-	log      *slog.Logger
-	rabbitmq *rabbitmq.Client
-	mailer   *mailer.Mailer
+	log            *slog.Logger
+	rabbitmq       *rabbitmq.Client
+	mailer         *mailer.Mailer
+	preferences    providers.NotificationPreferenceProvider
+	unsubscribeURL string
+	consumer       rabbitmq.ConsumerOptions
+	limiter        *domainLimiter
+	stats          stats
 }
 
-func NewMailService(log *slog.Logger, rabbitmqClient *rabbitmq.Client, smtpConfig *config.SMTP) *MailService {
+func NewMailService(
+	log *slog.Logger,
+	rabbitmqClient *rabbitmq.Client,
+	smtpConfig *config.SMTP,
+	mailConfig *config.MailConfig,
+	preferences providers.NotificationPreferenceProvider,
+	unsubscribeURL string,
+) (*MailService, error) {
+	// Шаблоны разбираются один раз: ошибка в них должна остановить запуск,
+	// а не каждое письмо.
+	templates, err := mailer.LoadTemplates()
+	if err != nil {
+		return nil, err
+	}
+
 	mailer := mailer.NewMailer(
 		smtpConfig.Host,
 		smtpConfig.Port,
@@ -25,15 +65,26 @@ func NewMailService(log *slog.Logger, rabbitmqClient *rabbitmq.Client, smtpConfi
 		smtpConfig.Password,
 		smtpConfig.FromName,
 		smtpConfig.FromAddress,
+		templates,
 	)
 
 	return &MailService{
-		log:      log,
-		rabbitmq: rabbitmqClient,
-		mailer:   mailer,
-	}
+		log:            log,
+		rabbitmq:       rabbitmqClient,
+		mailer:         mailer,
+		preferences:    preferences,
+		unsubscribeURL: unsubscribeURL,
+		consumer: rabbitmq.ConsumerOptions{
+			Workers:      mailConfig.Workers,
+			Prefetch:     mailConfig.Prefetch,
+			DrainTimeout: mailConfig.DrainTimeout,
+		},
+		limiter: newDomainLimiter(mailConfig.DomainRateLimit, mailConfig.DomainRateInterval),
+	}, nil
 }
 
+// StartConsumer обрабатывает очередь писем до отмены ctx, после чего
+// дожидается писем, которые уже отправляются.
 func (s *MailService) StartConsumer(ctx context.Context) error {
 	if s.rabbitmq == nil {
 		return fmt.Errorf("rabbitmq client is not initialized")
@@ -41,24 +92,75 @@ func (s *MailService) StartConsumer(ctx context.Context) error {
 
 	s.log.Info("starting mail service consumer")
 
-	return s.rabbitmq.ConsumeEmailEvents(ctx, s.handleEmailEvent)
+	return s.rabbitmq.ConsumeEmailEvents(ctx, s.consumer, s.handleEmailEvent)
+}
+
+// Stats возвращает счётчики обработки писем.
+func (s *MailService) Stats() Stats {
+	return s.stats.snapshot()
+}
+
+func (s *MailService) handleEmailEvent(ctx context.Context, event events.EmailEvent) error {
+	s.stats.inFlight.Add(1)
+	defer s.stats.inFlight.Add(-1)
+
+	err := s.deliver(ctx, event)
+	switch {
+	case err == nil:
+	case rabbitmq.IsPermanent(err):
+		s.stats.rejected.Add(1)
+	default:
+		s.stats.failed.Add(1)
+	}
+
+	return err
+}
+
+func (s *MailService) deliver(ctx context.Context, event events.EmailEvent) error {
+	rcpt, send, err := s.checkPreferences(ctx, &event)
+	if err != nil {
+		return err
+	}
+	if !send {
+		return nil
+	}
+
+	if err := s.limiter.Wait(ctx, event.To); err != nil {
+		return err
+	}
+
+	if err := classifySendError(s.sendEmail(event, rcpt)); err != nil {
+		return err
+	}
+
+	s.stats.sent.Add(1)
+	return nil
 }
 
-func (s *MailService) handleEmailEvent(event events.EmailEvent) error {
+func (s *MailService) sendEmail(event events.EmailEvent, rcpt recipient) error {
 	switch event.Template {
 	case "email_confirmation":
-		return s.sendConfirmationEmail(event)
+		return s.sendConfirmationEmail(event, rcpt)
 	case "password_reset":
-		return s.sendPasswordResetEmail(event)
+		return s.sendPasswordResetEmail(event, rcpt)
 	case "welcome":
-		return s.sendWelcomeEmail(event)
+		return s.sendWelcomeEmail(event, rcpt)
+	case "waitlist_promoted",
+		"join_request_created",
+		"join_request_approved",
+		"join_request_rejected",
+		"occurrence_cancelled",
+		"event_cancelled",
+		"event_joined",
+		"event_reminder":
+		return s.sendEventNotificationEmail(event, rcpt)
 	default:
 		s.log.Warn("unknown email template", "template", event.Template)
-		return fmt.Errorf("unknown email template: %s", event.Template)
+		return rabbitmq.Permanent(fmt.Errorf("unknown email template: %s", event.Template))
 	}
 }
 
-func (s *MailService) sendConfirmationEmail(event events.EmailEvent) error {
+func (s *MailService) sendConfirmationEmail(event events.EmailEvent, rcpt recipient) error {
 	s.log.Info("sending confirmation email", "to", event.To)
 
 	confirmationURL, _ := event.Data["confirmation_url"].(string)
@@ -68,6 +170,7 @@ func (s *MailService) sendConfirmationEmail(event events.EmailEvent) error {
 		Email:   userEmail,
 		Subject: "Подтвердите ваш email",
 		Type:    "email_confirmation",
+		Locale:  rcpt.locale,
 		Params: map[string]interface{}{
 			"ConfirmationURL": confirmationURL,
 			"UserEmail":       userEmail,
@@ -83,7 +186,7 @@ func (s *MailService) sendConfirmationEmail(event events.EmailEvent) error {
 	return nil
 }
 
-func (s *MailService) sendPasswordResetEmail(event events.EmailEvent) error {
+func (s *MailService) sendPasswordResetEmail(event events.EmailEvent, rcpt recipient) error {
 	s.log.Info("sending password reset email", "to", event.To)
 
 	resetURL, _ := event.Data["reset_url"].(string)
@@ -93,6 +196,7 @@ func (s *MailService) sendPasswordResetEmail(event events.EmailEvent) error {
 		Email:   userEmail,
 		Subject: "Сброс пароля",
 		Type:    "password_reset",
+		Locale:  rcpt.locale,
 		Params: map[string]interface{}{
 			"ResetURL":  resetURL,
 			"UserEmail": userEmail,
@@ -107,16 +211,18 @@ func (s *MailService) sendPasswordResetEmail(event events.EmailEvent) error {
 	return nil
 }
 
-func (s *MailService) sendWelcomeEmail(event events.EmailEvent) error {
+func (s *MailService) sendWelcomeEmail(event events.EmailEvent, rcpt recipient) error {
 	s.log.Info("sending welcome email", "to", event.To)
 
 	userEmail, _ := event.Data["user_email"].(string)
 	userName, _ := event.Data["user_name"].(string)
 
 	msg := mailer.MailMessage{
-		Email:   userEmail,
-		Subject: "Добро пожаловать в Meetly!",
-		Type:    "welcome",
+		Email:          userEmail,
+		Subject:        "Добро пожаловать в Meetly!",
+		Type:           "welcome",
+		UnsubscribeURL: rcpt.unsubscribeURL,
+		Locale:         rcpt.locale,
 		Params: map[string]interface{}{
 			"UserName":  userName,
 			"UserEmail": userEmail,
@@ -130,3 +236,100 @@ func (s *MailService) sendWelcomeEmail(event events.EmailEvent) error {
 
 	return nil
 }
+
+func (s *MailService) sendEventNotificationEmail(event events.EmailEvent, rcpt recipient) error {
+	s.log.Info("sending event notification email", "to", event.To, "template", event.Template)
+
+	userEmail, _ := event.Data["user_email"].(string)
+	eventTitle, _ := event.Data["event_title"].(string)
+	eventStartsAt, _ := event.Data["event_starts_at"].(string)
+
+	msg := mailer.MailMessage{
+		Email:          userEmail,
+		Subject:        event.Subject,
+		Type:           event.Template,
+		UnsubscribeURL: rcpt.unsubscribeURL,
+		Locale:         rcpt.locale,
+		Params: map[string]interface{}{
+			"UserEmail":     userEmail,
+			"EventTitle":    eventTitle,
+			"EventStartsAt": eventStartsAt,
+		},
+	}
+
+	for _, attachment := range event.Attachments {
+		msg.Attachments = append(msg.Attachments, mailer.Attachment{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Data:        attachment.Data,
+		})
+	}
+
+	if err := s.mailer.Send(msg); err != nil {
+		s.log.Error("failed to send event notification email", "error", err, "to", userEmail, "template", event.Template)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// classifySendError помечает окончательными ошибки, которые повторная отправка
+// не исправит: сломанный шаблон и отказ SMTP-сервера с кодом 5xx (например,
+// несуществующий адрес). Остальные ошибки считаются временными.
+func classifySendError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var smtpErr *textproto.Error
+	if errors.Is(err, mailer.ErrTemplate) || (errors.As(err, &smtpErr) && smtpErr.Code >= 500) {
+		return rabbitmq.Permanent(err)
+	}
+
+	return err
+}
+
+// recipient — сведения о получателе, от которых зависит вид письма.
+type recipient struct {
+	unsubscribeURL string
+	locale         string
+}
+
+// checkPreferences сверяет письмо с настройками получателя. Возвращает
+// ссылку для отписки и язык письма и false, если письмо отправлять сейчас не
+// нужно: получатель отключил такие письма или оно отложено до окончания
+// тихих часов.
+func (s *MailService) checkPreferences(ctx context.Context, event *events.EmailEvent) (recipient, bool, error) {
+	if s.preferences == nil {
+		return recipient{}, true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, preferencesTimeout)
+	defer cancel()
+
+	decision, err := s.preferences.CheckEmail(ctx, event.To, templateTypes[event.Template])
+	if err != nil {
+		return recipient{}, false, fmt.Errorf("failed to check notification preferences: %w", err)
+	}
+
+	if !decision.Allowed {
+		s.log.Info("email skipped by recipient preferences", "to", event.To, "template", event.Template)
+		s.stats.skipped.Add(1)
+		return recipient{}, false, nil
+	}
+
+	if decision.DeferUntil != nil {
+		if err := s.preferences.DeferEmail(ctx, decision.UserID, event, *decision.DeferUntil); err != nil {
+			return recipient{}, false, fmt.Errorf("failed to defer email: %w", err)
+		}
+		s.stats.deferred.Add(1)
+		return recipient{}, false, nil
+	}
+
+	result := recipient{locale: decision.Locale}
+	if s.unsubscribeURL != "" && decision.UnsubscribeToken != "" {
+		result.unsubscribeURL = s.unsubscribeURL + "?token=" + url.QueryEscape(decision.UnsubscribeToken)
+	}
+
+	return result, true, nil
+}
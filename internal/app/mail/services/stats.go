@@ -0,0 +1,39 @@
+package services
+
+import "sync/atomic"
+
+// Stats — счётчики обработки писем с момента запуска.
+type Stats struct {
+	// Sent — письма, принятые SMTP-сервером.
+	Sent uint64 `json:"sent"`
+	// Skipped — письма, которые получатель отключил в настройках.
+	Skipped uint64 `json:"skipped"`
+	// Deferred — письма, отложенные до конца тихих часов получателя.
+	Deferred uint64 `json:"deferred"`
+	// Failed — временные ошибки: письмо будет отправлено повторно.
+	Failed uint64 `json:"failed"`
+	// Rejected — окончательные ошибки: письмо ушло в очередь недоставленных.
+	Rejected uint64 `json:"rejected"`
+	// InFlight — письма, которые обрабатываются сейчас.
+	InFlight int64 `json:"in_flight"`
+}
+
+type stats struct {
+	sent     atomic.Uint64
+	skipped  atomic.Uint64
+	deferred atomic.Uint64
+	failed   atomic.Uint64
+	rejected atomic.Uint64
+	inFlight atomic.Int64
+}
+
+func (s *stats) snapshot() Stats {
+	return Stats{
+		Sent:     s.sent.Load(),
+		Skipped:  s.skipped.Load(),
+		Deferred: s.deferred.Load(),
+		Failed:   s.failed.Load(),
+		Rejected: s.rejected.Load(),
+		InFlight: s.inFlight.Load(),
+	}
+}
@@ -0,0 +1,8 @@
+package media
+
+// GetImageResponse — загруженное изображение. URL ведёт на основной размер,
+// Thumbnails — на все размеры по их названиям (large, medium, small).
+type GetImageResponse struct {
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
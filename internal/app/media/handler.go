@@ -0,0 +1,204 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/blob"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/imaging"
+	"github.com/darahayes/go-boom"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// uploadFormField — поле multipart-формы с файлом.
+const uploadFormField = "file"
+
+// multipartOverhead — запас на заголовки частей формы сверх размера файла.
+const multipartOverhead = 64 << 10
+
+// uploadReadTimeout заменяет ReadTimeout сервера на время загрузки: с мобильной
+// сети файл в несколько мегабайт передаётся дольше нескольких секунд.
+const uploadReadTimeout = time.Minute
+
+var (
+	errFileRequired = errors.New("необходимо передать файл в поле file")
+	errFileTooLarge = errors.New("файл слишком большой")
+)
+
+type Handler struct {
+	service       Service
+	maxUploadSize int64
+}
+
+func NewHandler(service Service, maxUploadSize int64) *Handler {
+	return &Handler{service: service, maxUploadSize: maxUploadSize}
+}
+
+func (h *Handler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	data, err := h.readUpload(w, r)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	result, err := h.service.UploadAvatar(r.Context(), userID, data)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) UploadEventCover(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	data, err := h.readUpload(w, r)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	result, err := h.service.UploadEventCover(r.Context(), eventID, userID, data)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+// Serve отдаёт файл из хранилища. Ключи содержат случайный ID загрузки,
+// поэтому ссылку нельзя подобрать, а содержимое по ней никогда не меняется.
+func (h *Handler) Serve(w http.ResponseWriter, r *http.Request) {
+	object, url, err := h.service.Open(r.Context(), chi.URLParam(r, "*"))
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	if url != "" {
+		w.Header().Set("Cache-Control", "private, max-age=60")
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+	defer object.Body.Close()
+
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if body, ok := object.Body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", object.ModTime, body)
+		return
+	}
+
+	if object.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, object.Body)
+}
+
+// readUpload читает файл из multipart-формы, не сохраняя его на диск
+// и не принимая больше maxUploadSize байт.
+func (h *Handler) readUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	// Ошибку игнорируем: без поддержки дедлайнов действует ReadTimeout сервера.
+	_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadReadTimeout))
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errFileRequired
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, errFileTooLarge
+			}
+			return nil, errFileRequired
+		}
+
+		if part.FormName() != uploadFormField {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+
+		data, err := io.ReadAll(io.LimitReader(part, h.maxUploadSize+1))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, errFileTooLarge
+			}
+			return nil, errFileRequired
+		}
+		if int64(len(data)) > h.maxUploadSize {
+			return nil, errFileTooLarge
+		}
+		if len(data) == 0 {
+			return nil, errFileRequired
+		}
+
+		return data, nil
+	}
+}
+
+func (h *Handler) sendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errFileTooLarge):
+		boom.EntityTooLarge(w, err.Error())
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		boom.UnsupportedMediaType(w, err.Error())
+	case errors.Is(err, errFileRequired),
+		errors.Is(err, imaging.ErrTooLarge),
+		errors.Is(err, imaging.ErrTooSmall):
+		boom.BadRequest(w, err.Error())
+	case errors.Is(err, ErrNotOrganizer):
+		boom.Forbidden(w, err.Error())
+	case errors.Is(err, ErrFileNotFound),
+		errors.Is(err, blob.ErrNotFound):
+		boom.NotFound(w, err.Error())
+	default:
+		boom.Internal(w, err)
+	}
+}
+
+func currentUserID(r *http.Request) (uuid.UUID, error) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user_id not found in context")
+	}
+
+	return uuid.Parse(userID)
+}
+
+func (h *Handler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,39 @@
+package media
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/blob"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+)
+
+// defaultMaxUploadSize — ограничение размера файла, если оно не задано в конфигурации.
+const defaultMaxUploadSize = 10 << 20
+
+type Module struct {
+	Service Service
+	Handler *Handler
+}
+
+func NewModule(
+	log *slog.Logger,
+	store blob.BlobStore,
+	publicURL string,
+	maxUploadSize int64,
+	presignTTL time.Duration,
+	userProvider providers.UserProvider,
+	eventProvider providers.EventProvider,
+) *Module {
+	if maxUploadSize <= 0 {
+		maxUploadSize = defaultMaxUploadSize
+	}
+
+	service := NewService(log, store, publicURL, presignTTL, userProvider, eventProvider)
+	handler := NewHandler(service, maxUploadSize)
+
+	return &Module{
+		Service: service,
+		Handler: handler,
+	}
+}
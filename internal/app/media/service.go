@@ -0,0 +1,230 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/blob"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/imaging"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/google/uuid"
+)
+
+type Service interface {
+	UploadAvatar(ctx context.Context, userID uuid.UUID, data []byte) (*GetImageResponse, error)
+	UploadEventCover(ctx context.Context, eventID, userID uuid.UUID, data []byte) (*GetImageResponse, error)
+	// Open возвращает объект для отдачи через API. Если хранилище умеет
+	// выдавать прямые ссылки, вместо объекта возвращается ссылка.
+	Open(ctx context.Context, key string) (*blob.Object, string, error)
+}
+
+var (
+	ErrNotOrganizer = errors.New("только организатор может менять обложку события")
+	ErrFileNotFound = errors.New("файл не найден")
+)
+
+// mediaPath — путь, по которому API раздаёт файлы из хранилища.
+const mediaPath = "/v1/media/"
+
+const thumbnailContentType = "image/jpeg"
+
+// kind описывает вид изображения: каталог в хранилище, размеры миниатюр
+// (первый — основной) и наименьшую допустимую сторону исходника.
+type kind struct {
+	prefix  string
+	sizes   []imaging.Size
+	minSide int
+}
+
+var (
+	avatarKind = kind{
+		prefix: "avatars",
+		sizes: []imaging.Size{
+			{Name: "large", Width: 512, Height: 512},
+			{Name: "medium", Width: 256, Height: 256},
+			{Name: "small", Width: 64, Height: 64},
+		},
+		minSide: 64,
+	}
+	coverKind = kind{
+		prefix: "covers",
+		sizes: []imaging.Size{
+			{Name: "large", Width: 1280, Height: 720},
+			{Name: "medium", Width: 640, Height: 360},
+			{Name: "small", Width: 320, Height: 180},
+		},
+		minSide: 180,
+	}
+)
+
+type service struct {
+	log           *slog.Logger
+	store         blob.BlobStore
+	publicURL     string
+	presignTTL    time.Duration
+	userProvider  providers.UserProvider
+	eventProvider providers.EventProvider
+}
+
+func NewService(
+	log *slog.Logger,
+	store blob.BlobStore,
+	publicURL string,
+	presignTTL time.Duration,
+	userProvider providers.UserProvider,
+	eventProvider providers.EventProvider,
+) Service {
+	return &service{
+		log:           log,
+		store:         store,
+		publicURL:     strings.TrimSuffix(publicURL, "/"),
+		presignTTL:    presignTTL,
+		userProvider:  userProvider,
+		eventProvider: eventProvider,
+	}
+}
+
+func (s *service) UploadAvatar(ctx context.Context, userID uuid.UUID, data []byte) (*GetImageResponse, error) {
+	keys, result, err := s.upload(ctx, avatarKind, userID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.userProvider.SetAvatar(ctx, userID, result.URL)
+	if err != nil {
+		s.deleteKeys(ctx, keys)
+		return nil, err
+	}
+
+	s.log.Info("avatar uploaded", "user_id", userID)
+	s.deleteUpload(ctx, avatarKind, userID, previous)
+
+	return result, nil
+}
+
+func (s *service) UploadEventCover(ctx context.Context, eventID, userID uuid.UUID, data []byte) (*GetImageResponse, error) {
+	isOrganizer, err := s.eventProvider.IsOrganizer(ctx, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isOrganizer {
+		s.log.Warn("non-organizer tried to upload event cover", "event_id", eventID, "user_id", userID)
+		return nil, ErrNotOrganizer
+	}
+
+	keys, result, err := s.upload(ctx, coverKind, eventID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.eventProvider.SetCover(ctx, eventID, result.URL)
+	if err != nil {
+		s.deleteKeys(ctx, keys)
+		return nil, err
+	}
+
+	s.log.Info("event cover uploaded", "event_id", eventID, "user_id", userID)
+	if previous != nil {
+		s.deleteUpload(ctx, coverKind, eventID, *previous)
+	}
+
+	return result, nil
+}
+
+func (s *service) Open(ctx context.Context, key string) (*blob.Object, string, error) {
+	if err := blob.ValidateKey(key); err != nil {
+		return nil, "", ErrFileNotFound
+	}
+
+	if presigner, ok := s.store.(blob.Presigner); ok {
+		url, err := presigner.PresignGet(ctx, key, s.presignTTL)
+		if err != nil {
+			s.log.Error("failed to presign media url", "key", key, "error", err)
+			return nil, "", err
+		}
+		return nil, url, nil
+	}
+
+	object, err := s.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, "", ErrFileNotFound
+		}
+		s.log.Error("failed to open media", "key", key, "error", err)
+		return nil, "", err
+	}
+
+	return object, "", nil
+}
+
+// upload строит миниатюры и сохраняет их в каталог <prefix>/<ownerID>/<uploadID>.
+// Каждая загрузка получает новый каталог, поэтому ссылки можно кэшировать навсегда.
+func (s *service) upload(ctx context.Context, kind kind, ownerID uuid.UUID, data []byte) ([]string, *GetImageResponse, error) {
+	img, err := imaging.Decode(data, kind.minSide)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	thumbnails, err := imaging.Thumbnails(img, kind.sizes)
+	if err != nil {
+		s.log.Error("failed to build thumbnails", "owner_id", ownerID, "error", err)
+		return nil, nil, err
+	}
+
+	dir := fmt.Sprintf("%s/%s/%s", kind.prefix, ownerID, uuid.New())
+	keys := make([]string, 0, len(thumbnails))
+	result := &GetImageResponse{Thumbnails: make(map[string]string, len(thumbnails))}
+
+	for i, thumbnail := range thumbnails {
+		key := dir + "/" + thumbnail.Size.Name + ".jpg"
+		if err := s.store.Put(ctx, key, thumbnailContentType, thumbnail.Data); err != nil {
+			s.log.Error("failed to store thumbnail", "key", key, "error", err)
+			s.deleteKeys(ctx, keys)
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+
+		url := s.publicURL + mediaPath + key
+		result.Thumbnails[thumbnail.Size.Name] = url
+		if i == 0 {
+			result.URL = url
+		}
+	}
+
+	return keys, result, nil
+}
+
+// deleteUpload удаляет миниатюры прежней загрузки владельца. Ссылки не из
+// хранилища (например, аватары из Google) и ссылки на чужие файлы
+// пропускаются.
+func (s *service) deleteUpload(ctx context.Context, kind kind, ownerID uuid.UUID, url string) {
+	_, key, ok := strings.Cut(url, mediaPath)
+	if !ok {
+		return
+	}
+	// Ожидается ключ вида <prefix>/<ownerID>/<uploadID>/<size>.jpg.
+	if blob.ValidateKey(key) != nil || strings.Count(key, "/") != 3 || !strings.HasPrefix(key, kind.prefix+"/"+ownerID.String()+"/") {
+		s.log.Warn("skipped deleting media of another owner", "owner_id", ownerID, "key", key)
+		return
+	}
+
+	dir := key[:strings.LastIndex(key, "/")]
+	keys := make([]string, 0, len(kind.sizes))
+	for _, size := range kind.sizes {
+		keys = append(keys, dir+"/"+size.Name+".jpg")
+	}
+
+	s.deleteKeys(ctx, keys)
+}
+
+func (s *service) deleteKeys(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			s.log.Error("failed to delete media", "key", key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("неверный курсор")
+
+// Cursor — позиция в ленте уведомлений для keyset-пагинации по (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func NewCursor(notification *Notification) *Cursor {
+	return &Cursor{CreatedAt: notification.CreatedAt, ID: notification.ID}
+}
+
+// Encode возвращает курсор в виде непрозрачной строки для клиента.
+func (c *Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAtValue, idValue, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtValue)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(idValue)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: createdAt, ID: id}, nil
+}
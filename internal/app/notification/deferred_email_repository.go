@@ -0,0 +1,120 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/outbox"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DeferredEmailRepository interface {
+	Create(ctx context.Context, model *DeferredEmail) (*DeferredEmail, error)
+	GetDue(ctx context.Context, now time.Time, limit int) ([]DeferredEmail, error)
+	// Release удаляет отложенное письмо и в той же транзакции записывает его
+	// в outbox. Возвращает false, если письмо уже выпустил другой экземпляр.
+	Release(ctx context.Context, model *DeferredEmail) (bool, error)
+}
+
+type deferredEmailRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewDeferredEmailRepository(pool *pgxpool.Pool) DeferredEmailRepository {
+	return &deferredEmailRepository{pool}
+}
+
+func (r *deferredEmailRepository) Create(ctx context.Context, model *DeferredEmail) (*DeferredEmail, error) {
+	payload, err := json.Marshal(model.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось отложить письмо: %w", err)
+	}
+
+	query := `
+		INSERT INTO deferred_emails (user_id, payload, deliver_at)
+		VALUES ($1, $2::jsonb, $3)
+		RETURNING id, created_at
+	`
+
+	err = r.pool.QueryRow(ctx, query, model.UserID, string(payload), model.DeliverAt).Scan(
+		&model.ID,
+		&model.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось отложить письмо: %w", err)
+	}
+
+	return model, nil
+}
+
+// GetDue возвращает до limit писем, время отправки которых наступило.
+func (r *deferredEmailRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]DeferredEmail, error) {
+	query := `
+		SELECT id, user_id, payload, deliver_at, created_at
+		FROM deferred_emails
+		WHERE deliver_at <= $1
+		ORDER BY deliver_at
+		LIMIT $2
+	`
+
+	rows, err := r.pool.Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить отложенные письма: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]DeferredEmail, 0)
+	for rows.Next() {
+		var email DeferredEmail
+		var payload []byte
+		err := rows.Scan(
+			&email.ID,
+			&email.UserID,
+			&payload,
+			&email.DeliverAt,
+			&email.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось получить отложенное письмо: %w", err)
+		}
+		if err := json.Unmarshal(payload, &email.Payload); err != nil {
+			return nil, fmt.Errorf("не удалось разобрать отложенное письмо: %w", err)
+		}
+
+		result = append(result, email)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить отложенные письма: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *deferredEmailRepository) Release(ctx context.Context, model *DeferredEmail) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM deferred_emails WHERE id = $1`, model.ID)
+	if err != nil {
+		return false, fmt.Errorf("не удалось удалить отложенное письмо: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := outbox.AddEmail(ctx, tx, &model.Payload); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("не удалось зафиксировать транзакцию: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,125 @@
+package notification
+
+import "time"
+
+// GetNotificationResponse — уведомление. Payload имеет одинаковую форму для
+// всех типов: поля, не относящиеся к типу, не передаются.
+type GetNotificationResponse struct {
+	ID        string                         `json:"id"`
+	Type      string                         `json:"type"`
+	Payload   GetNotificationPayloadResponse `json:"payload"`
+	Read      bool                           `json:"read"`
+	ReadAt    *time.Time                     `json:"read_at"`
+	CreatedAt time.Time                      `json:"created_at"`
+}
+
+type GetNotificationPayloadResponse struct {
+	Actor         *GetActorResponse `json:"actor,omitempty"`
+	EventID       *string           `json:"event_id,omitempty"`
+	EventTitle    string            `json:"event_title,omitempty"`
+	EventStartsAt *time.Time        `json:"event_starts_at,omitempty"`
+	MessageID     *string           `json:"message_id,omitempty"`
+	Text          string            `json:"text,omitempty"`
+}
+
+type GetActorResponse struct {
+	ID        string `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	AvatarUrl string `json:"avatar_url"`
+}
+
+// GetNotificationsPageResponse — страница уведомлений от новых к старым.
+// NextCursor передаётся в параметре before, чтобы получить более старые уведомления.
+type GetNotificationsPageResponse struct {
+	Items       []GetNotificationResponse `json:"items"`
+	NextCursor  *string                   `json:"next_cursor"`
+	UnreadCount int                       `json:"unread_count"`
+}
+
+type GetUnreadCountResponse struct {
+	Count int `json:"count"`
+}
+
+type MarkAllReadResponse struct {
+	Updated int64 `json:"updated"`
+}
+
+// GetPreferencesResponse — настройки уведомлений. Для каждого типа переданы
+// только каналы, по которым он доставляется.
+type GetPreferencesResponse struct {
+	Types        []GetTypePreferenceResponse `json:"types"`
+	QuietHours   *QuietHoursDTO              `json:"quiet_hours"`
+	Unsubscribed bool                        `json:"unsubscribed"`
+	// Locale — язык писем.
+	Locale string `json:"locale"`
+}
+
+type GetTypePreferenceResponse struct {
+	Type  string `json:"type"`
+	Email *bool  `json:"email,omitempty"`
+	InApp *bool  `json:"in_app,omitempty"`
+	Push  *bool  `json:"push,omitempty"`
+}
+
+// QuietHoursDTO — тихие часы в формате ЧЧ:ММ по часовому поясу Timezone.
+// Если End меньше Start, интервал переходит через полночь.
+type QuietHoursDTO struct {
+	Start    string `json:"start" validate:"required,datetime=15:04"`
+	End      string `json:"end" validate:"required,datetime=15:04"`
+	Timezone string `json:"timezone" validate:"required,timezone"`
+}
+
+// UpdatePreferencesRequest заменяет настройки целиком: типы, которых нет
+// в Types, и не переданные каналы возвращаются к значениям по умолчанию,
+// а quiet_hours: null отключает тихие часы.
+type UpdatePreferencesRequest struct {
+	Types        []UpdateTypePreferenceRequest `json:"types" validate:"omitempty,max=50,dive"`
+	QuietHours   *QuietHoursDTO                `json:"quiet_hours" validate:"omitempty"`
+	Unsubscribed bool                          `json:"unsubscribed"`
+	// Locale — язык писем; без него письма приходят на языке по умолчанию.
+	Locale *string `json:"locale" validate:"omitempty,oneof=ru en"`
+}
+
+type UpdateTypePreferenceRequest struct {
+	Type  string `json:"type" validate:"required,max=64"`
+	Email *bool  `json:"email"`
+	InApp *bool  `json:"in_app"`
+	Push  *bool  `json:"push"`
+}
+
+// CreatePushSubscriptionRequest — результат PushSubscription.toJSON() в браузере.
+type CreatePushSubscriptionRequest struct {
+	Endpoint string                      `json:"endpoint" validate:"required,url,max=2048"`
+	Keys     PushSubscriptionKeysRequest `json:"keys" validate:"required"`
+}
+
+type PushSubscriptionKeysRequest struct {
+	P256dh string `json:"p256dh" validate:"required,max=128"`
+	Auth   string `json:"auth" validate:"required,max=64"`
+}
+
+type DeletePushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" validate:"required,max=2048"`
+}
+
+type GetPushSubscriptionResponse struct {
+	ID        string    `json:"id"`
+	Endpoint  string    `json:"endpoint"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetPushPublicKeyResponse — открытый ключ VAPID для PushManager.subscribe
+// (applicationServerKey).
+type GetPushPublicKeyResponse struct {
+	PublicKey string `json:"public_key"`
+}
+
+// PushMessage — содержимое push-уведомления. Title и Body готовы для
+// showNotification в service worker, Notification совпадает с элементом
+// ленты уведомлений (ID пуст, если уведомления в приложении отключены).
+type PushMessage struct {
+	Title        string                  `json:"title"`
+	Body         string                  `json:"body"`
+	Notification GetNotificationResponse `json:"notification"`
+}
@@ -0,0 +1,322 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	validation "github.com/RuLap/meetly-api/meetly/internal/pkg/validator"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush"
+	"github.com/darahayes/go-boom"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	var before *Cursor
+	if value := r.URL.Query().Get("before"); value != "" {
+		before, err = DecodeCursor(value)
+		if err != nil {
+			boom.BadRequest(w, err.Error())
+			return
+		}
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		boom.BadRequest(w, err.Error())
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	result, err := h.service.GetNotifications(r.Context(), userID, before, limit, unreadOnly)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetUnreadCount(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.MarkRead(r.Context(), userID, id); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.MarkAllRead(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.GetPreferences(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var req UpdatePreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.UpdatePreferences(r.Context(), userID, &req)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+// UnsubscribePage показывает страницу подтверждения, когда пользователь
+// открывает ссылку для отписки в браузере. Сам GET ничего не меняет: ссылки
+// в письмах открывают и сканеры почты.
+func (h *Handler) UnsubscribePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("token") == "" {
+		renderUnsubscribePage(w, unsubscribeInvalid, http.StatusBadRequest)
+		return
+	}
+
+	renderUnsubscribePage(w, unsubscribeConfirm, http.StatusOK)
+}
+
+// Unsubscribe обрабатывает ссылку List-Unsubscribe. Почтовые клиенты
+// отправляют на неё POST без авторизации (RFC 8058), поэтому пользователь
+// определяется по токену из ссылки. Форма со страницы подтверждения
+// передаёт поле confirm и получает в ответ страницу, а не пустой ответ.
+func (h *Handler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	if r.PostFormValue("confirm") != "" {
+		h.unsubscribeFromPage(w, r, token)
+		return
+	}
+
+	if token == "" {
+		boom.BadRequest(w, "необходимо передать token")
+		return
+	}
+
+	if err := h.service.Unsubscribe(r.Context(), token); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) unsubscribeFromPage(w http.ResponseWriter, r *http.Request, token string) {
+	if token == "" {
+		renderUnsubscribePage(w, unsubscribeInvalid, http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.Unsubscribe(r.Context(), token)
+	switch {
+	case err == nil:
+		renderUnsubscribePage(w, unsubscribeDone, http.StatusOK)
+	case errors.Is(err, ErrInvalidUnsubscribeToken):
+		renderUnsubscribePage(w, unsubscribeInvalid, http.StatusNotFound)
+	default:
+		renderUnsubscribePage(w, unsubscribeFailed, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetPushPublicKey(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.GetPushPublicKey()
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *Handler) SubscribePush(w http.ResponseWriter, r *http.Request) {
+	var req CreatePushSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	result, err := h.service.SubscribePush(r.Context(), userID, &req, r.UserAgent())
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, result, http.StatusCreated)
+}
+
+func (h *Handler) UnsubscribePush(w http.ResponseWriter, r *http.Request) {
+	var req DeletePushSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	if errors := validation.ValidateStruct(req); errors != nil {
+		boom.BadRequest(w, "Ошибки валидации", errors)
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.UnsubscribePush(r.Context(), userID, req.Endpoint); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseLimit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, fmt.Errorf("limit должен быть числом от 1 до %d", maxPageLimit)
+	}
+
+	return limit, nil
+}
+
+func (h *Handler) sendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidCursor),
+		errors.Is(err, ErrUnknownNotificationType),
+		errors.Is(err, ErrUnsupportedChannel),
+		errors.Is(err, ErrInsecurePushEndpoint),
+		errors.Is(err, webpush.ErrInvalidSubscription):
+		boom.BadRequest(w, err.Error())
+	case errors.Is(err, ErrNotificationNotFound),
+		errors.Is(err, ErrInvalidUnsubscribeToken),
+		errors.Is(err, ErrPushSubscriptionNotFound):
+		boom.NotFound(w, err.Error())
+	case errors.Is(err, ErrPushDisabled):
+		boom.ServerUnavailable(w, err.Error())
+	default:
+		boom.Internal(w, err)
+	}
+}
+
+func currentUserID(r *http.Request) (uuid.UUID, error) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user_id not found in context")
+	}
+
+	return uuid.Parse(userID)
+}
+
+func (h *Handler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
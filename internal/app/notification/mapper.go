@@ -0,0 +1,242 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush"
+	"github.com/google/uuid"
+)
+
+func ProviderNotificationToModel(userID uuid.UUID, notification *providers.Notification) *Notification {
+	return &Notification{
+		UserID: userID,
+		Type:   notification.Type,
+		Payload: Payload{
+			ActorID:       notification.ActorID,
+			EventID:       notification.EventID,
+			EventTitle:    notification.EventTitle,
+			EventStartsAt: notification.EventStartsAt,
+			MessageID:     notification.MessageID,
+			Text:          notification.Text,
+		},
+	}
+}
+
+// NotificationsToGetResponse сопоставляет уведомления с авторами действий. Если
+// автор не найден (например, удалён), поле actor не передаётся.
+func NotificationsToGetResponse(models []Notification, actors map[string]providers.UserInfo) []GetNotificationResponse {
+	result := make([]GetNotificationResponse, 0, len(models))
+	for _, model := range models {
+		var actor *GetActorResponse
+		if model.Payload.ActorID != nil {
+			if user, ok := actors[model.Payload.ActorID.String()]; ok {
+				actor = UserInfoToGetActorResponse(&user)
+			}
+		}
+
+		result = append(result, *NotificationToGetResponse(&model, actor))
+	}
+
+	return result
+}
+
+func NotificationToGetResponse(model *Notification, actor *GetActorResponse) *GetNotificationResponse {
+	return &GetNotificationResponse{
+		ID:   model.ID.String(),
+		Type: model.Type,
+		Payload: GetNotificationPayloadResponse{
+			Actor:         actor,
+			EventID:       uuidToString(model.Payload.EventID),
+			EventTitle:    model.Payload.EventTitle,
+			EventStartsAt: model.Payload.EventStartsAt,
+			MessageID:     uuidToString(model.Payload.MessageID),
+			Text:          model.Payload.Text,
+		},
+		Read:      model.ReadAt != nil,
+		ReadAt:    model.ReadAt,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+func UserInfoToGetActorResponse(user *providers.UserInfo) *GetActorResponse {
+	return &GetActorResponse{
+		ID:        user.ID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		AvatarUrl: user.AvatarUrl,
+	}
+}
+
+func CreatePushSubscriptionRequestToModel(dto *CreatePushSubscriptionRequest, userID uuid.UUID, userAgent string) *PushSubscription {
+	model := &PushSubscription{
+		UserID:   userID,
+		Endpoint: dto.Endpoint,
+		P256dh:   dto.Keys.P256dh,
+		Auth:     dto.Keys.Auth,
+	}
+
+	if userAgent != "" {
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = userAgent[:maxUserAgentLength]
+		}
+		model.UserAgent = &userAgent
+	}
+
+	return model
+}
+
+func PushSubscriptionToWebPush(model *PushSubscription) *webpush.Subscription {
+	return &webpush.Subscription{
+		Endpoint: model.Endpoint,
+		P256dh:   model.P256dh,
+		Auth:     model.Auth,
+	}
+}
+
+func PushSubscriptionToGetResponse(model *PushSubscription) *GetPushSubscriptionResponse {
+	return &GetPushSubscriptionResponse{
+		ID:        model.ID.String(),
+		Endpoint:  model.Endpoint,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+// NotificationToPushMessage формирует заголовок и текст push-уведомления.
+// Если уведомление не сохранено в приложении, его ID не передаётся.
+func NotificationToPushMessage(model *Notification, actors map[string]providers.UserInfo) *PushMessage {
+	response := NotificationsToGetResponse([]Notification{*model}, actors)[0]
+	if model.ID == uuid.Nil {
+		response.ID = ""
+	}
+
+	actorName := ""
+	if actor := response.Payload.Actor; actor != nil {
+		actorName = strings.TrimSpace(actor.FirstName + " " + actor.LastName)
+	}
+
+	message := &PushMessage{
+		Title:        model.Payload.EventTitle,
+		Body:         model.Payload.Text,
+		Notification: response,
+	}
+
+	switch model.Type {
+	case providers.NotificationParticipantJoined:
+		message.Body = "Новый участник"
+		if actorName != "" {
+			message.Body = actorName + " теперь участвует"
+		}
+	case providers.NotificationEventCancelled:
+		message.Title = "Событие отменено"
+		message.Body = model.Payload.EventTitle
+	case providers.NotificationWaitlistPromoted:
+		message.Title = "Для вас освободилось место"
+		message.Body = model.Payload.EventTitle
+	case providers.NotificationChatMention:
+		message.Title = fmt.Sprintf("Упоминание в чате «%s»", model.Payload.EventTitle)
+		if actorName != "" {
+			message.Body = actorName + ": " + model.Payload.Text
+		}
+	case providers.NotificationEventReminder:
+		message.Title = model.Payload.Text
+		message.Body = model.Payload.EventTitle
+	}
+
+	if message.Title == "" {
+		message.Title = "Meetly"
+	}
+
+	return message
+}
+
+func uuidToString(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+
+	value := id.String()
+	return &value
+}
+
+// PreferencesToGetResponse перечисляет все типы уведомлений с учётом выбора
+// пользователя и значений по умолчанию.
+func PreferencesToGetResponse(settings *Settings, preferences []Preference) *GetPreferencesResponse {
+	byType := make(map[string]*Preference, len(preferences))
+	for i := range preferences {
+		byType[preferences[i].Type] = &preferences[i]
+	}
+
+	result := &GetPreferencesResponse{
+		Types:        make([]GetTypePreferenceResponse, 0, len(notificationTypes)),
+		QuietHours:   SettingsToQuietHoursDTO(settings),
+		Unsubscribed: settings.UnsubscribedAt != nil,
+		Locale:       settings.EmailLocale(),
+	}
+
+	for i := range notificationTypes {
+		info := &notificationTypes[i]
+		preference := byType[info.Type]
+
+		result.Types = append(result.Types, GetTypePreferenceResponse{
+			Type:  info.Type,
+			Email: channelValue(preference, info, ChannelEmail),
+			InApp: channelValue(preference, info, ChannelInApp),
+			Push:  channelValue(preference, info, ChannelPush),
+		})
+	}
+
+	return result
+}
+
+func SettingsToQuietHoursDTO(settings *Settings) *QuietHoursDTO {
+	if settings.QuietHoursStart == nil || settings.QuietHoursEnd == nil {
+		return nil
+	}
+
+	timezone := "UTC"
+	if settings.Timezone != nil {
+		timezone = *settings.Timezone
+	}
+
+	return &QuietHoursDTO{
+		Start:    formatMinutes(*settings.QuietHoursStart),
+		End:      formatMinutes(*settings.QuietHoursEnd),
+		Timezone: timezone,
+	}
+}
+
+func UpdateTypePreferenceRequestToModel(dto *UpdateTypePreferenceRequest, userID uuid.UUID) *Preference {
+	return &Preference{
+		UserID: userID,
+		Type:   dto.Type,
+		Email:  dto.Email,
+		InApp:  dto.InApp,
+		Push:   dto.Push,
+	}
+}
+
+func channelValue(preference *Preference, info *TypeInfo, channel string) *bool {
+	if !info.Supports(channel) {
+		return nil
+	}
+
+	value := preference.Enabled(info, channel)
+	return &value
+}
+
+func formatMinutes(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseMinutes переводит время ЧЧ:ММ в минуты от полуночи.
+func parseMinutes(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
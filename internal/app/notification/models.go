@@ -0,0 +1,201 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    uuid.UUID  `db:"user_id"`
+	Type      string     `db:"type"`
+	Payload   Payload    `db:"payload"`
+	ReadAt    *time.Time `db:"read_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// Payload — данные для отображения уведомления, хранятся в JSONB. Автор
+// действия хранится только ссылкой: имя и аватар подставляются при чтении.
+type Payload struct {
+	ActorID       *uuid.UUID `json:"actor_id,omitempty"`
+	EventID       *uuid.UUID `json:"event_id,omitempty"`
+	EventTitle    string     `json:"event_title,omitempty"`
+	EventStartsAt *time.Time `json:"event_starts_at,omitempty"`
+	MessageID     *uuid.UUID `json:"message_id,omitempty"`
+	Text          string     `json:"text,omitempty"`
+}
+
+// Каналы доставки уведомлений.
+const (
+	ChannelEmail = "email"
+	ChannelInApp = "in_app"
+	ChannelPush  = "push"
+)
+
+// TypeInfo описывает тип уведомления и каналы, по которым он доставляется.
+// По умолчанию все поддерживаемые каналы включены.
+type TypeInfo struct {
+	Type  string
+	Email bool
+	InApp bool
+	Push  bool
+}
+
+var notificationTypes = []TypeInfo{
+	{Type: providers.NotificationParticipantJoined, InApp: true, Push: true},
+	{Type: providers.NotificationEventCancelled, Email: true, InApp: true, Push: true},
+	{Type: providers.NotificationWaitlistPromoted, Email: true, InApp: true, Push: true},
+	{Type: providers.NotificationChatMention, InApp: true, Push: true},
+	{Type: providers.NotificationEventJoined, Email: true},
+	{Type: providers.NotificationEventReminder, Email: true, InApp: true, Push: true},
+	{Type: providers.NotificationJoinRequestUpdated, Email: true},
+}
+
+func findType(notificationType string) (*TypeInfo, bool) {
+	for i := range notificationTypes {
+		if notificationTypes[i].Type == notificationType {
+			return &notificationTypes[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Supports сообщает, доставляется ли тип уведомления по каналу.
+func (t *TypeInfo) Supports(channel string) bool {
+	switch channel {
+	case ChannelEmail:
+		return t.Email
+	case ChannelInApp:
+		return t.InApp
+	case ChannelPush:
+		return t.Push
+	default:
+		return false
+	}
+}
+
+// Preference — выбор пользователя для типа уведомления. nil означает
+// значение по умолчанию.
+type Preference struct {
+	UserID uuid.UUID `db:"user_id"`
+	Type   string    `db:"type"`
+	Email  *bool     `db:"email"`
+	InApp  *bool     `db:"in_app"`
+	Push   *bool     `db:"push"`
+}
+
+// Enabled сообщает, включён ли канал для типа с учётом значений по умолчанию.
+func (p *Preference) Enabled(info *TypeInfo, channel string) bool {
+	if !info.Supports(channel) {
+		return false
+	}
+	if p == nil {
+		return true
+	}
+
+	var value *bool
+	switch channel {
+	case ChannelEmail:
+		value = p.Email
+	case ChannelInApp:
+		value = p.InApp
+	case ChannelPush:
+		value = p.Push
+	}
+
+	return value == nil || *value
+}
+
+type Settings struct {
+	UserID           uuid.UUID  `db:"user_id"`
+	QuietHoursStart  *int       `db:"quiet_hours_start"`
+	QuietHoursEnd    *int       `db:"quiet_hours_end"`
+	Timezone         *string    `db:"timezone"`
+	Locale           *string    `db:"locale"`
+	UnsubscribedAt   *time.Time `db:"unsubscribed_at"`
+	UnsubscribeToken string     `db:"unsubscribe_token"`
+	UpdatedAt        time.Time  `db:"updated_at"`
+}
+
+// defaultEmailLocale — язык писем, если пользователь его не выбрал.
+const defaultEmailLocale = "ru"
+
+// EmailLocale возвращает язык писем пользователя.
+func (s *Settings) EmailLocale() string {
+	if s.Locale == nil || *s.Locale == "" {
+		return defaultEmailLocale
+	}
+	return *s.Locale
+}
+
+// QuietHours возвращает тихие часы пользователя или nil, если они не заданы.
+func (s *Settings) QuietHours() *QuietHours {
+	if s.QuietHoursStart == nil || s.QuietHoursEnd == nil || *s.QuietHoursStart == *s.QuietHoursEnd {
+		return nil
+	}
+
+	location := time.UTC
+	if s.Timezone != nil {
+		if loc, err := time.LoadLocation(*s.Timezone); err == nil {
+			location = loc
+		}
+	}
+
+	return &QuietHours{Start: *s.QuietHoursStart, End: *s.QuietHoursEnd, Location: location}
+}
+
+// QuietHours — ежедневный интервал [Start, End) в минутах от полуночи по
+// местному времени. Если Start больше End, интервал переходит через полночь.
+type QuietHours struct {
+	Start    int
+	End      int
+	Location *time.Location
+}
+
+// Until возвращает момент окончания тихих часов, если now попадает в них.
+func (q *QuietHours) Until(now time.Time) (time.Time, bool) {
+	local := now.In(q.Location)
+	minute := local.Hour()*60 + local.Minute()
+
+	inside := minute >= q.Start && minute < q.End
+	if q.Start > q.End {
+		inside = minute >= q.Start || minute < q.End
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+
+	end := time.Date(local.Year(), local.Month(), local.Day(), q.End/60, q.End%60, 0, 0, q.Location)
+	if !end.After(local) {
+		end = time.Date(local.Year(), local.Month(), local.Day()+1, q.End/60, q.End%60, 0, 0, q.Location)
+	}
+
+	return end, true
+}
+
+// DeferredEmail — письмо, отложенное до окончания тихих часов.
+type DeferredEmail struct {
+	ID        uuid.UUID         `db:"id"`
+	UserID    uuid.UUID         `db:"user_id"`
+	Payload   events.EmailEvent `db:"payload"`
+	DeliverAt time.Time         `db:"deliver_at"`
+	CreatedAt time.Time         `db:"created_at"`
+}
+
+// maxUserAgentLength соответствует размеру колонки push_subscriptions.user_agent.
+const maxUserAgentLength = 512
+
+// PushSubscription — подписка браузера на Web Push.
+type PushSubscription struct {
+	ID        uuid.UUID `db:"id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Endpoint  string    `db:"endpoint"`
+	P256dh    string    `db:"p256dh"`
+	Auth      string    `db:"auth"`
+	UserAgent *string   `db:"user_agent"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Module struct {
+	Repo               Repository
+	PreferenceRepo     PreferenceRepository
+	DeferredEmailRepo  DeferredEmailRepository
+	PushRepo           PushSubscriptionRepository
+	Service            Service
+	Handler            *Handler
+	Provider           providers.NotificationProvider
+	PreferenceProvider providers.NotificationPreferenceProvider
+}
+
+func NewModule(
+	log *slog.Logger,
+	pool *pgxpool.Pool,
+	userProvider providers.UserProvider,
+	pusher *webpush.Sender,
+	pushTTL time.Duration,
+	allowInsecurePush bool,
+) *Module {
+	repo := NewRepository(pool)
+	preferenceRepo := NewPreferenceRepository(pool)
+	deferredEmailRepo := NewDeferredEmailRepository(pool)
+	pushRepo := NewPushSubscriptionRepository(pool)
+	service := NewService(log, repo, preferenceRepo, deferredEmailRepo, pushRepo, userProvider, pusher, pushTTL, allowInsecurePush)
+	handler := NewHandler(service)
+
+	return &Module{
+		Repo:               repo,
+		PreferenceRepo:     preferenceRepo,
+		DeferredEmailRepo:  deferredEmailRepo,
+		PushRepo:           pushRepo,
+		Service:            service,
+		Handler:            handler,
+		Provider:           NewNotificationProvider(service),
+		PreferenceProvider: NewNotificationPreferenceProvider(service),
+	}
+}
@@ -0,0 +1,199 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrInvalidUnsubscribeToken = errors.New("ссылка для отписки недействительна")
+
+type PreferenceRepository interface {
+	// GetSettings возвращает настройки пользователя, создавая их с токеном
+	// token, если их ещё нет.
+	GetSettings(ctx context.Context, userID uuid.UUID, token string) (*Settings, error)
+	GetPreferences(ctx context.Context, userID uuid.UUID) ([]Preference, error)
+	GetPreference(ctx context.Context, userID uuid.UUID, notificationType string) (*Preference, error)
+	// Save заменяет настройки и выбор каналов пользователя.
+	Save(ctx context.Context, settings *Settings, preferences []Preference) (*Settings, error)
+	Unsubscribe(ctx context.Context, token string) (uuid.UUID, error)
+}
+
+type preferenceRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPreferenceRepository(pool *pgxpool.Pool) PreferenceRepository {
+	return &preferenceRepository{pool}
+}
+
+const settingsColumns = `user_id, quiet_hours_start, quiet_hours_end, timezone, locale, unsubscribed_at, unsubscribe_token, updated_at`
+
+func (r *preferenceRepository) GetSettings(ctx context.Context, userID uuid.UUID, token string) (*Settings, error) {
+	// DO UPDATE без изменений нужен, чтобы RETURNING вернул уже существующую строку.
+	query := `
+		INSERT INTO notification_settings (user_id, unsubscribe_token)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+		RETURNING ` + settingsColumns + `
+	`
+
+	var settings Settings
+	if err := scanSettings(r.pool.QueryRow(ctx, query, userID, token), &settings); err != nil {
+		return nil, fmt.Errorf("не удалось получить настройки уведомлений: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func (r *preferenceRepository) GetPreferences(ctx context.Context, userID uuid.UUID) ([]Preference, error) {
+	query := `
+		SELECT user_id, type, email, in_app, push
+		FROM notification_preferences
+		WHERE user_id = $1
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить настройки уведомлений: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]Preference, 0)
+	for rows.Next() {
+		var preference Preference
+		if err := scanPreference(rows, &preference); err != nil {
+			return nil, fmt.Errorf("не удалось получить настройки уведомлений: %w", err)
+		}
+
+		result = append(result, preference)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить настройки уведомлений: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetPreference возвращает выбор пользователя для типа или nil, если
+// пользователь его не менял.
+func (r *preferenceRepository) GetPreference(ctx context.Context, userID uuid.UUID, notificationType string) (*Preference, error) {
+	query := `
+		SELECT user_id, type, email, in_app, push
+		FROM notification_preferences
+		WHERE user_id = $1 AND type = $2
+	`
+
+	var preference Preference
+	if err := scanPreference(r.pool.QueryRow(ctx, query, userID, notificationType), &preference); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("не удалось получить настройки уведомлений: %w", err)
+	}
+
+	return &preference, nil
+}
+
+func (r *preferenceRepository) Save(ctx context.Context, settings *Settings, preferences []Preference) (*Settings, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить настройки уведомлений: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE notification_settings
+		SET
+			quiet_hours_start = $2,
+			quiet_hours_end = $3,
+			timezone = $4,
+			locale = $5,
+			unsubscribed_at = $6,
+			updated_at = NOW()
+		WHERE user_id = $1
+		RETURNING ` + settingsColumns + `
+	`
+
+	var result Settings
+	err = scanSettings(tx.QueryRow(ctx, query,
+		settings.UserID,
+		settings.QuietHoursStart,
+		settings.QuietHoursEnd,
+		settings.Timezone,
+		settings.Locale,
+		settings.UnsubscribedAt,
+	), &result)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить настройки уведомлений: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM notification_preferences WHERE user_id = $1`, settings.UserID); err != nil {
+		return nil, fmt.Errorf("не удалось сохранить настройки уведомлений: %w", err)
+	}
+
+	for _, preference := range preferences {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO notification_preferences (user_id, type, email, in_app, push)
+			VALUES ($1, $2, $3, $4, $5)
+		`, settings.UserID, preference.Type, preference.Email, preference.InApp, preference.Push)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось сохранить настройки уведомлений: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("не удалось сохранить настройки уведомлений: %w", err)
+	}
+
+	return &result, nil
+}
+
+// Unsubscribe отключает письма пользователю, которому выдан токен.
+// Повторная отписка не меняет её время.
+func (r *preferenceRepository) Unsubscribe(ctx context.Context, token string) (uuid.UUID, error) {
+	query := `
+		UPDATE notification_settings
+		SET unsubscribed_at = COALESCE(unsubscribed_at, NOW()), updated_at = NOW()
+		WHERE unsubscribe_token = $1
+		RETURNING user_id
+	`
+
+	var userID uuid.UUID
+	if err := r.pool.QueryRow(ctx, query, token).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvalidUnsubscribeToken
+		}
+		return uuid.Nil, fmt.Errorf("не удалось отписаться от писем: %w", err)
+	}
+
+	return userID, nil
+}
+
+func scanSettings(row pgx.Row, settings *Settings) error {
+	return row.Scan(
+		&settings.UserID,
+		&settings.QuietHoursStart,
+		&settings.QuietHoursEnd,
+		&settings.Timezone,
+		&settings.Locale,
+		&settings.UnsubscribedAt,
+		&settings.UnsubscribeToken,
+		&settings.UpdatedAt,
+	)
+}
+
+func scanPreference(row pgx.Row, preference *Preference) error {
+	return row.Scan(
+		&preference.UserID,
+		&preference.Type,
+		&preference.Email,
+		&preference.InApp,
+		&preference.Push,
+	)
+}
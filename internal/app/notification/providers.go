@@ -0,0 +1,38 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/google/uuid"
+)
+
+type notificationProvider struct {
+	service Service
+}
+
+func NewNotificationProvider(service Service) providers.NotificationProvider {
+	return &notificationProvider{service: service}
+}
+
+func (p *notificationProvider) Notify(ctx context.Context, userID uuid.UUID, notification *providers.Notification) error {
+	return p.service.Notify(ctx, userID, notification)
+}
+
+type notificationPreferenceProvider struct {
+	service Service
+}
+
+func NewNotificationPreferenceProvider(service Service) providers.NotificationPreferenceProvider {
+	return &notificationPreferenceProvider{service: service}
+}
+
+func (p *notificationPreferenceProvider) CheckEmail(ctx context.Context, email, notificationType string) (*providers.EmailDecision, error) {
+	return p.service.CheckEmail(ctx, email, notificationType)
+}
+
+func (p *notificationPreferenceProvider) DeferEmail(ctx context.Context, userID uuid.UUID, event *events.EmailEvent, until time.Time) error {
+	return p.service.DeferEmail(ctx, userID, event, until)
+}
@@ -0,0 +1,122 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrPushSubscriptionNotFound = errors.New("push-подписка не найдена")
+
+type PushSubscriptionRepository interface {
+	// Save сохраняет подписку. Если endpoint уже зарегистрирован, подписка
+	// переходит к пользователю userID с новыми ключами.
+	Save(ctx context.Context, model *PushSubscription) (*PushSubscription, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]PushSubscription, error)
+	Delete(ctx context.Context, userID uuid.UUID, endpoint string) error
+	DeleteByID(ctx context.Context, id uuid.UUID) error
+}
+
+type pushSubscriptionRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPushSubscriptionRepository(pool *pgxpool.Pool) PushSubscriptionRepository {
+	return &pushSubscriptionRepository{pool}
+}
+
+const pushSubscriptionColumns = `id, user_id, endpoint, p256dh, auth, user_agent, created_at`
+
+func (r *pushSubscriptionRepository) Save(ctx context.Context, model *PushSubscription) (*PushSubscription, error) {
+	query := `
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (endpoint) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			p256dh = EXCLUDED.p256dh,
+			auth = EXCLUDED.auth,
+			user_agent = EXCLUDED.user_agent
+		RETURNING ` + pushSubscriptionColumns + `
+	`
+
+	var result PushSubscription
+	err := scanPushSubscription(r.pool.QueryRow(ctx, query,
+		model.UserID,
+		model.Endpoint,
+		model.P256dh,
+		model.Auth,
+		model.UserAgent,
+	), &result)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить push-подписку: %w", err)
+	}
+
+	return &result, nil
+}
+
+func (r *pushSubscriptionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]PushSubscription, error) {
+	query := `
+		SELECT ` + pushSubscriptionColumns + `
+		FROM push_subscriptions
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить push-подписки: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]PushSubscription, 0)
+	for rows.Next() {
+		var subscription PushSubscription
+		if err := scanPushSubscription(rows, &subscription); err != nil {
+			return nil, fmt.Errorf("не удалось получить push-подписку: %w", err)
+		}
+
+		result = append(result, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить push-подписки: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *pushSubscriptionRepository) Delete(ctx context.Context, userID uuid.UUID, endpoint string) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM push_subscriptions WHERE user_id = $1 AND endpoint = $2`, userID, endpoint)
+	if err != nil {
+		return fmt.Errorf("не удалось удалить push-подписку: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPushSubscriptionNotFound
+	}
+
+	return nil
+}
+
+func (r *pushSubscriptionRepository) DeleteByID(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM push_subscriptions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("не удалось удалить push-подписку: %w", err)
+	}
+
+	return nil
+}
+
+func scanPushSubscription(row pgx.Row, subscription *PushSubscription) error {
+	return row.Scan(
+		&subscription.ID,
+		&subscription.UserID,
+		&subscription.Endpoint,
+		&subscription.P256dh,
+		&subscription.Auth,
+		&subscription.UserAgent,
+		&subscription.CreatedAt,
+	)
+}
@@ -0,0 +1,162 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrNotificationNotFound = errors.New("уведомление не найдено")
+
+type Repository interface {
+	Create(ctx context.Context, model *Notification) (*Notification, error)
+	GetPage(ctx context.Context, userID uuid.UUID, before *Cursor, limit int, unreadOnly bool) ([]Notification, error)
+	MarkRead(ctx context.Context, userID, id uuid.UUID) error
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) Repository {
+	return &repository{pool}
+}
+
+const notificationColumns = `id, user_id, type, payload, read_at, created_at`
+
+func (r *repository) Create(ctx context.Context, model *Notification) (*Notification, error) {
+	payload, err := json.Marshal(model.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить уведомление: %w", err)
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, type, payload)
+		VALUES ($1, $2, $3::jsonb)
+		RETURNING id, created_at
+	`
+
+	err = r.pool.QueryRow(ctx, query, model.UserID, model.Type, string(payload)).Scan(
+		&model.ID,
+		&model.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сохранить уведомление: %w", err)
+	}
+
+	return model, nil
+}
+
+// GetPage возвращает до limit уведомлений пользователя, созданных раньше
+// курсора before (или последних, если курсор не задан), от новых к старым.
+func (r *repository) GetPage(ctx context.Context, userID uuid.UUID, before *Cursor, limit int, unreadOnly bool) ([]Notification, error) {
+	var beforeCreatedAt *time.Time
+	var beforeID *uuid.UUID
+	if before != nil {
+		beforeCreatedAt = &before.CreatedAt
+		beforeID = &before.ID
+	}
+
+	query := `
+		SELECT ` + notificationColumns + `
+		FROM notifications
+		WHERE user_id = $1
+			AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3))
+			AND (NOT $4 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, beforeCreatedAt, beforeID, unreadOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить уведомления: %w", err)
+	}
+
+	return scanNotifications(rows)
+}
+
+// MarkRead отмечает уведомление прочитанным. Повторная отметка не меняет
+// время прочтения.
+func (r *repository) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := r.pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("не удалось отметить уведомление: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := `
+		UPDATE notifications
+		SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL
+	`
+
+	result, err := r.pool.Exec(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось отметить уведомления: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (r *repository) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`
+
+	var count int
+	if err := r.pool.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("не удалось посчитать уведомления: %w", err)
+	}
+
+	return count, nil
+}
+
+func scanNotifications(rows pgx.Rows) ([]Notification, error) {
+	defer rows.Close()
+
+	result := make([]Notification, 0)
+	for rows.Next() {
+		var notification Notification
+		var payload []byte
+		err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.Type,
+			&payload,
+			&notification.ReadAt,
+			&notification.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось получить уведомление: %w", err)
+		}
+		if err := json.Unmarshal(payload, &notification.Payload); err != nil {
+			return nil, fmt.Errorf("не удалось разобрать уведомление: %w", err)
+		}
+
+		result = append(result, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить уведомления: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,557 @@
+package notification
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush"
+	"github.com/google/uuid"
+)
+
+type Service interface {
+	Notify(ctx context.Context, userID uuid.UUID, notification *providers.Notification) error
+	GetNotifications(ctx context.Context, userID uuid.UUID, before *Cursor, limit int, unreadOnly bool) (*GetNotificationsPageResponse, error)
+	GetUnreadCount(ctx context.Context, userID uuid.UUID) (*GetUnreadCountResponse, error)
+	MarkRead(ctx context.Context, userID, id uuid.UUID) error
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (*MarkAllReadResponse, error)
+
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*GetPreferencesResponse, error)
+	UpdatePreferences(ctx context.Context, userID uuid.UUID, req *UpdatePreferencesRequest) (*GetPreferencesResponse, error)
+	Unsubscribe(ctx context.Context, token string) error
+	CheckEmail(ctx context.Context, email, notificationType string) (*providers.EmailDecision, error)
+	DeferEmail(ctx context.Context, userID uuid.UUID, event *events.EmailEvent, until time.Time) error
+	SendDeferredEmails(ctx context.Context) error
+
+	GetPushPublicKey() (*GetPushPublicKeyResponse, error)
+	SubscribePush(ctx context.Context, userID uuid.UUID, req *CreatePushSubscriptionRequest, userAgent string) (*GetPushSubscriptionResponse, error)
+	UnsubscribePush(ctx context.Context, userID uuid.UUID, endpoint string) error
+}
+
+var (
+	ErrUnknownNotificationType = errors.New("неизвестный тип уведомления")
+	ErrUnsupportedChannel      = errors.New("этот тип уведомлений не доставляется по выбранному каналу")
+	ErrPushDisabled            = errors.New("push-уведомления не настроены")
+	ErrInsecurePushEndpoint    = errors.New("адрес push-подписки должен использовать HTTPS")
+)
+
+const deferredEmailBatchSize = 100
+
+// pushTimeout ограничивает отправку одного уведомления на все устройства пользователя.
+const pushTimeout = 30 * time.Second
+
+type service struct {
+	log               *slog.Logger
+	repo              Repository
+	preferenceRepo    PreferenceRepository
+	deferredEmailRepo DeferredEmailRepository
+	pushRepo          PushSubscriptionRepository
+	userProvider      providers.UserProvider
+	pusher            *webpush.Sender
+	pushTTL           time.Duration
+	allowInsecurePush bool
+}
+
+func NewService(
+	log *slog.Logger,
+	repo Repository,
+	preferenceRepo PreferenceRepository,
+	deferredEmailRepo DeferredEmailRepository,
+	pushRepo PushSubscriptionRepository,
+	userProvider providers.UserProvider,
+	pusher *webpush.Sender,
+	pushTTL time.Duration,
+	allowInsecurePush bool,
+) Service {
+	return &service{
+		log:               log,
+		repo:              repo,
+		preferenceRepo:    preferenceRepo,
+		deferredEmailRepo: deferredEmailRepo,
+		pushRepo:          pushRepo,
+		userProvider:      userProvider,
+		pusher:            pusher,
+		pushTTL:           pushTTL,
+		allowInsecurePush: allowInsecurePush,
+	}
+}
+
+// Notify создаёт уведомление в приложении и отправляет его push-подпискам
+// пользователя — по тем каналам, которые он не отключил. Push отправляется
+// в фоне, чтобы медленный сервис доставки не задерживал основное действие.
+func (s *service) Notify(ctx context.Context, userID uuid.UUID, notification *providers.Notification) error {
+	if notification.ActorID != nil && *notification.ActorID == userID {
+		return nil
+	}
+
+	info, preference, err := s.getPreference(ctx, userID, notification.Type)
+	if err != nil {
+		return err
+	}
+
+	model := ProviderNotificationToModel(userID, notification)
+	model.CreatedAt = time.Now()
+	if channelAllowed(info, preference, ChannelInApp) {
+		model, err = s.repo.Create(ctx, model)
+		if err != nil {
+			s.log.Error("failed to create notification", "user_id", userID, "type", notification.Type, "error", err)
+			return err
+		}
+
+		s.log.Info("notification created", "notification_id", model.ID, "user_id", userID, "type", model.Type)
+	}
+
+	if s.pusher != nil && channelAllowed(info, preference, ChannelPush) {
+		go s.sendPush(context.WithoutCancel(ctx), model)
+	}
+
+	return nil
+}
+
+// GetNotifications возвращает страницу уведомлений, более старую, чем курсор before.
+func (s *service) GetNotifications(ctx context.Context, userID uuid.UUID, before *Cursor, limit int, unreadOnly bool) (*GetNotificationsPageResponse, error) {
+	notifications, err := s.repo.GetPage(ctx, userID, before, limit+1, unreadOnly)
+	if err != nil {
+		s.log.Error("failed to get notifications page", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	result := &GetNotificationsPageResponse{}
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		cursor := NewCursor(&notifications[len(notifications)-1]).Encode()
+		result.NextCursor = &cursor
+	}
+
+	result.UnreadCount, err = s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to count unread notifications", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	actors, err := s.getActors(ctx, notifications)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Items = NotificationsToGetResponse(notifications, actors)
+	return result, nil
+}
+
+func (s *service) GetUnreadCount(ctx context.Context, userID uuid.UUID) (*GetUnreadCountResponse, error) {
+	count, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to count unread notifications", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	return &GetUnreadCountResponse{Count: count}, nil
+}
+
+func (s *service) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	if err := s.repo.MarkRead(ctx, userID, id); err != nil {
+		if !errors.Is(err, ErrNotificationNotFound) {
+			s.log.Error("failed to mark notification read", "notification_id", id, "user_id", userID, "error", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) MarkAllRead(ctx context.Context, userID uuid.UUID) (*MarkAllReadResponse, error) {
+	updated, err := s.repo.MarkAllRead(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to mark all notifications read", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	return &MarkAllReadResponse{Updated: updated}, nil
+}
+
+func (s *service) GetPreferences(ctx context.Context, userID uuid.UUID) (*GetPreferencesResponse, error) {
+	settings, err := s.getSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences, err := s.preferenceRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get notification preferences", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	return PreferencesToGetResponse(settings, preferences), nil
+}
+
+func (s *service) UpdatePreferences(ctx context.Context, userID uuid.UUID, req *UpdatePreferencesRequest) (*GetPreferencesResponse, error) {
+	settings, err := s.getSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := make(map[string]*Preference, len(req.Types))
+	for i := range req.Types {
+		preference := UpdateTypePreferenceRequestToModel(&req.Types[i], userID)
+
+		info, ok := findType(preference.Type)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNotificationType, preference.Type)
+		}
+		if (preference.Email != nil && !info.Email) ||
+			(preference.InApp != nil && !info.InApp) ||
+			(preference.Push != nil && !info.Push) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedChannel, preference.Type)
+		}
+		if preference.Email == nil && preference.InApp == nil && preference.Push == nil {
+			delete(byType, preference.Type)
+			continue
+		}
+
+		byType[preference.Type] = preference
+	}
+
+	settings.QuietHoursStart, settings.QuietHoursEnd, settings.Timezone = nil, nil, nil
+	if req.QuietHours != nil {
+		start, err := parseMinutes(req.QuietHours.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseMinutes(req.QuietHours.End)
+		if err != nil {
+			return nil, err
+		}
+
+		settings.QuietHoursStart, settings.QuietHoursEnd = &start, &end
+		settings.Timezone = &req.QuietHours.Timezone
+	}
+
+	settings.Locale = req.Locale
+
+	if !req.Unsubscribed {
+		settings.UnsubscribedAt = nil
+	} else if settings.UnsubscribedAt == nil {
+		now := time.Now()
+		settings.UnsubscribedAt = &now
+	}
+
+	preferences := make([]Preference, 0, len(byType))
+	for _, preference := range byType {
+		preferences = append(preferences, *preference)
+	}
+
+	settings, err = s.preferenceRepo.Save(ctx, settings, preferences)
+	if err != nil {
+		s.log.Error("failed to save notification preferences", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	s.log.Info("notification preferences updated", "user_id", userID, "unsubscribed", settings.UnsubscribedAt != nil)
+	return PreferencesToGetResponse(settings, preferences), nil
+}
+
+// Unsubscribe отключает письма по ссылке List-Unsubscribe. Служебные письма
+// (подтверждение адреса, сброс пароля) продолжают приходить.
+func (s *service) Unsubscribe(ctx context.Context, token string) error {
+	userID, err := s.preferenceRepo.Unsubscribe(ctx, token)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidUnsubscribeToken) {
+			s.log.Error("failed to unsubscribe", "error", err)
+		}
+		return err
+	}
+
+	s.log.Info("user unsubscribed from emails", "user_id", userID)
+	return nil
+}
+
+func (s *service) CheckEmail(ctx context.Context, email, notificationType string) (*providers.EmailDecision, error) {
+	userID, err := s.userProvider.GetUserIDByEmail(ctx, email)
+	if err != nil {
+		s.log.Error("failed to get email recipient", "error", err)
+		return nil, err
+	}
+	if userID == nil {
+		return &providers.EmailDecision{Allowed: true}, nil
+	}
+
+	settings, err := s.getSettings(ctx, *userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &providers.EmailDecision{
+		UserID:           *userID,
+		Allowed:          true,
+		UnsubscribeToken: settings.UnsubscribeToken,
+		Locale:           settings.EmailLocale(),
+	}
+	if notificationType == "" {
+		return result, nil
+	}
+
+	if settings.UnsubscribedAt != nil {
+		result.Allowed = false
+		return result, nil
+	}
+
+	result.Allowed, err = s.channelEnabled(ctx, *userID, notificationType, ChannelEmail)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Allowed {
+		return result, nil
+	}
+
+	if quietHours := settings.QuietHours(); quietHours != nil {
+		if until, ok := quietHours.Until(time.Now()); ok {
+			result.DeferUntil = &until
+		}
+	}
+
+	return result, nil
+}
+
+func (s *service) DeferEmail(ctx context.Context, userID uuid.UUID, event *events.EmailEvent, until time.Time) error {
+	email, err := s.deferredEmailRepo.Create(ctx, &DeferredEmail{
+		UserID:    userID,
+		Payload:   *event,
+		DeliverAt: until,
+	})
+	if err != nil {
+		s.log.Error("failed to defer email", "user_id", userID, "template", event.Template, "error", err)
+		return err
+	}
+
+	s.log.Info("email deferred until quiet hours end", "id", email.ID, "user_id", userID, "template", event.Template, "deliver_at", until)
+	return nil
+}
+
+// SendDeferredEmails возвращает в очередь письма, у которых закончились тихие
+// часы получателя: письмо переносится в outbox одной транзакцией.
+func (s *service) SendDeferredEmails(ctx context.Context) error {
+	emails, err := s.deferredEmailRepo.GetDue(ctx, time.Now(), deferredEmailBatchSize)
+	if err != nil {
+		s.log.Error("failed to get deferred emails", "error", err)
+		return err
+	}
+
+	released := 0
+	for _, email := range emails {
+		ok, err := s.deferredEmailRepo.Release(ctx, &email)
+		if err != nil {
+			s.log.Error("failed to release deferred email", "id", email.ID, "error", err)
+			return err
+		}
+		if ok {
+			released++
+		}
+	}
+
+	if released > 0 {
+		s.log.Info("deferred emails sent", "count", released)
+	}
+
+	return nil
+}
+
+func (s *service) GetPushPublicKey() (*GetPushPublicKeyResponse, error) {
+	if s.pusher == nil {
+		return nil, ErrPushDisabled
+	}
+
+	return &GetPushPublicKeyResponse{PublicKey: s.pusher.PublicKey()}, nil
+}
+
+func (s *service) SubscribePush(ctx context.Context, userID uuid.UUID, req *CreatePushSubscriptionRequest, userAgent string) (*GetPushSubscriptionResponse, error) {
+	if s.pusher == nil {
+		return nil, ErrPushDisabled
+	}
+
+	model := CreatePushSubscriptionRequestToModel(req, userID, userAgent)
+
+	endpoint, err := url.Parse(model.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, webpush.ErrInvalidSubscription
+	}
+	if endpoint.Scheme != "https" && !(s.allowInsecurePush && endpoint.Scheme == "http") {
+		return nil, ErrInsecurePushEndpoint
+	}
+
+	if err := PushSubscriptionToWebPush(model).Validate(); err != nil {
+		return nil, err
+	}
+
+	subscription, err := s.pushRepo.Save(ctx, model)
+	if err != nil {
+		s.log.Error("failed to save push subscription", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	s.log.Info("push subscription saved", "subscription_id", subscription.ID, "user_id", userID)
+	return PushSubscriptionToGetResponse(subscription), nil
+}
+
+func (s *service) UnsubscribePush(ctx context.Context, userID uuid.UUID, endpoint string) error {
+	if err := s.pushRepo.Delete(ctx, userID, endpoint); err != nil {
+		if !errors.Is(err, ErrPushSubscriptionNotFound) {
+			s.log.Error("failed to delete push subscription", "user_id", userID, "error", err)
+		}
+		return err
+	}
+
+	s.log.Info("push subscription deleted", "user_id", userID)
+	return nil
+}
+
+// sendPush отправляет уведомление на все устройства пользователя. Во время
+// тихих часов push не отправляется: уведомление остаётся в приложении.
+// Подписки, которые сервис доставки больше не знает, удаляются.
+func (s *service) sendPush(ctx context.Context, model *Notification) {
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+
+	subscriptions, err := s.pushRepo.GetByUserID(ctx, model.UserID)
+	if err != nil {
+		s.log.Error("failed to get push subscriptions", "user_id", model.UserID, "error", err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	settings, err := s.getSettings(ctx, model.UserID)
+	if err != nil {
+		return
+	}
+	if quietHours := settings.QuietHours(); quietHours != nil {
+		if _, ok := quietHours.Until(time.Now()); ok {
+			s.log.Debug("push skipped during quiet hours", "user_id", model.UserID, "type", model.Type)
+			return
+		}
+	}
+
+	actors, err := s.getActors(ctx, []Notification{*model})
+	if err != nil {
+		return
+	}
+
+	payload, err := json.Marshal(NotificationToPushMessage(model, actors))
+	if err != nil {
+		s.log.Error("failed to marshal push message", "user_id", model.UserID, "error", err)
+		return
+	}
+
+	options := webpush.Options{TTL: s.pushTTL, Urgency: webpush.UrgencyNormal}
+	for _, subscription := range subscriptions {
+		err := s.pusher.Send(ctx, PushSubscriptionToWebPush(&subscription), payload, options)
+		switch {
+		case errors.Is(err, webpush.ErrSubscriptionGone):
+			if err := s.pushRepo.DeleteByID(ctx, subscription.ID); err != nil {
+				s.log.Error("failed to delete expired push subscription", "subscription_id", subscription.ID, "error", err)
+				continue
+			}
+			s.log.Info("expired push subscription deleted", "subscription_id", subscription.ID, "user_id", model.UserID)
+		case err != nil:
+			s.log.Error("failed to send push", "subscription_id", subscription.ID, "user_id", model.UserID, "error", err)
+		}
+	}
+}
+
+func (s *service) getSettings(ctx context.Context, userID uuid.UUID) (*Settings, error) {
+	token, err := generateUnsubscribeToken()
+	if err != nil {
+		s.log.Error("failed to generate unsubscribe token", "error", err)
+		return nil, err
+	}
+
+	settings, err := s.preferenceRepo.GetSettings(ctx, userID, token)
+	if err != nil {
+		s.log.Error("failed to get notification settings", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// channelEnabled сообщает, включён ли канал для типа уведомления.
+func (s *service) channelEnabled(ctx context.Context, userID uuid.UUID, notificationType, channel string) (bool, error) {
+	info, preference, err := s.getPreference(ctx, userID, notificationType)
+	if err != nil {
+		return false, err
+	}
+
+	return channelAllowed(info, preference, channel), nil
+}
+
+// getPreference возвращает описание типа и выбор пользователя для него.
+// Для неизвестного типа описание равно nil.
+func (s *service) getPreference(ctx context.Context, userID uuid.UUID, notificationType string) (*TypeInfo, *Preference, error) {
+	info, ok := findType(notificationType)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	preference, err := s.preferenceRepo.GetPreference(ctx, userID, notificationType)
+	if err != nil {
+		s.log.Error("failed to get notification preference", "user_id", userID, "type", notificationType, "error", err)
+		return nil, nil, err
+	}
+
+	return info, preference, nil
+}
+
+// channelAllowed — неизвестные типы не настраиваются и доставляются всегда.
+func channelAllowed(info *TypeInfo, preference *Preference, channel string) bool {
+	if info == nil {
+		return true
+	}
+
+	return preference.Enabled(info, channel)
+}
+
+func generateUnsubscribeToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// getActors подгружает авторов действий одним пакетным запросом.
+func (s *service) getActors(ctx context.Context, notifications []Notification) (map[string]providers.UserInfo, error) {
+	actorIDs := make([]uuid.UUID, 0, len(notifications))
+	seen := make(map[uuid.UUID]struct{}, len(notifications))
+	for _, notification := range notifications {
+		actorID := notification.Payload.ActorID
+		if actorID == nil {
+			continue
+		}
+		if _, ok := seen[*actorID]; ok {
+			continue
+		}
+		seen[*actorID] = struct{}{}
+		actorIDs = append(actorIDs, *actorID)
+	}
+
+	if len(actorIDs) == 0 {
+		return map[string]providers.UserInfo{}, nil
+	}
+
+	actors, err := s.userProvider.GetUsersByIDs(ctx, actorIDs)
+	if err != nil {
+		s.log.Error("failed to get notification actors", "count", len(actorIDs), "error", err)
+		return nil, err
+	}
+
+	return actors, nil
+}
@@ -0,0 +1,135 @@
+package notification
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush/webpushtest"
+	"github.com/google/uuid"
+)
+
+// fakePushRepository хранит подписки в памяти. Методы, которые sendPush не
+// вызывает, не реализованы.
+type fakePushRepository struct {
+	PushSubscriptionRepository
+
+	mu            sync.Mutex
+	subscriptions []PushSubscription
+	deleted       []uuid.UUID
+}
+
+func (r *fakePushRepository) GetByUserID(_ context.Context, userID uuid.UUID) ([]PushSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []PushSubscription
+	for _, subscription := range r.subscriptions {
+		if subscription.UserID == userID {
+			result = append(result, subscription)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakePushRepository) DeleteByID(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deleted = append(r.deleted, id)
+	r.subscriptions = slices.DeleteFunc(r.subscriptions, func(subscription PushSubscription) bool {
+		return subscription.ID == id
+	})
+	return nil
+}
+
+type fakePreferenceRepository struct {
+	PreferenceRepository
+}
+
+func (fakePreferenceRepository) GetSettings(_ context.Context, userID uuid.UUID, token string) (*Settings, error) {
+	return &Settings{UserID: userID, UnsubscribeToken: token}, nil
+}
+
+type fakeUserProvider struct {
+	providers.UserProvider
+}
+
+func (fakeUserProvider) GetUsersByIDs(_ context.Context, ids []uuid.UUID) (map[string]providers.UserInfo, error) {
+	users := make(map[string]providers.UserInfo, len(ids))
+	for _, id := range ids {
+		users[id.String()] = providers.UserInfo{ID: id.String(), FirstName: "Анна"}
+	}
+	return users, nil
+}
+
+func TestSendPushDeletesExpiredSubscriptions(t *testing.T) {
+	pushService := webpushtest.NewFakeService()
+	defer pushService.Close()
+
+	publicKey, privateKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := webpush.NewSender(webpush.VAPID{PublicKey: publicKey, PrivateKey: privateKey, Subject: "mailto:push@example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := uuid.New()
+	pushRepo := &fakePushRepository{}
+	for range 2 {
+		subscription, err := pushService.Subscribe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pushRepo.subscriptions = append(pushRepo.subscriptions, PushSubscription{
+			ID:       uuid.New(),
+			UserID:   userID,
+			Endpoint: subscription.Endpoint,
+			P256dh:   subscription.P256dh,
+			Auth:     subscription.Auth,
+		})
+	}
+	active, expired := pushRepo.subscriptions[0], pushRepo.subscriptions[1]
+	pushService.Expire(expired.Endpoint)
+
+	s := &service{
+		log:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		preferenceRepo: fakePreferenceRepository{},
+		pushRepo:       pushRepo,
+		userProvider:   fakeUserProvider{},
+		pusher:         sender,
+		pushTTL:        time.Hour,
+	}
+
+	actorID, eventID := uuid.New(), uuid.New()
+	s.sendPush(context.Background(), &Notification{
+		ID:     uuid.New(),
+		UserID: userID,
+		Type:   providers.NotificationParticipantJoined,
+		Payload: Payload{
+			ActorID:    &actorID,
+			EventID:    &eventID,
+			EventTitle: "Пробежка",
+		},
+	})
+
+	if !slices.Equal(pushRepo.deleted, []uuid.UUID{expired.ID}) {
+		t.Errorf("deleted = %v, want [%v]", pushRepo.deleted, expired.ID)
+	}
+
+	messages := pushService.Messages()
+	if len(messages) != 1 || messages[0].Endpoint != active.Endpoint {
+		t.Fatalf("messages = %+v, want one message to %s", messages, active.Endpoint)
+	}
+	if messages[0].TTL != "3600" {
+		t.Errorf("TTL = %q, want 3600", messages[0].TTL)
+	}
+}
@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// unsubscribePage — страница, которую видит пользователь, открыв ссылку для
+// отписки в браузере. Форма без action отправляет POST на тот же адрес,
+// поэтому токен из ссылки сохраняется.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} — Meetly</title>
+<style>
+body { font-family: Arial, sans-serif; color: #333; max-width: 480px; margin: 60px auto; padding: 0 20px; text-align: center; }
+button { background: #4f46e5; color: #fff; border: 0; border-radius: 6px; padding: 12px 24px; font-size: 16px; cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Text}}</p>
+{{if .Confirm}}<form method="post">
+<input type="hidden" name="confirm" value="1">
+<button type="submit">Отписаться</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+type unsubscribePageData struct {
+	Title   string
+	Text    string
+	Confirm bool
+}
+
+var (
+	unsubscribeConfirm = unsubscribePageData{
+		Title:   "Отписаться от писем",
+		Text:    "Вы больше не будете получать письма о встречах. Письма для входа в аккаунт и сброса пароля продолжат приходить.",
+		Confirm: true,
+	}
+	unsubscribeDone = unsubscribePageData{
+		Title: "Вы отписались",
+		Text:  "Письма о встречах больше не будут приходить. Включить их снова можно в настройках уведомлений.",
+	}
+	unsubscribeInvalid = unsubscribePageData{
+		Title: "Ссылка недействительна",
+		Text:  "Ссылка для отписки устарела или повреждена. Отключить письма можно в настройках уведомлений.",
+	}
+	unsubscribeFailed = unsubscribePageData{
+		Title: "Не удалось отписаться",
+		Text:  "Попробуйте ещё раз позже.",
+	}
+)
+
+func renderUnsubscribePage(w http.ResponseWriter, data unsubscribePageData, statusCode int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	unsubscribePage.Execute(w, data)
+}
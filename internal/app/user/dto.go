@@ -14,5 +14,10 @@ type SaveUserRequest struct {
 	LastName  string `json:"last_name" validate:"required,min=2"`
 	BirthDate string `json:"birth_date" validate:"required,date"`
 	Gender    string `json:"gender" validate:"required"`
-	AvatarUrl string `json:"avatar_url" validate:"required,url"`
+}
+
+// DirectMessageSettings — принимает ли пользователь личные сообщения от людей
+// без общих событий. Диалог возможен, только если согласны оба.
+type DirectMessageSettings struct {
+	Open bool `json:"open"`
 }
@@ -2,6 +2,7 @@ package user
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"net/http"
 
@@ -73,6 +74,122 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, response, http.StatusOK)
 }
 
+func (h *Handler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	blockedID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.BlockUser(r.Context(), userID, blockedID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	blockedID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		boom.BadRequest(w, "неверный формат ID")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	if err := h.service.UnblockUser(r.Context(), userID, blockedID); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	response, err := h.service.GetBlockedUsers(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) GetDirectMessageSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	response, err := h.service.GetDirectMessageSettings(r.Context(), userID)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) UpdateDirectMessageSettings(w http.ResponseWriter, r *http.Request) {
+	var req DirectMessageSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		boom.BadRequest(w, "неверный формат JSON")
+		return
+	}
+
+	userID, err := currentUserID(r)
+	if err != nil {
+		boom.Unathorized(w, "требуется аутентификация")
+		return
+	}
+
+	response, err := h.service.UpdateDirectMessageSettings(r.Context(), userID, &req)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	h.sendJSON(w, response, http.StatusOK)
+}
+
+func (h *Handler) sendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		boom.NotFound(w, err.Error())
+	case errors.Is(err, ErrCannotBlockSelf):
+		boom.BadRequest(w, err.Error())
+	default:
+		boom.Internal(w, err.Error())
+	}
+}
+
+func currentUserID(r *http.Request) (uuid.UUID, error) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		return uuid.Nil, errors.New("user_id not found in context")
+	}
+
+	return uuid.Parse(userID)
+}
+
 func (h *Handler) sendJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
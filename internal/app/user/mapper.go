@@ -11,7 +11,6 @@ func SaveRequestToUser(dto *SaveUserRequest, id uuid.UUID) (*User, error) {
 		ID:        id,
 		FirstName: dto.FirstName,
 		LastName:  dto.LastName,
-		AvatarUrl: dto.AvatarUrl,
 	}
 
 	switch dto.Gender {
@@ -34,6 +33,7 @@ func SaveRequestToUser(dto *SaveUserRequest, id uuid.UUID) (*User, error) {
 
 func UserToGetResponse(model *User) *GetUserResponse {
 	return &GetUserResponse{
+		ID:        model.ID.String(),
 		FirstName: model.FirstName,
 		LastName:  model.LastName,
 		BirthDate: model.BirthDate.Format(time.DateOnly),
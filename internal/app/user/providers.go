@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"errors"
 	"github.com/google/uuid"
 
 	"github.com/RuLap/meetly-api/meetly/internal/pkg/providers"
@@ -53,3 +54,36 @@ func (p *userProvider) GetUserByID(ctx context.Context, userID uuid.UUID) (*prov
 
 	return &result, err
 }
+
+func (p *userProvider) GetUserEmail(ctx context.Context, userID uuid.UUID) (string, error) {
+	return p.service.GetEmailByID(ctx, userID)
+}
+
+func (p *userProvider) GetUserIDByEmail(ctx context.Context, email string) (*uuid.UUID, error) {
+	id, err := p.service.GetIDByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+func (p *userProvider) SetAvatar(ctx context.Context, userID uuid.UUID, avatarUrl string) (string, error) {
+	return p.service.UpdateAvatar(ctx, userID, avatarUrl)
+}
+
+func (p *userProvider) HasBlock(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	return p.service.HasBlock(ctx, userA, userB)
+}
+
+func (p *userProvider) AllowsDirectMessages(ctx context.Context, userID uuid.UUID) (bool, error) {
+	settings, err := p.service.GetDirectMessageSettings(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return settings.Open, nil
+}
@@ -2,15 +2,28 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*User, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error)
+	GetEmailByID(ctx context.Context, id uuid.UUID) (string, error)
+	GetIDByEmail(ctx context.Context, email string) (uuid.UUID, error)
 	Update(ctx context.Context, req *User) (*User, error)
+	UpdateAvatar(ctx context.Context, id uuid.UUID, avatarUrl string) (string, error)
+	Exists(ctx context.Context, id uuid.UUID) (bool, error)
+	Block(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	Unblock(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	GetBlocked(ctx context.Context, blockerID uuid.UUID) ([]User, error)
+	HasBlock(ctx context.Context, userA, userB uuid.UUID) (bool, error)
+	GetOpenDirectMessages(ctx context.Context, id uuid.UUID) (bool, error)
+	SetOpenDirectMessages(ctx context.Context, id uuid.UUID, open bool) error
 }
 
 type repository struct {
@@ -43,6 +56,69 @@ func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	return &user, nil
 }
 
+func (r *repository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	query := `
+		SELECT id, first_name, last_name, birth_date, gender, avatar_url
+		FROM users WHERE id = ANY($1)`
+
+	rows, err := r.pool.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить пользователей")
+	}
+	defer rows.Close()
+
+	users := make([]User, 0, len(ids))
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&user.ID,
+			&user.FirstName,
+			&user.LastName,
+			&user.BirthDate,
+			&user.Gender,
+			&user.AvatarUrl,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось получить пользователя")
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить пользователей")
+	}
+
+	return users, nil
+}
+
+func (r *repository) GetEmailByID(ctx context.Context, id uuid.UUID) (string, error) {
+	query := `
+		SELECT email
+		FROM users WHERE id = $1`
+
+	var email string
+	if err := r.pool.QueryRow(ctx, query, id).Scan(&email); err != nil {
+		return "", fmt.Errorf("не удалось получить email пользователя")
+	}
+
+	return email, nil
+}
+
+func (r *repository) GetIDByEmail(ctx context.Context, email string) (uuid.UUID, error) {
+	query := `SELECT id FROM users WHERE email = $1`
+
+	var id uuid.UUID
+	if err := r.pool.QueryRow(ctx, query, email).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrUserNotFound
+		}
+		return uuid.Nil, fmt.Errorf("не удалось получить пользователя")
+	}
+
+	return id, nil
+}
+
 func (r *repository) Update(ctx context.Context, req *User) (*User, error) {
 	query := `
 		UPDATE users
@@ -50,15 +126,156 @@ func (r *repository) Update(ctx context.Context, req *User) (*User, error) {
 			first_name = $2,
 			last_name = $3,
 			birth_date = $4,
-			gender = $5,
-			avatar_url = $6
+			gender = $5
 		WHERE id = $1
+		RETURNING COALESCE(avatar_url, '')
 	`
 
-	_, err := r.pool.Exec(ctx, query, req.FirstName, req.LastName, req.BirthDate, req.Gender, req.AvatarUrl)
+	// Аватар меняется только загрузкой файла (UpdateAvatar): по ссылке из
+	// профиля при следующей загрузке удаляются файлы прежнего аватара.
+	err := r.pool.QueryRow(ctx, query, req.ID, req.FirstName, req.LastName, req.BirthDate, req.Gender).Scan(&req.AvatarUrl)
 	if err != nil {
 		return nil, fmt.Errorf("не удалось сохрнить пользователя")
 	}
 
 	return req, nil
 }
+
+// UpdateAvatar сохраняет ссылку на аватар и возвращает предыдущую.
+func (r *repository) UpdateAvatar(ctx context.Context, id uuid.UUID, avatarUrl string) (string, error) {
+	query := `
+		UPDATE users u
+		SET avatar_url = $2
+		FROM (SELECT id, avatar_url FROM users WHERE id = $1 FOR UPDATE) prev
+		WHERE u.id = prev.id
+		RETURNING COALESCE(prev.avatar_url, '')
+	`
+
+	var previous string
+	if err := r.pool.QueryRow(ctx, query, id, avatarUrl).Scan(&previous); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("не удалось сохранить аватар")
+	}
+
+	return previous, nil
+}
+
+func (r *repository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`
+
+	var exists bool
+	if err := r.pool.QueryRow(ctx, query, id).Scan(&exists); err != nil {
+		return false, fmt.Errorf("не удалось получить пользователя")
+	}
+
+	return exists, nil
+}
+
+func (r *repository) Block(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	query := `
+		INSERT INTO user_blocks (blocker_id, blocked_id)
+		VALUES ($1, $2)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING`
+
+	if _, err := r.pool.Exec(ctx, query, blockerID, blockedID); err != nil {
+		return fmt.Errorf("не удалось заблокировать пользователя")
+	}
+
+	return nil
+}
+
+func (r *repository) Unblock(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	query := `
+		DELETE FROM user_blocks
+		WHERE blocker_id = $1 AND blocked_id = $2`
+
+	if _, err := r.pool.Exec(ctx, query, blockerID, blockedID); err != nil {
+		return fmt.Errorf("не удалось разблокировать пользователя")
+	}
+
+	return nil
+}
+
+func (r *repository) GetBlocked(ctx context.Context, blockerID uuid.UUID) ([]User, error) {
+	query := `
+		SELECT u.id, u.first_name, u.last_name, u.birth_date, u.gender, u.avatar_url
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC`
+
+	rows, err := r.pool.Query(ctx, query, blockerID)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить заблокированных пользователей")
+	}
+	defer rows.Close()
+
+	users := make([]User, 0)
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&user.ID,
+			&user.FirstName,
+			&user.LastName,
+			&user.BirthDate,
+			&user.Gender,
+			&user.AvatarUrl,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось получить пользователя")
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось получить заблокированных пользователей")
+	}
+
+	return users, nil
+}
+
+// HasBlock проверяет, заблокировал ли кто-то из двух пользователей другого.
+func (r *repository) HasBlock(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2)
+				OR (blocker_id = $2 AND blocked_id = $1)
+		)`
+
+	var blocked bool
+	if err := r.pool.QueryRow(ctx, query, userA, userB).Scan(&blocked); err != nil {
+		return false, fmt.Errorf("не удалось проверить блокировку")
+	}
+
+	return blocked, nil
+}
+
+func (r *repository) GetOpenDirectMessages(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		SELECT open_direct_messages
+		FROM users WHERE id = $1`
+
+	var open bool
+	if err := r.pool.QueryRow(ctx, query, id).Scan(&open); err != nil {
+		return false, fmt.Errorf("не удалось получить настройки личных сообщений")
+	}
+
+	return open, nil
+}
+
+func (r *repository) SetOpenDirectMessages(ctx context.Context, id uuid.UUID, open bool) error {
+	query := `
+		UPDATE users
+		SET open_direct_messages = $2
+		WHERE id = $1`
+
+	if _, err := r.pool.Exec(ctx, query, id, open); err != nil {
+		return fmt.Errorf("не удалось сохранить настройки личных сообщений")
+	}
+
+	return nil
+}
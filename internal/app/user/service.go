@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
@@ -11,9 +12,23 @@ import (
 type Service interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*GetUserResponse, error)
 	GetByIDs(ctx context.Context, ids []uuid.UUID) (map[string]GetUserResponse, error)
+	GetEmailByID(ctx context.Context, id uuid.UUID) (string, error)
+	GetIDByEmail(ctx context.Context, email string) (uuid.UUID, error)
 	UpdateUser(ctx context.Context, id uuid.UUID, req *SaveUserRequest) (*GetUserResponse, error)
+	UpdateAvatar(ctx context.Context, id uuid.UUID, avatarUrl string) (string, error)
+	BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]GetUserResponse, error)
+	HasBlock(ctx context.Context, userA, userB uuid.UUID) (bool, error)
+	GetDirectMessageSettings(ctx context.Context, id uuid.UUID) (*DirectMessageSettings, error)
+	UpdateDirectMessageSettings(ctx context.Context, id uuid.UUID, req *DirectMessageSettings) (*DirectMessageSettings, error)
 }
 
+var (
+	ErrUserNotFound    = errors.New("пользователь не найден")
+	ErrCannotBlockSelf = errors.New("нельзя заблокировать самого себя")
+)
+
 type service struct {
 	log  *slog.Logger
 	repo Repository
@@ -36,7 +51,45 @@ func (s *service) GetByID(ctx context.Context, id uuid.UUID) (*GetUserResponse,
 }
 
 func (s *service) GetByIDs(ctx context.Context, ids []uuid.UUID) (map[string]GetUserResponse, error) {
-	return nil, nil
+	result := make(map[string]GetUserResponse, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	users, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		s.log.Error("failed to get users by ids", "count", len(ids), "error", err)
+		return nil, err
+	}
+
+	for _, user := range users {
+		dto := UserToGetResponse(&user)
+		result[dto.ID] = *dto
+	}
+
+	return result, nil
+}
+
+func (s *service) GetEmailByID(ctx context.Context, id uuid.UUID) (string, error) {
+	email, err := s.repo.GetEmailByID(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get user email by id", "id", id, "error", err)
+		return "", err
+	}
+
+	return email, nil
+}
+
+func (s *service) GetIDByEmail(ctx context.Context, email string) (uuid.UUID, error) {
+	id, err := s.repo.GetIDByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			s.log.Error("failed to get user id by email", "error", err)
+		}
+		return uuid.Nil, err
+	}
+
+	return id, nil
 }
 
 func (s *service) UpdateUser(ctx context.Context, id uuid.UUID, req *SaveUserRequest) (*GetUserResponse, error) {
@@ -56,3 +109,88 @@ func (s *service) UpdateUser(ctx context.Context, id uuid.UUID, req *SaveUserReq
 
 	return result, nil
 }
+
+func (s *service) UpdateAvatar(ctx context.Context, id uuid.UUID, avatarUrl string) (string, error) {
+	previous, err := s.repo.UpdateAvatar(ctx, id, avatarUrl)
+	if err != nil {
+		s.log.Error("failed to update user avatar", "id", id, "error", err)
+		return "", err
+	}
+
+	return previous, nil
+}
+
+func (s *service) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	if blockerID == blockedID {
+		return ErrCannotBlockSelf
+	}
+
+	exists, err := s.repo.Exists(ctx, blockedID)
+	if err != nil {
+		s.log.Error("failed to check user existence", "id", blockedID, "error", err)
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	if err := s.repo.Block(ctx, blockerID, blockedID); err != nil {
+		s.log.Error("failed to block user", "blocker_id", blockerID, "blocked_id", blockedID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	if err := s.repo.Unblock(ctx, blockerID, blockedID); err != nil {
+		s.log.Error("failed to unblock user", "blocker_id", blockerID, "blocked_id", blockedID, "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]GetUserResponse, error) {
+	users, err := s.repo.GetBlocked(ctx, blockerID)
+	if err != nil {
+		s.log.Error("failed to get blocked users", "blocker_id", blockerID, "error", err)
+		return nil, err
+	}
+
+	result := make([]GetUserResponse, 0, len(users))
+	for _, user := range users {
+		result = append(result, *UserToGetResponse(&user))
+	}
+
+	return result, nil
+}
+
+func (s *service) HasBlock(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	blocked, err := s.repo.HasBlock(ctx, userA, userB)
+	if err != nil {
+		s.log.Error("failed to check user block", "user_a", userA, "user_b", userB, "error", err)
+		return false, err
+	}
+
+	return blocked, nil
+}
+
+func (s *service) GetDirectMessageSettings(ctx context.Context, id uuid.UUID) (*DirectMessageSettings, error) {
+	open, err := s.repo.GetOpenDirectMessages(ctx, id)
+	if err != nil {
+		s.log.Error("failed to get direct message settings", "id", id, "error", err)
+		return nil, err
+	}
+
+	return &DirectMessageSettings{Open: open}, nil
+}
+
+func (s *service) UpdateDirectMessageSettings(ctx context.Context, id uuid.UUID, req *DirectMessageSettings) (*DirectMessageSettings, error) {
+	if err := s.repo.SetOpenDirectMessages(ctx, id, req.Open); err != nil {
+		s.log.Error("failed to update direct message settings", "id", id, "error", err)
+		return nil, err
+	}
+
+	return &DirectMessageSettings{Open: req.Open}, nil
+}
@@ -0,0 +1,44 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("объект не найден")
+	ErrInvalidKey = errors.New("неверный ключ объекта")
+)
+
+// BlobStore хранит двоичные объекты по ключу вида "avatars/<id>/<name>.jpg".
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner реализуют хранилища, которые умеют выдавать временную ссылку
+// на объект, чтобы клиент скачивал его напрямую, минуя API.
+type Presigner interface {
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Object — содержимое объекта. Body нужно закрыть после чтения.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// ValidateKey проверяет, что ключ относительный и не выходит за пределы хранилища.
+func ValidateKey(key string) error {
+	if key == "" || !fs.ValidPath(key) || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// FileSystem хранит объекты в каталоге root. Тип содержимого определяется
+// по расширению ключа, поэтому ключи должны его содержать.
+type FileSystem struct {
+	root string
+}
+
+func NewFileSystem(root string) (*FileSystem, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob root %s: %w", root, err)
+	}
+
+	return &FileSystem{root: root}, nil
+}
+
+func (f *FileSystem) Put(ctx context.Context, key, contentType string, data []byte) error {
+	filename, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	// Файл пишется во временный и переименовывается, чтобы читатели
+	// никогда не видели частично записанный объект.
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return fmt.Errorf("failed to store %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (f *FileSystem) Get(ctx context.Context, key string) (*Object, error) {
+	filename, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat %s: %w", key, err)
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return &Object{
+		Body:        file,
+		ContentType: contentType,
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (f *FileSystem) Delete(ctx context.Context, key string) error {
+	filename, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (f *FileSystem) path(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(f.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,144 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSystemRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "media")
+
+	store, err := NewFileSystem(root)
+	if err != nil {
+		t.Fatalf("NewFileSystem: %v", err)
+	}
+
+	key := "avatars/42/upload/large.jpg"
+	if err := store.Put(ctx, key, "image/jpeg", []byte("first")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := store.Put(ctx, key, "image/jpeg", []byte("second")); err != nil {
+		t.Fatalf("Put overwrite: %v", err)
+	}
+
+	object, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, err := io.ReadAll(object.Body)
+	object.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" || object.Size != int64(len("second")) {
+		t.Errorf("Get = %q (size %d), want %q", data, object.Size, "second")
+	}
+	if object.ContentType != "image/jpeg" {
+		t.Errorf("ContentType = %q, want image/jpeg", object.ContentType)
+	}
+	if object.ModTime.IsZero() {
+		t.Error("ModTime is zero")
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, "avatars", "42", "upload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the object (no temporary files)", len(entries))
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete missing object = %v, want nil", err)
+	}
+}
+
+func TestFileSystemContentType(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewFileSystem(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"covers/1/large.jpg", "image/jpeg"},
+		{"covers/1/large.png", "image/png"},
+		{"covers/1/raw", "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		if err := store.Put(ctx, tt.key, "ignored/type", []byte("data")); err != nil {
+			t.Fatalf("Put(%s): %v", tt.key, err)
+		}
+
+		object, err := store.Get(ctx, tt.key)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", tt.key, err)
+		}
+		object.Body.Close()
+
+		if object.ContentType != tt.want {
+			t.Errorf("Get(%s).ContentType = %q, want %q", tt.key, object.ContentType, tt.want)
+		}
+	}
+}
+
+func TestFileSystemRejectsTraversal(t *testing.T) {
+	ctx := context.Background()
+	parent := t.TempDir()
+	root := filepath.Join(parent, "media")
+
+	store, err := NewFileSystem(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outside := filepath.Join(parent, "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{
+		"",
+		"../secret.txt",
+		"avatars/../../secret.txt",
+		"/etc/passwd",
+		"avatars/./large.jpg",
+		"avatars//large.jpg",
+		"avatars/",
+		`avatars\..\..\secret.txt`,
+		"..",
+	}
+
+	for _, key := range keys {
+		if err := store.Put(ctx, key, "text/plain", []byte("overwritten")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+
+	data, err := os.ReadFile(outside)
+	if err != nil || string(data) != "secret" {
+		t.Errorf("file outside root = %q (%v), want it untouched", data, err)
+	}
+}
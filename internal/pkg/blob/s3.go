@@ -0,0 +1,348 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3Service        = "s3"
+	s3TimeFormat     = "20060102T150405Z"
+	s3DateFormat     = "20060102"
+	s3UnsignedBody   = "UNSIGNED-PAYLOAD"
+	s3MaxPresignTTL  = 7 * 24 * time.Hour
+	s3ErrorBodyLimit = 1024
+)
+
+type S3Config struct {
+	// Endpoint — адрес S3-совместимого сервиса, например http://minio:9000.
+	Endpoint string
+	// PublicEndpoint — адрес, по которому сервис доступен клиентам. Используется
+	// в подписанных ссылках; если не задан, берётся Endpoint.
+	PublicEndpoint string
+	Region         string
+	Bucket         string
+	AccessKey      string
+	SecretKey      string
+	// PathStyle включает адреса вида endpoint/bucket/key (нужно для MinIO).
+	PathStyle bool
+}
+
+// S3 хранит объекты в S3-совместимом хранилище (AWS S3, MinIO). Запросы
+// подписываются Signature Version 4.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	public   *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3(cfg S3Config, client *http.Client) (*S3, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+
+	public := endpoint
+	if cfg.PublicEndpoint != "" {
+		public, err = url.Parse(cfg.PublicEndpoint)
+		if err != nil || public.Host == "" {
+			return nil, fmt.Errorf("invalid s3 public endpoint %q", cfg.PublicEndpoint)
+		}
+	}
+
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &S3{
+		cfg:      cfg,
+		endpoint: endpoint,
+		public:   public,
+		client:   client,
+		now:      time.Now,
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key, contentType string, data []byte) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req, hashHex(data))
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to put %s: %w", key, responseError(resp))
+	}
+
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (*Object, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req, hashHex(nil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("failed to get %s: %w", key, responseError(resp))
+	}
+
+	object := &Object{
+		Body:        resp.Body,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		object.ModTime = modTime
+	}
+
+	return object, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, hashHex(nil))
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	// S3 отвечает 204 и на удаление несуществующего объекта.
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete %s: %w", key, responseError(resp))
+	}
+
+	return nil
+}
+
+// PresignGet возвращает ссылку на объект, действующую ttl (не дольше 7 дней).
+func (s *S3) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > s3MaxPresignTTL {
+		ttl = s3MaxPresignTTL
+	}
+
+	u := s.objectURL(s.public, key)
+	now := s.now().UTC()
+	scope := s.scope(now)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	u.RawQuery = canonicalQuery(query)
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedBody,
+	}, "\n")
+
+	signature := s.signature(now, scope, canonical)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+
+	return u.String(), nil
+}
+
+func (s *S3) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(s.endpoint, key).String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 request: %w", err)
+	}
+
+	return req, nil
+}
+
+// do подписывает запрос заголовком Authorization и выполняет его.
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	now := s.now().UTC()
+	scope := s.scope(now)
+
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           req.Header.Get("X-Amz-Date"),
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.cfg.AccessKey, scope, signedHeaders, s.signature(now, scope, canonical)))
+
+	return s.client.Do(req)
+}
+
+func (s *S3) objectURL(base *url.URL, key string) *url.URL {
+	u := *base
+	u.RawQuery = ""
+
+	escaped := strings.TrimSuffix(base.EscapedPath(), "/")
+	if s.cfg.PathStyle {
+		escaped += "/" + escape(s.cfg.Bucket)
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	escaped += "/" + escapePath(key)
+
+	// Путь задаётся в закодированном виде, чтобы подпись считалась
+	// по той же строке, что уйдёт в запросе.
+	u.RawPath = escaped
+	u.Path, _ = url.PathUnescape(escaped)
+
+	return &u
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format(s3DateFormat) + "/" + s.cfg.Region + "/" + s3Service + "/aws4_request"
+}
+
+func (s *S3) signature(now time.Time, scope, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3TimeFormat),
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), now.Format(s3DateFormat))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery кодирует параметры по правилам SigV4: RFC 3986, сортировка по имени.
+func canonicalQuery(values url.Values) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range values[name] {
+			parts = append(parts, escape(name)+"="+escape(value))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// escapePath кодирует каждый сегмент ключа, сохраняя разделители "/".
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, s3ErrorBodyLimit))
+	return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,134 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
+	"github.com/google/uuid"
+)
+
+// ErrUnsupportedVersion — событие опубликовано по более новой схеме, чем
+// известна подписчику. Такое событие не теряется, а доставляется повторно:
+// обычно это значит, что подписчик ещё не обновлён.
+var ErrUnsupportedVersion = errors.New("bus: unsupported event version")
+
+// Handler обрабатывает событие. Если он вернул ошибку, событие доставляется
+// повторно, кроме ошибок, помеченных rabbitmq.Permanent.
+type Handler = func(ctx context.Context, envelope *events.Envelope) error
+
+type Publisher interface {
+	Publish(ctx context.Context, envelope *events.Envelope) error
+}
+
+type Subscriber interface {
+	// Subscribe читает очередь queue, в которую попадают события с именами,
+	// подходящими под шаблоны patterns ("*" — ровно одно слово, "#" — любое
+	// число слов), и блокирует выполнение до отмены ctx. Подписчики одной
+	// очереди делят события между собой, разных очередей — получают каждый
+	// свою копию.
+	Subscribe(ctx context.Context, queue string, patterns []string, handler Handler) error
+}
+
+type Bus interface {
+	Publisher
+	Subscriber
+}
+
+// NewEnvelope упаковывает событие для отправки.
+func NewEnvelope[T events.Event](event T) (*events.Envelope, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось сериализовать событие %s: %w", event.EventName(), err)
+	}
+
+	return &events.Envelope{
+		ID:         uuid.NewString(),
+		Name:       event.EventName(),
+		Version:    event.EventVersion(),
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}, nil
+}
+
+// Publish публикует типизированное событие.
+func Publish[T events.Event](ctx context.Context, publisher Publisher, event T) error {
+	envelope, err := NewEnvelope(event)
+	if err != nil {
+		return err
+	}
+
+	return publisher.Publish(ctx, envelope)
+}
+
+// Decode распаковывает событие типа T. Событие более старой версии
+// читается как есть: поля, которых в нём нет, остаются нулевыми.
+func Decode[T events.Event](envelope *events.Envelope) (T, error) {
+	var event T
+
+	if envelope.Name != event.EventName() {
+		return event, fmt.Errorf("ожидалось событие %s, получено %s", event.EventName(), envelope.Name)
+	}
+
+	if envelope.Version > event.EventVersion() {
+		return event, fmt.Errorf("%w: %s v%d, поддерживается до v%d",
+			ErrUnsupportedVersion, envelope.Name, envelope.Version, event.EventVersion())
+	}
+
+	if err := json.Unmarshal(envelope.Data, &event); err != nil {
+		return event, fmt.Errorf("не удалось разобрать событие %s: %w", envelope.Name, err)
+	}
+
+	return event, nil
+}
+
+// Subscribe подписывает handler на события типа T через очередь queue и
+// блокирует выполнение до отмены ctx. T должен быть типом-значением.
+// Событие, которое не удалось разобрать, не доставляется повторно.
+func Subscribe[T events.Event](ctx context.Context, subscriber Subscriber, queue string, handler func(ctx context.Context, event T) error) error {
+	var zero T
+
+	return subscriber.Subscribe(ctx, queue, []string{zero.EventName()}, func(ctx context.Context, envelope *events.Envelope) error {
+		event, err := Decode[T](envelope)
+		if err != nil {
+			if errors.Is(err, ErrUnsupportedVersion) {
+				return err
+			}
+			return rabbitmq.Permanent(err)
+		}
+
+		return handler(ctx, event)
+	})
+}
+
+// Matches сообщает, подходит ли имя события под шаблон по правилам topic
+// exchange: слова разделяются точкой, "*" заменяет ровно одно слово, "#" —
+// любое число слов, в том числе ни одного.
+func Matches(pattern, name string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(name, "."))
+}
+
+func matchWords(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(name); i++ {
+			if matchWords(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(name) > 0 && matchWords(pattern[1:], name[1:])
+	default:
+		return len(name) > 0 && pattern[0] == name[0] && matchWords(pattern[1:], name[1:])
+	}
+}
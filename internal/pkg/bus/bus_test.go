@@ -0,0 +1,232 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/rabbitmq"
+	"github.com/google/uuid"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"participant.joined", "participant.joined", true},
+		{"participant.joined", "participant.left", false},
+		{"participant.*", "participant.joined", true},
+		{"*.joined", "participant.joined", true},
+		{"*", "participant.joined", false},
+		{"participant.*", "participant", false},
+		{"participant.*", "participant.joined.late", false},
+		{"#", "participant.joined", true},
+		{"#", "", true},
+		{"participant.#", "participant", true},
+		{"participant.#", "participant.joined.late", true},
+		{"#.joined", "participant.joined", true},
+		{"#.joined", "joined", true},
+		{"#.joined", "participant.left", false},
+		{"event.#.cancelled", "event.cancelled", true},
+		{"event.#.cancelled", "event.occurrence.cancelled", true},
+		{"event.*.cancelled", "event.cancelled", false},
+	}
+
+	for _, tt := range tests {
+		if got := Matches(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	joined := events.ParticipantJoined{EventID: uuid.New(), UserID: uuid.New(), Reason: "joined"}
+
+	envelope, err := NewEnvelope(joined)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("same version", func(t *testing.T) {
+		got, err := Decode[events.ParticipantJoined](envelope)
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if got != joined {
+			t.Errorf("Decode = %+v, want %+v", got, joined)
+		}
+	})
+
+	t.Run("newer version", func(t *testing.T) {
+		newer := *envelope
+		newer.Version = joined.EventVersion() + 1
+
+		if _, err := Decode[events.ParticipantJoined](&newer); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Fatalf("Decode = %v, want ErrUnsupportedVersion", err)
+		}
+	})
+
+	t.Run("older version keeps missing fields zero", func(t *testing.T) {
+		older := *envelope
+		older.Version = 0
+		older.Data = json.RawMessage(`{"event_id":"` + joined.EventID.String() + `"}`)
+
+		got, err := Decode[events.ParticipantJoined](&older)
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if got.EventID != joined.EventID || got.UserID != uuid.Nil || got.Reason != "" {
+			t.Errorf("Decode = %+v", got)
+		}
+	})
+
+	t.Run("other event", func(t *testing.T) {
+		if _, err := Decode[events.EventCreated](envelope); err == nil || errors.Is(err, ErrUnsupportedVersion) {
+			t.Fatalf("Decode = %v, want name mismatch error", err)
+		}
+	})
+}
+
+// subscribe запускает подписку в отдельной горутине и ждёт, пока она
+// зарегистрируется в шине.
+func subscribe(t *testing.T, ctx context.Context, m *Memory, queue string, run func(ctx context.Context) error) {
+	t.Helper()
+
+	m.mu.Lock()
+	before := 0
+	if q, ok := m.queues[queue]; ok {
+		before = len(q.subscriptions)
+	}
+	m.mu.Unlock()
+
+	go func() {
+		if err := run(ctx); err != nil {
+			t.Errorf("Subscribe(%s): %v", queue, err)
+		}
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		m.mu.Lock()
+		q, ok := m.queues[queue]
+		ready := ok && len(q.subscriptions) > before
+		m.mu.Unlock()
+
+		if ready {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("subscription to %s not registered", queue)
+}
+
+func TestMemoryRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewMemory()
+
+	var notifications, analytics []events.ParticipantJoined
+	var created []events.EventCreated
+
+	subscribe(t, ctx, m, "notifications", func(ctx context.Context) error {
+		return Subscribe(ctx, m, "notifications", func(_ context.Context, event events.ParticipantJoined) error {
+			notifications = append(notifications, event)
+			return nil
+		})
+	})
+	subscribe(t, ctx, m, "analytics", func(ctx context.Context) error {
+		return Subscribe(ctx, m, "analytics", func(_ context.Context, event events.ParticipantJoined) error {
+			analytics = append(analytics, event)
+			return nil
+		})
+	})
+	subscribe(t, ctx, m, "feed", func(ctx context.Context) error {
+		return Subscribe(ctx, m, "feed", func(_ context.Context, event events.EventCreated) error {
+			created = append(created, event)
+			return nil
+		})
+	})
+
+	joined := events.ParticipantJoined{EventID: uuid.New(), UserID: uuid.New(), Reason: "promoted"}
+	if err := Publish(ctx, m, joined); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	if len(notifications) != 1 || notifications[0] != joined {
+		t.Errorf("notifications = %+v, want [%+v]", notifications, joined)
+	}
+	if len(analytics) != 1 || analytics[0] != joined {
+		t.Errorf("analytics = %+v, want [%+v]", analytics, joined)
+	}
+	if len(created) != 0 {
+		t.Errorf("created = %+v, want none", created)
+	}
+
+	published := m.Published()
+	if len(published) != 1 || published[0].Name != events.ParticipantJoinedName || published[0].Version != joined.EventVersion() {
+		t.Errorf("published = %+v", published)
+	}
+}
+
+func TestMemorySharedQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewMemory()
+	counts := make([]int, 2)
+
+	for i := range counts {
+		subscribe(t, ctx, m, "workers", func(ctx context.Context) error {
+			return m.Subscribe(ctx, "workers", []string{"participant.*"}, func(context.Context, *events.Envelope) error {
+				counts[i]++
+				return nil
+			})
+		})
+	}
+
+	for range 4 {
+		if err := Publish(ctx, m, events.ParticipantJoined{EventID: uuid.New(), UserID: uuid.New()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if counts[0] != 2 || counts[1] != 2 {
+		t.Errorf("deliveries = %v, want [2 2]", counts)
+	}
+}
+
+func TestSubscribeErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewMemory()
+	subscribe(t, ctx, m, "notifications", func(ctx context.Context) error {
+		return Subscribe(ctx, m, "notifications", func(context.Context, events.ParticipantJoined) error {
+			return nil
+		})
+	})
+
+	envelope, err := NewEnvelope(events.ParticipantJoined{EventID: uuid.New(), UserID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newer := *envelope
+	newer.Version++
+	err = m.Publish(ctx, &newer)
+	if !errors.Is(err, ErrUnsupportedVersion) || rabbitmq.IsPermanent(err) {
+		t.Errorf("newer version: Publish = %v, want retryable ErrUnsupportedVersion", err)
+	}
+
+	broken := *envelope
+	broken.Data = json.RawMessage(`{"event_id":1}`)
+	if err := m.Publish(ctx, &broken); !rabbitmq.IsPermanent(err) {
+		t.Errorf("broken payload: Publish = %v, want permanent error", err)
+	}
+}
@@ -0,0 +1,107 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+)
+
+type memorySubscription struct {
+	patterns []string
+	handler  Handler
+}
+
+type memoryQueue struct {
+	subscriptions []*memorySubscription
+	next          int
+}
+
+// Memory — шина в пределах одного процесса для тестов. Publish доставляет
+// событие синхронно и возвращает ошибки обработчиков, повторной доставки нет.
+// Как и у брокера, подписчики одной очереди получают события по очереди.
+type Memory struct {
+	mu        sync.Mutex
+	queues    map[string]*memoryQueue
+	published []*events.Envelope
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		queues: make(map[string]*memoryQueue),
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, envelope *events.Envelope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.published = append(m.published, envelope)
+
+	var handlers []Handler
+	for _, queue := range m.queues {
+		if sub := queue.take(envelope.Name); sub != nil {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	m.mu.Unlock()
+
+	// Обработчики вызываются без блокировки, чтобы они могли сами публиковать события.
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, envelope); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Memory) Subscribe(ctx context.Context, queue string, patterns []string, handler Handler) error {
+	sub := &memorySubscription{patterns: patterns, handler: handler}
+
+	m.mu.Lock()
+	q, ok := m.queues[queue]
+	if !ok {
+		q = &memoryQueue{}
+		m.queues[queue] = q
+	}
+	q.subscriptions = append(q.subscriptions, sub)
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	q.subscriptions = slices.DeleteFunc(q.subscriptions, func(s *memorySubscription) bool { return s == sub })
+	if len(q.subscriptions) == 0 {
+		delete(m.queues, queue)
+	}
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Published возвращает все опубликованные события в порядке публикации.
+func (m *Memory) Published() []*events.Envelope {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.published)
+}
+
+// take выбирает следующего по кругу подписчика очереди, которому подходит событие.
+func (q *memoryQueue) take(name string) *memorySubscription {
+	for range q.subscriptions {
+		sub := q.subscriptions[q.next%len(q.subscriptions)]
+		q.next++
+
+		if slices.ContainsFunc(sub.patterns, func(pattern string) bool { return Matches(pattern, name) }) {
+			return sub
+		}
+	}
+	return nil
+}
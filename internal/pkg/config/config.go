@@ -10,15 +10,20 @@ import (
 )
 
 type Config struct {
-	Env                string         `yaml:"env"`
-	PostgresConnString string         `yaml:"postgres_conn_string"`
-	HTTPServer         HTTPServer     `yaml:"http_server"`
-	Log                Log            `yaml:"log"`
-	JWT                JWT            `yaml:"jwt"`
-	GoogleOAuth        GoogleOAuth    `yaml:"google_oauth"`
-	SMTP               SMTP           `yaml:"smtp"`
-	Redis              RedisConfig    `yaml:"redis"`
-	RabbitMQ           RabbitMQConfig `yaml:"rabbitmq"`
+	Env                string              `yaml:"env"`
+	PostgresConnString string              `yaml:"postgres_conn_string"`
+	HTTPServer         HTTPServer          `yaml:"http_server"`
+	Log                Log                 `yaml:"log"`
+	JWT                JWT                 `yaml:"jwt"`
+	GoogleOAuth        GoogleOAuth         `yaml:"google_oauth"`
+	SMTP               SMTP                `yaml:"smtp"`
+	Mail               MailConfig          `yaml:"mail"`
+	Redis              RedisConfig         `yaml:"redis"`
+	RabbitMQ           RabbitMQConfig      `yaml:"rabbitmq"`
+	Chat               ChatConfig          `yaml:"chat"`
+	Media              MediaConfig         `yaml:"media"`
+	Notifications      NotificationsConfig `yaml:"notifications"`
+	Admin              AdminConfig         `yaml:"admin"`
 }
 
 type HTTPServer struct {
@@ -53,6 +58,26 @@ type SMTP struct {
 	FromAddress string `yaml:"from_address"`
 }
 
+// MailConfig — обработка очереди писем.
+type MailConfig struct {
+	// Standalone — письма отправляет отдельный процесс cmd/mailer, а API
+	// только ставит их в очередь. По умолчанию письма отправляет сам API.
+	Standalone bool `yaml:"standalone"`
+	// HealthAddress — адрес эндпоинтов /health и /metrics процесса cmd/mailer.
+	HealthAddress string `yaml:"health_address"`
+	// Workers — сколько писем отправляется одновременно.
+	Workers int `yaml:"workers"`
+	// Prefetch — сколько писем брокер выдаёт без подтверждения, не меньше Workers.
+	Prefetch int `yaml:"prefetch"`
+	// DrainTimeout — сколько при остановке ждать писем, которые уже
+	// отправляются. Остальные возвращаются в очередь.
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+	// DomainRateLimit — сколько писем можно отправить на один домен получателя
+	// за DomainRateInterval. При 0 ограничения нет.
+	DomainRateLimit    int           `yaml:"domain_rate_limit"`
+	DomainRateInterval time.Duration `yaml:"domain_rate_interval"`
+}
+
 type RedisConfig struct {
 	Address  string `yaml:"address"`
 	Password string `yaml:"password"`
@@ -60,8 +85,83 @@ type RedisConfig struct {
 }
 
 type RabbitMQConfig struct {
-	URL       string `yaml:"url"`
+	URL string `yaml:"url"`
+	// QueueName — очередь писем, по умолчанию "email_events".
 	QueueName string `yaml:"queue_name"`
+	// Exchange — topic exchange доменных событий, по умолчанию "meetly.events".
+	Exchange string      `yaml:"exchange"`
+	Retry    RetryConfig `yaml:"retry"`
+	// ReconnectDelay — пауза перед переподключением после обрыва. Она
+	// удваивается с каждой неудачной попыткой до MaxReconnectDelay.
+	ReconnectDelay    time.Duration `yaml:"reconnect_delay"`
+	MaxReconnectDelay time.Duration `yaml:"max_reconnect_delay"`
+	// PublishBufferSize — сколько сообщений держать в памяти, пока брокер
+	// недоступен. При 0 публикация без соединения сразу возвращает ошибку.
+	PublishBufferSize int `yaml:"publish_buffer_size"`
+}
+
+// RetryConfig — повторная доставка писем, которые не удалось отправить.
+// Задержка удваивается с каждой попыткой от InitialDelay до MaxDelay.
+type RetryConfig struct {
+	MaxRetries   int           `yaml:"max_retries"`
+	InitialDelay time.Duration `yaml:"initial_delay"`
+	MaxDelay     time.Duration `yaml:"max_delay"`
+}
+
+type AdminConfig struct {
+	// UserIDs — пользователи с доступом к служебным эндпоинтам /v1/admin.
+	UserIDs []string `yaml:"user_ids"`
+}
+
+type ChatConfig struct {
+	// PubSub — через что рассылаются сообщения чата: "redis" (по умолчанию)
+	// для нескольких экземпляров или "memory" для одного.
+	PubSub string `yaml:"pubsub"`
+}
+
+type MediaConfig struct {
+	// Storage — где хранятся файлы: "fs" (по умолчанию) или "s3".
+	Storage string `yaml:"storage"`
+	// PublicURL — внешний адрес API для ссылок на файлы. Если не задан,
+	// ссылки возвращаются относительными.
+	PublicURL string `yaml:"public_url"`
+	// MaxUploadSize — наибольший размер загружаемого файла в байтах.
+	MaxUploadSize int64 `yaml:"max_upload_size"`
+	// PresignTTL — срок действия прямых ссылок на объекты в S3.
+	PresignTTL time.Duration `yaml:"presign_ttl"`
+	FSRoot     string        `yaml:"fs_root"`
+	S3         S3Config      `yaml:"s3"`
+}
+
+type NotificationsConfig struct {
+	// UnsubscribeURL — внешний адрес обработчика отписки для заголовка
+	// List-Unsubscribe. Если не задан, письма отправляются без него.
+	UnsubscribeURL string     `yaml:"unsubscribe_url"`
+	Push           PushConfig `yaml:"push"`
+}
+
+type PushConfig struct {
+	// VAPIDPublicKey и VAPIDPrivateKey — ключи VAPID в base64url, их выдаёт
+	// cmd/vapid-keys. Если закрытый ключ не задан, push-уведомления отключены.
+	VAPIDPublicKey  string `yaml:"vapid_public_key"`
+	VAPIDPrivateKey string `yaml:"vapid_private_key"`
+	// Subject — контакт для сервисов доставки: адрес mailto: или https:.
+	Subject string `yaml:"subject"`
+	// TTL — сколько сервис доставки хранит уведомление для недоступного браузера.
+	TTL time.Duration `yaml:"ttl"`
+	// AllowInsecureEndpoints разрешает подписки с адресом http://, например
+	// на локальный тестовый сервис доставки.
+	AllowInsecureEndpoints bool `yaml:"allow_insecure_endpoints"`
+}
+
+type S3Config struct {
+	Endpoint       string `yaml:"endpoint"`
+	PublicEndpoint string `yaml:"public_endpoint"`
+	Region         string `yaml:"region"`
+	Bucket         string `yaml:"bucket"`
+	AccessKey      string `yaml:"access_key"`
+	SecretKey      string `yaml:"secret_key"`
+	PathStyle      bool   `yaml:"path_style"`
 }
 
 func MustLoad() *Config {
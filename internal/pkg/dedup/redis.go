@@ -0,0 +1,37 @@
+package dedup
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis хранит ключи обработанных сообщений в Redis. Ключ живёт ttl: это
+// окно, в котором повторная доставка распознаётся как дубликат.
+type Redis struct {
+	redis  *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+func NewRedis(redis *redis.Client, prefix string, ttl time.Duration) *Redis {
+	return &Redis{
+		redis:  redis,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (d *Redis) Seen(ctx context.Context, key string) (bool, error) {
+	count, err := d.redis.Exists(ctx, d.prefix+key).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (d *Redis) Remember(ctx context.Context, key string) error {
+	return d.redis.Set(ctx, d.prefix+key, 1, d.ttl).Err()
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Имена доменных событий. Имя служит ключом маршрутизации в topic exchange,
+// поэтому подписчик может выбрать группу событий шаблоном вроде "event.*".
+const (
+	UserRegisteredName    = "user.registered"
+	EventCreatedName      = "event.created"
+	ParticipantJoinedName = "participant.joined"
+	EventCancelledName    = "event.cancelled"
+)
+
+// Event — доменное событие. Версия схемы увеличивается при несовместимом
+// изменении полей; добавление необязательного поля версию не меняет.
+type Event interface {
+	EventName() string
+	EventVersion() int
+}
+
+// Envelope — доменное событие в том виде, в котором оно передаётся через
+// брокер: данные события и сведения, нужные для маршрутизации и проверки
+// версии схемы.
+type Envelope struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// UserRegistered — пользователь зарегистрировался.
+type UserRegistered struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Email    string    `json:"email"`
+	Provider string    `json:"provider"`
+}
+
+func (UserRegistered) EventName() string { return UserRegisteredName }
+func (UserRegistered) EventVersion() int { return 1 }
+
+// EventCreated — организатор создал событие или серию событий.
+type EventCreated struct {
+	EventID    uuid.UUID  `json:"event_id"`
+	CreatorID  uuid.UUID  `json:"creator_id"`
+	CategoryID uuid.UUID  `json:"category_id"`
+	Title      string     `json:"title"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	IsPublic   bool       `json:"is_public"`
+	Recurring  bool       `json:"recurring"`
+}
+
+func (EventCreated) EventName() string { return EventCreatedName }
+func (EventCreated) EventVersion() int { return 1 }
+
+// ParticipantJoined — пользователь стал участником события: записался сам,
+// принял приглашение, получил одобрение заявки или перешёл из листа ожидания.
+type ParticipantJoined struct {
+	EventID uuid.UUID `json:"event_id"`
+	UserID  uuid.UUID `json:"user_id"`
+	Reason  string    `json:"reason"`
+}
+
+func (ParticipantJoined) EventName() string { return ParticipantJoinedName }
+func (ParticipantJoined) EventVersion() int { return 1 }
+
+// EventCancelled — событие или одно повторение серии отменено. Для
+// повторения заполнены SeriesID и OccurrenceStart.
+type EventCancelled struct {
+	EventID         uuid.UUID  `json:"event_id"`
+	CreatorID       uuid.UUID  `json:"creator_id"`
+	SeriesID        *uuid.UUID `json:"series_id,omitempty"`
+	OccurrenceStart *time.Time `json:"occurrence_starts_at,omitempty"`
+	CancelledAt     time.Time  `json:"cancelled_at"`
+}
+
+func (EventCancelled) EventName() string { return EventCancelledName }
+func (EventCancelled) EventVersion() int { return 1 }
@@ -1,8 +1,18 @@
 package events
 
+// EmailEventKind — вид сообщения outbox для EmailEvent.
+const EmailEventKind = "email"
+
 type EmailEvent struct {
-	To       string                 `json:"to"`
-	Template string                 `json:"template"`
-	Data     map[string]interface{} `json:"data"`
-	Subject  string                 `json:"subject,omitempty"`
+	To          string                 `json:"to"`
+	Template    string                 `json:"template"`
+	Data        map[string]interface{} `json:"data"`
+	Subject     string                 `json:"subject,omitempty"`
+	Attachments []Attachment           `json:"attachments,omitempty"`
+}
+
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
 }
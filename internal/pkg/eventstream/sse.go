@@ -0,0 +1,148 @@
+package eventstream
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/darahayes/go-boom"
+	"github.com/google/uuid"
+)
+
+const (
+	// heartbeatInterval — как часто отправляется комментарий, чтобы прокси
+	// не закрывали простаивающее соединение.
+	heartbeatInterval = 25 * time.Second
+	// retryInterval — через сколько браузер переподключается после обрыва.
+	retryInterval = 3 * time.Second
+)
+
+// Serve отдаёт поток события в формате Server-Sent Events. Если клиент
+// передал Last-Event-ID (заголовком или параметром last_event_id), сначала
+// досылаются пропущенные записи из буфера. Блокирует выполнение до отключения
+// клиента или до новой записи, для которой last вернула true: она
+// отправляется последней. Записи из буфера last не проверяет: они описывают
+// прошлое, которое клиент пропустил. last может быть nil.
+func (s *Stream) Serve(w http.ResponseWriter, r *http.Request, topicID uuid.UUID, last func(entry *Entry) bool) {
+	ctx := r.Context()
+
+	rc := http.NewResponseController(w)
+	// Поток живёт дольше WriteTimeout сервера.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		s.log.Warn("failed to clear write deadline for event stream", "error", err)
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	// Новый клиент получает только записи, появившиеся после подключения.
+	// Последний ID читается до подписки, а записи между ними досылаются из
+	// буфера.
+	resume := lastEventID != ""
+	if !resume {
+		var err error
+		lastEventID, err = s.lastID(ctx, topicID)
+		if err != nil {
+			s.log.Error("failed to read event stream position", "topic_id", topicID, "error", err)
+			boom.Internal(w, "Не удалось открыть поток событий")
+			return
+		}
+	}
+
+	// Подписка оформляется до чтения буфера, чтобы не пропустить записи,
+	// опубликованные между ними. Повторы отсекаются по ID.
+	sub := s.subscribe(topicID)
+	defer s.unsubscribe(topicID, sub)
+
+	var backlog []Entry
+	var err error
+	if resume {
+		backlog, err = s.Replay(ctx, topicID, lastEventID)
+	} else {
+		backlog, err = s.readAfter(ctx, topicID, lastEventID)
+	}
+	if err != nil {
+		s.log.Error("failed to replay event stream", "topic_id", topicID, "last_event_id", lastEventID, "error", err)
+		boom.Internal(w, "Не удалось восстановить поток событий")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", retryInterval.Milliseconds())
+
+	sent := lastEventID
+	for _, entry := range backlog {
+		if err := writeEntry(w, &entry); err != nil {
+			return
+		}
+		if entry.Type != TypeReset {
+			sent = entry.ID
+		}
+		// Без Last-Event-ID в буфере только записи, появившиеся уже после
+		// подключения, — для них last проверяется как для новых.
+		if !resume && last != nil && last(&entry) {
+			rc.Flush()
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notice, ok := <-sub.entries:
+			if !ok {
+				return
+			}
+			if compareIDs(notice.ID, sent) <= 0 {
+				continue
+			}
+
+			// Уведомление о записи может обогнать уведомление о более ранней
+			// записи другого экземпляра, поэтому всё после sent дочитывается
+			// из буфера по порядку.
+			entries, err := s.readAfter(ctx, topicID, sent)
+			if err != nil {
+				// Клиент переподключится с Last-Event-ID и получит пропущенное.
+				s.log.Error("failed to read event stream", "topic_id", topicID, "after", sent, "error", err)
+				return
+			}
+			for _, entry := range entries {
+				if err := writeEntry(w, &entry); err != nil {
+					return
+				}
+				sent = entry.ID
+				if last != nil && last(&entry) {
+					rc.Flush()
+					return
+				}
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEntry(w http.ResponseWriter, entry *Entry) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", entry.ID, entry.Type, entry.Data)
+	return err
+}
@@ -0,0 +1,298 @@
+package eventstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/pubsub"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix     = "events:stream:"
+	channelPrefix = "stream:event:"
+)
+
+// resubscribeDelay — пауза перед повторной подпиской после ошибки.
+const resubscribeDelay = 5 * time.Second
+
+// subscriberBuffer — сколько записей может ждать отправки одному клиенту.
+// Клиент, который не успевает их забирать, отключается и переподключается
+// с Last-Event-ID.
+const subscriberBuffer = 64
+
+// TypeReset сообщает клиенту, что часть записей уже вытеснена из буфера
+// и состояние события нужно перечитать через REST API.
+const TypeReset = "reset"
+
+// Entry — запись потока события. ID назначается Redis Stream и растёт
+// монотонно, поэтому клиент может продолжить поток с Last-Event-ID.
+type Entry struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Publisher публикует записи в поток события.
+type Publisher interface {
+	Publish(ctx context.Context, topicID uuid.UUID, typ string, data any) error
+}
+
+// Stream хранит последние записи каждого события в Redis Stream ограниченной
+// длины, а о новых записях сообщает открытым соединениям через pub/sub, поэтому
+// они доходят и до клиентов других экземпляров. Уведомления разных
+// экземпляров могут прийти не в порядке ID, поэтому сами записи соединение
+// дочитывает из Redis Stream (см. Serve).
+type Stream struct {
+	log    *slog.Logger
+	redis  *redis.Client
+	pubsub pubsub.PubSub
+	maxLen int64
+	ttl    time.Duration
+
+	mu     sync.Mutex
+	topics map[uuid.UUID]map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	entries chan *Entry
+	once    sync.Once
+}
+
+func (s *subscriber) close() {
+	s.once.Do(func() { close(s.entries) })
+}
+
+func New(log *slog.Logger, redis *redis.Client, pubsub pubsub.PubSub, maxLen int64, ttl time.Duration) *Stream {
+	return &Stream{
+		log:    log,
+		redis:  redis,
+		pubsub: pubsub,
+		maxLen: maxLen,
+		ttl:    ttl,
+		topics: make(map[uuid.UUID]map[*subscriber]struct{}),
+	}
+}
+
+// Publish сохраняет запись в буфер события и рассылает её подписчикам.
+func (s *Stream) Publish(ctx context.Context, topicID uuid.UUID, typ string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream entry: %w", err)
+	}
+
+	key := keyPrefix + topicID.String()
+	pipe := s.redis.TxPipeline()
+	add := pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]any{"type": typ, "data": string(raw)},
+	})
+	pipe.Expire(ctx, key, s.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", key, err)
+	}
+
+	entry := &Entry{ID: add.Val(), Type: typ, Data: raw}
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream entry: %w", err)
+	}
+
+	if err := s.pubsub.Publish(ctx, channelPrefix+topicID.String(), payload); err != nil {
+		// Запись уже в буфере: локальные клиенты получат её сразу,
+		// остальные — при переподключении с Last-Event-ID.
+		s.log.Error("failed to publish stream entry, delivering locally", "topic_id", topicID, "error", err)
+		s.deliver(topicID, entry)
+	}
+
+	return nil
+}
+
+// Replay возвращает записи после after. Если after уже вытеснена из буфера,
+// первой возвращается запись TypeReset.
+func (s *Stream) Replay(ctx context.Context, topicID uuid.UUID, after string) ([]Entry, error) {
+	key := keyPrefix + topicID.String()
+
+	messages, err := s.redis.XRange(ctx, key, "-", "+").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	result := make([]Entry, 0)
+	if len(messages) == 0 || compareIDs(messages[0].ID, after) > 0 {
+		// after уже вытеснена из буфера: часть записей после неё потеряна.
+		result = append(result, Entry{ID: after, Type: TypeReset, Data: json.RawMessage("{}")})
+	}
+
+	for _, msg := range messages {
+		if compareIDs(msg.ID, after) <= 0 {
+			continue
+		}
+		result = append(result, toEntry(msg))
+	}
+
+	return result, nil
+}
+
+// readAfter возвращает записи буфера с ID больше after в порядке ID.
+func (s *Stream) readAfter(ctx context.Context, topicID uuid.UUID, after string) ([]Entry, error) {
+	key := keyPrefix + topicID.String()
+
+	messages, err := s.redis.XRange(ctx, key, "("+after, "+").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	result := make([]Entry, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, toEntry(msg))
+	}
+
+	return result, nil
+}
+
+// lastID возвращает ID последней записи буфера или "0-0", если буфер пуст.
+func (s *Stream) lastID(ctx context.Context, topicID uuid.UUID) (string, error) {
+	key := keyPrefix + topicID.String()
+
+	messages, err := s.redis.XRevRangeN(ctx, key, "+", "-", 1).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	if len(messages) == 0 {
+		return "0-0", nil
+	}
+
+	return messages[0].ID, nil
+}
+
+func toEntry(msg redis.XMessage) Entry {
+	typ, _ := msg.Values["type"].(string)
+	data, _ := msg.Values["data"].(string)
+	return Entry{ID: msg.ID, Type: typ, Data: json.RawMessage(data)}
+}
+
+// Run получает записи из pub/sub и раздаёт их локальным подписчикам.
+// Если подписаться не удалось, попытка повторяется. Блокирует выполнение до отмены ctx.
+func (s *Stream) Run(ctx context.Context) {
+	s.log.Info("starting event stream subscription")
+
+	for {
+		err := s.pubsub.Subscribe(ctx, channelPrefix, func(msg *pubsub.Message) {
+			topicID, err := uuid.Parse(strings.TrimPrefix(msg.Channel, channelPrefix))
+			if err != nil {
+				s.log.Warn("unexpected stream channel", "channel", msg.Channel)
+				return
+			}
+
+			var entry Entry
+			if err := json.Unmarshal(msg.Payload, &entry); err != nil {
+				s.log.Warn("invalid stream entry", "channel", msg.Channel, "error", err)
+				return
+			}
+
+			s.deliver(topicID, &entry)
+		})
+		if err != nil {
+			s.log.Error("event stream subscription failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.log.Info("event stream stopped")
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+func (s *Stream) subscribe(topicID uuid.UUID) *subscriber {
+	sub := &subscriber{entries: make(chan *Entry, subscriberBuffer)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topic, ok := s.topics[topicID]
+	if !ok {
+		topic = make(map[*subscriber]struct{})
+		s.topics[topicID] = topic
+	}
+	topic[sub] = struct{}{}
+
+	return sub
+}
+
+func (s *Stream) unsubscribe(topicID uuid.UUID, sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topic, ok := s.topics[topicID]
+	if !ok {
+		return
+	}
+
+	delete(topic, sub)
+	if len(topic) == 0 {
+		delete(s.topics, topicID)
+	}
+	sub.close()
+}
+
+func (s *Stream) deliver(topicID uuid.UUID, entry *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topic := s.topics[topicID]
+	for sub := range topic {
+		select {
+		case sub.entries <- entry:
+		default:
+			s.log.Warn("stream subscriber too slow, dropping connection", "topic_id", topicID)
+			delete(topic, sub)
+			sub.close()
+		}
+	}
+	if len(topic) == 0 {
+		delete(s.topics, topicID)
+	}
+}
+
+// compareIDs сравнивает идентификаторы записей Redis Stream вида "ms-seq".
+// Пустой идентификатор меньше любого другого.
+func compareIDs(a, b string) int {
+	aMs, aSeq := splitID(a)
+	bMs, bSeq := splitID(b)
+
+	if aMs != bMs {
+		return cmpUint(aMs, bMs)
+	}
+	return cmpUint(aSeq, bSeq)
+}
+
+func splitID(id string) (uint64, uint64) {
+	var ms, seq uint64
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	fmt.Sscan(msPart, &ms)
+	fmt.Sscan(seqPart, &seq)
+	return ms, seq
+}
+
+func cmpUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
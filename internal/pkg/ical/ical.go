@@ -0,0 +1,313 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MethodPublish = "PUBLISH"
+
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+
+	ContentType = "text/calendar; charset=utf-8"
+)
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+
+	// maxLineLength — ограничение длины строки из RFC 5545 (в октетах, без CRLF).
+	maxLineLength = 75
+
+	// openEndedYears — на сколько лет вперёд описываются переходы часового пояса
+	// для серий без даты окончания.
+	openEndedYears = 5
+)
+
+// Calendar — объект VCALENDAR.
+type Calendar struct {
+	ProdID          string
+	Name            string
+	Method          string
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Event — компонент VEVENT. Время начала и окончания записывается в часовом
+// поясе Location: для UTC — в форме с суффиксом Z, для остальных — с TZID
+// и соответствующим VTIMEZONE.
+type Event struct {
+	UID          string
+	Sequence     int
+	Summary      string
+	Description  string
+	Location     string
+	Latitude     *float64
+	Longitude    *float64
+	URL          string
+	Start        time.Time
+	End          *time.Time
+	TimeZone     *time.Location
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID *time.Time
+	Status       string
+	Created      time.Time
+	LastModified time.Time
+}
+
+// Bytes сериализует календарь в формат iCalendar.
+func (c *Calendar) Bytes() []byte {
+	w := &writer{}
+	now := time.Now().UTC()
+
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + c.ProdID)
+	w.line("CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		w.line("METHOD:" + c.Method)
+	}
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		duration := formatDuration(c.RefreshInterval)
+		w.line("REFRESH-INTERVAL;VALUE=DURATION:" + duration)
+		w.line("X-PUBLISHED-TTL:" + duration)
+	}
+
+	for _, tz := range c.timezones(now) {
+		tz.write(w)
+	}
+
+	for _, event := range c.Events {
+		event.write(w, now)
+	}
+
+	w.line("END:VCALENDAR")
+
+	return []byte(w.String())
+}
+
+func (e *Event) location() *time.Location {
+	if e.TimeZone == nil {
+		return time.UTC
+	}
+	return e.TimeZone
+}
+
+func (e *Event) write(w *writer, now time.Time) {
+	loc := e.location()
+
+	w.line("BEGIN:VEVENT")
+	w.line("UID:" + e.UID)
+	w.line("DTSTAMP:" + now.Format(utcLayout))
+	w.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	w.line(formatDateTime("DTSTART", e.Start, loc))
+	if e.End != nil {
+		w.line(formatDateTime("DTEND", *e.End, loc))
+	}
+	if e.RecurrenceID != nil {
+		w.line(formatDateTime("RECURRENCE-ID", *e.RecurrenceID, loc))
+	}
+	if e.RRule != "" {
+		w.line("RRULE:" + e.RRule)
+	}
+	for _, exdate := range e.ExDates {
+		w.line(formatDateTime("EXDATE", exdate, loc))
+	}
+	w.line("SUMMARY:" + escapeText(e.Summary))
+	if e.Description != "" {
+		w.line("DESCRIPTION:" + escapeText(e.Description))
+	}
+	if e.Location != "" {
+		w.line("LOCATION:" + escapeText(e.Location))
+	}
+	if e.Latitude != nil && e.Longitude != nil {
+		w.line(fmt.Sprintf("GEO:%.6f;%.6f", *e.Latitude, *e.Longitude))
+	}
+	if e.URL != "" {
+		w.line("URL:" + e.URL)
+	}
+	if e.Status != "" {
+		w.line("STATUS:" + e.Status)
+	}
+	if !e.Created.IsZero() {
+		w.line("CREATED:" + e.Created.UTC().Format(utcLayout))
+	}
+	if !e.LastModified.IsZero() {
+		w.line("LAST-MODIFIED:" + e.LastModified.UTC().Format(utcLayout))
+	}
+	w.line("END:VEVENT")
+}
+
+// timezones собирает VTIMEZONE для всех поясов, кроме UTC, в диапазоне дат,
+// который покрывают события календаря.
+func (c *Calendar) timezones(now time.Time) []timezone {
+	byName := make(map[string]*timezone)
+
+	for _, event := range c.Events {
+		loc := event.location()
+		if loc == time.UTC {
+			continue
+		}
+
+		from := event.Start
+		to := event.Start
+		if event.End != nil && event.End.After(to) {
+			to = *event.End
+		}
+		if event.RRule != "" {
+			to = maxTime(to, now).AddDate(openEndedYears, 0, 0)
+		}
+
+		tz, ok := byName[loc.String()]
+		if !ok {
+			byName[loc.String()] = &timezone{loc: loc, from: from, to: to}
+			continue
+		}
+		if from.Before(tz.from) {
+			tz.from = from
+		}
+		if to.After(tz.to) {
+			tz.to = to
+		}
+	}
+
+	result := make([]timezone, 0, len(byName))
+	for _, tz := range byName {
+		result = append(result, *tz)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].loc.String() < result[j].loc.String() })
+
+	return result
+}
+
+type timezone struct {
+	loc  *time.Location
+	from time.Time
+	to   time.Time
+}
+
+// write описывает пояс явным списком переходов в интервале [from, to].
+// Первое наблюдение начинается с правила, действующего на момент from.
+func (tz *timezone) write(w *writer) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + tz.loc.String())
+
+	t := tz.from.In(tz.loc)
+	name, offset := t.Zone()
+	start, end := t.ZoneBounds()
+
+	if start.IsZero() {
+		start = time.Date(1970, 1, 1, 0, 0, 0, 0, tz.loc)
+	}
+	writeObservance(w, t.IsDST(), name, start.In(time.FixedZone("", offset)), offset, offset)
+
+	for !end.IsZero() && !end.After(tz.to) {
+		next := end.In(tz.loc)
+		nextName, nextOffset := next.Zone()
+
+		writeObservance(w, next.IsDST(), nextName, end.In(time.FixedZone("", offset)), offset, nextOffset)
+
+		offset = nextOffset
+		_, end = next.ZoneBounds()
+	}
+
+	w.line("END:VTIMEZONE")
+}
+
+func writeObservance(w *writer, isDST bool, name string, start time.Time, offsetFrom, offsetTo int) {
+	kind := "STANDARD"
+	if isDST {
+		kind = "DAYLIGHT"
+	}
+
+	w.line("BEGIN:" + kind)
+	w.line("DTSTART:" + start.Format(localLayout))
+	w.line("TZOFFSETFROM:" + formatOffset(offsetFrom))
+	w.line("TZOFFSETTO:" + formatOffset(offsetTo))
+	if name != "" && !strings.HasPrefix(name, "+") && !strings.HasPrefix(name, "-") {
+		w.line("TZNAME:" + name)
+	}
+	w.line("END:" + kind)
+}
+
+func formatDateTime(name string, t time.Time, loc *time.Location) string {
+	if loc == time.UTC {
+		return name + ":" + t.UTC().Format(utcLayout)
+	}
+	return name + ";TZID=" + loc.String() + ":" + t.In(loc).Format(localLayout)
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+
+	result := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		result += fmt.Sprintf("%02d", seconds%60)
+	}
+	return result
+}
+
+func formatDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("PT%dH", d/time.Hour)
+	}
+	return fmt.Sprintf("PT%dM", d/time.Minute)
+}
+
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+type writer struct {
+	strings.Builder
+}
+
+// line записывает строку контента, перенося её по 75 октетов
+// без разрыва многобайтовых символов UTF-8.
+func (w *writer) line(value string) {
+	limit := maxLineLength
+	for len(value) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(value[cut]) {
+			cut--
+		}
+
+		w.WriteString(value[:cut])
+		w.WriteString("\r\n ")
+		value = value[cut:]
+
+		// Продолжение начинается с пробела, который входит в длину строки.
+		limit = maxLineLength - 1
+	}
+
+	w.WriteString(value)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,298 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestTimezone(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	moscow := mustLocation(t, "Europe/Moscow")
+	fixed := mustLocation(t, "Etc/GMT-3")
+
+	tests := []struct {
+		name string
+		tz   timezone
+		want []string
+	}{
+		{
+			name: "dst zone lists transitions in range",
+			tz: timezone{
+				loc:  berlin,
+				from: time.Date(2025, time.March, 1, 18, 0, 0, 0, berlin),
+				to:   time.Date(2025, time.December, 1, 18, 0, 0, 0, berlin),
+			},
+			want: []string{
+				"BEGIN:VTIMEZONE",
+				"TZID:Europe/Berlin",
+				"BEGIN:STANDARD",
+				"DTSTART:20241027T020000",
+				"TZOFFSETFROM:+0100",
+				"TZOFFSETTO:+0100",
+				"TZNAME:CET",
+				"END:STANDARD",
+				"BEGIN:DAYLIGHT",
+				"DTSTART:20250330T020000",
+				"TZOFFSETFROM:+0100",
+				"TZOFFSETTO:+0200",
+				"TZNAME:CEST",
+				"END:DAYLIGHT",
+				"BEGIN:STANDARD",
+				"DTSTART:20251026T030000",
+				"TZOFFSETFROM:+0200",
+				"TZOFFSETTO:+0100",
+				"TZNAME:CET",
+				"END:STANDARD",
+				"END:VTIMEZONE",
+			},
+		},
+		{
+			name: "dst zone inside one period",
+			tz: timezone{
+				loc:  berlin,
+				from: time.Date(2025, time.June, 1, 18, 0, 0, 0, berlin),
+				to:   time.Date(2025, time.June, 1, 20, 0, 0, 0, berlin),
+			},
+			want: []string{
+				"BEGIN:VTIMEZONE",
+				"TZID:Europe/Berlin",
+				"BEGIN:DAYLIGHT",
+				"DTSTART:20250330T030000",
+				"TZOFFSETFROM:+0200",
+				"TZOFFSETTO:+0200",
+				"TZNAME:CEST",
+				"END:DAYLIGHT",
+				"END:VTIMEZONE",
+			},
+		},
+		{
+			name: "zone without further transitions",
+			tz: timezone{
+				loc:  moscow,
+				from: time.Date(2025, time.March, 1, 19, 0, 0, 0, moscow),
+				to:   time.Date(2030, time.March, 1, 19, 0, 0, 0, moscow),
+			},
+			want: []string{
+				"BEGIN:VTIMEZONE",
+				"TZID:Europe/Moscow",
+				"BEGIN:STANDARD",
+				"DTSTART:20141026T010000",
+				"TZOFFSETFROM:+0300",
+				"TZOFFSETTO:+0300",
+				"TZNAME:MSK",
+				"END:STANDARD",
+				"END:VTIMEZONE",
+			},
+		},
+		{
+			name: "zone without transitions and numeric name",
+			tz: timezone{
+				loc:  fixed,
+				from: time.Date(2025, time.March, 1, 19, 0, 0, 0, fixed),
+				to:   time.Date(2025, time.March, 1, 21, 0, 0, 0, fixed),
+			},
+			want: []string{
+				"BEGIN:VTIMEZONE",
+				"TZID:Etc/GMT-3",
+				"BEGIN:STANDARD",
+				"DTSTART:19700101T000000",
+				"TZOFFSETFROM:+0300",
+				"TZOFFSETTO:+0300",
+				"END:STANDARD",
+				"END:VTIMEZONE",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &writer{}
+			tt.tz.write(w)
+
+			want := strings.Join(tt.want, "\r\n") + "\r\n"
+			if got := w.String(); got != want {
+				t.Errorf("VTIMEZONE =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestFormatOffset(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "+0000"},
+		{3 * 3600, "+0300"},
+		{-(4*3600 + 30*60), "-0430"},
+		{2*3600 + 30*60 + 17, "+023017"},
+	}
+
+	for _, tt := range tests {
+		if got := formatOffset(tt.seconds); got != tt.want {
+			t.Errorf("formatOffset(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "short line",
+			value: "SUMMARY:Пробежка",
+			want:  "SUMMARY:Пробежка\r\n",
+		},
+		{
+			name:  "exactly 75 octets",
+			value: strings.Repeat("a", 75),
+			want:  strings.Repeat("a", 75) + "\r\n",
+		},
+		{
+			name:  "ascii",
+			value: strings.Repeat("a", 80),
+			want:  strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 5) + "\r\n",
+		},
+		{
+			name: "multibyte character is not split",
+			// 74 октета ASCII и двухбайтовая «ж» на границе 75-го октета.
+			value: strings.Repeat("a", 74) + "жж",
+			want:  strings.Repeat("a", 74) + "\r\n жж\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &writer{}
+			w.line(tt.value)
+			if got := w.String(); got != tt.want {
+				t.Errorf("line() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("long multibyte text", func(t *testing.T) {
+		value := "DESCRIPTION:" + strings.Repeat("Встреча у фонтана 🌳 ", 20)
+
+		w := &writer{}
+		w.line(value)
+		got := w.String()
+
+		if !strings.HasSuffix(got, "\r\n") {
+			t.Fatalf("line() = %q, want CRLF at the end", got)
+		}
+		lines := strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n")
+		if len(lines) < 2 {
+			t.Fatalf("line() = %q, want folded line", got)
+		}
+		for i, line := range lines {
+			if len(line) > maxLineLength {
+				t.Errorf("line %d is %d octets long, want at most %d", i, len(line), maxLineLength)
+			}
+			if i > 0 && !strings.HasPrefix(line, " ") {
+				t.Errorf("line %d = %q, want leading space", i, line)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("line %d = %q splits a character", i, line)
+			}
+		}
+
+		if unfolded := strings.ReplaceAll(strings.TrimSuffix(got, "\r\n"), "\r\n ", ""); unfolded != value {
+			t.Errorf("unfolded = %q, want %q", unfolded, value)
+		}
+	})
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Пробежка", "Пробежка"},
+		{"Парк, вход; с юга", `Парк\, вход\; с юга`},
+		{`C:\meetly`, `C:\\meetly`},
+		{`\,`, `\\\,`},
+		{"первая\nвторая", `первая\nвторая`},
+		{"первая\r\nвторая\rтретья", `первая\nвторая\nтретья`},
+		{"URL: https://meetly.app/e/1", "URL: https://meetly.app/e/1"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeText(tt.value); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCalendarBytes(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	start := time.Date(2025, time.June, 6, 19, 0, 0, 0, berlin)
+	end := start.Add(2 * time.Hour)
+
+	calendar := &Calendar{
+		ProdID:          "-//Meetly//Meetly API//RU",
+		Name:            "Встречи, Meetly",
+		Method:          MethodPublish,
+		RefreshInterval: time.Hour,
+		Events: []Event{
+			{
+				UID:      "event-1@meetly",
+				Sequence: 2,
+				Summary:  "Пробежка; парк",
+				Start:    start,
+				End:      &end,
+				TimeZone: berlin,
+				Status:   StatusConfirmed,
+			},
+			{
+				UID:     "event-2@meetly",
+				Summary: "Созвон",
+				Start:   time.Date(2025, time.June, 7, 10, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	got := string(calendar.Bytes())
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"METHOD:PUBLISH\r\n",
+		`X-WR-CALNAME:Встречи\, Meetly` + "\r\n",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n",
+		"TZID:Europe/Berlin\r\n",
+		"DTSTART;TZID=Europe/Berlin:20250606T190000\r\n",
+		"DTEND;TZID=Europe/Berlin:20250606T210000\r\n",
+		"SEQUENCE:2\r\n",
+		`SUMMARY:Пробежка\; парк` + "\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"DTSTART:20250607T100000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar does not contain %q:\n%s", want, got)
+		}
+	}
+
+	if n := strings.Count(got, "BEGIN:VTIMEZONE"); n != 1 {
+		t.Errorf("got %d VTIMEZONE, want 1 (UTC needs none)", n)
+	}
+	if strings.Index(got, "END:VTIMEZONE") > strings.Index(got, "BEGIN:VEVENT") {
+		t.Errorf("VTIMEZONE must precede events:\n%s", got)
+	}
+}
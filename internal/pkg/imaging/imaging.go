@@ -0,0 +1,175 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("поддерживаются только изображения JPEG, PNG и GIF")
+	ErrTooLarge          = errors.New("слишком большое разрешение изображения")
+	ErrTooSmall          = errors.New("слишком маленькое изображение")
+)
+
+// maxPixels ограничивает разрешение, чтобы небольшой файл не развернулся
+// в гигабайты памяти при декодировании.
+const maxPixels = 40_000_000
+
+const jpegQuality = 85
+
+// supportedTypes — форматы, для которых в стандартной библиотеке есть декодер.
+var supportedTypes = map[string]struct{}{
+	"image/jpeg": {},
+	"image/png":  {},
+	"image/gif":  {},
+}
+
+// Size — размер миниатюры. Исходное изображение обрезается по центру
+// до пропорций Width:Height и уменьшается; увеличение не выполняется.
+type Size struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Thumbnail — готовая миниатюра в формате JPEG.
+type Thumbnail struct {
+	Size   Size
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Sniff определяет тип содержимого по первым байтам, не доверяя имени файла
+// и заголовку Content-Type клиента.
+func Sniff(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := supportedTypes[contentType]; !ok {
+		return contentType, ErrUnsupportedFormat
+	}
+	return contentType, nil
+}
+
+// Decode проверяет формат и разрешение изображения и декодирует его.
+func Decode(data []byte, minSide int) (image.Image, error) {
+	if _, err := Sniff(data); err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	if config.Width < minSide || config.Height < minSide {
+		return nil, ErrTooSmall
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	return img, nil
+}
+
+// Thumbnails строит миниатюры всех размеров из одного исходного изображения.
+func Thumbnails(img image.Image, sizes []Size) ([]Thumbnail, error) {
+	src := toRGBA(img)
+
+	result := make([]Thumbnail, 0, len(sizes))
+	for _, size := range sizes {
+		resized := resize(crop(src, size.Width, size.Height), size.Width, size.Height)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s thumbnail: %w", size.Name, err)
+		}
+
+		bounds := resized.Bounds()
+		result = append(result, Thumbnail{
+			Size:   size,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			Data:   buf.Bytes(),
+		})
+	}
+
+	return result, nil
+}
+
+// toRGBA переводит изображение в RGBA на белом фоне: JPEG не поддерживает
+// прозрачность, а прямой доступ к пикселям ускоряет масштабирование.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
+
+// crop вырезает из центра изображения наибольшую область с пропорциями width:height.
+func crop(src *image.RGBA, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	cropW, cropH := w, w*height/width
+	if cropH > h {
+		cropW, cropH = h*width/height, h
+	}
+
+	x := (w - cropW) / 2
+	y := (h - cropH) / 2
+	return src.SubImage(image.Rect(x, y, x+cropW, y+cropH)).(*image.RGBA)
+}
+
+// resize уменьшает изображение усреднением пикселей (box filter). Если
+// исходник меньше требуемого размера, он возвращается как есть.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= width || srcH <= height {
+		width, height = srcW, srcH
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0 := bounds.Min.Y + dy*srcH/height
+		y1 := max(bounds.Min.Y+(dy+1)*srcH/height, y0+1)
+
+		for dx := 0; dx < width; dx++ {
+			x0 := bounds.Min.X + dx*srcW/width
+			x1 := max(bounds.Min.X+(dx+1)*srcW/width, x0+1)
+
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				offset := src.PixOffset(x0, y)
+				for x := x0; x < x1; x++ {
+					r += uint64(src.Pix[offset])
+					g += uint64(src.Pix[offset+1])
+					b += uint64(src.Pix[offset+2])
+					a += uint64(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,216 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func filled(width, height int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// pngHeader возвращает PNG, в котором есть только заголовок IHDR: для
+// проверки разрешения декодировать само изображение не нужно.
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // глубина цвета
+	ihdr[9] = 2 // RGB
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	var gifData bytes.Buffer
+	if err := gif.Encode(&gifData, filled(120, 80, color.White), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, filled(100, 100, color.White), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pngData := encodePNG(t, filled(100, 60, color.White))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		minSide int
+		wantErr error
+	}{
+		{name: "png", data: pngData, minSide: 60},
+		{name: "jpeg", data: jpegData.Bytes(), minSide: 64},
+		{name: "gif", data: gifData.Bytes(), minSide: 64},
+		{name: "too small", data: pngData, minSide: 64, wantErr: ErrTooSmall},
+		{name: "too large", data: pngHeader(10_000, 5_000), minSide: 64, wantErr: ErrTooLarge},
+		{name: "pixel limit is inclusive, body missing", data: pngHeader(8_000, 5_000), minSide: 64, wantErr: ErrUnsupportedFormat},
+		{name: "text", data: []byte("definitely not an image"), wantErr: ErrUnsupportedFormat},
+		{name: "webp", data: []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), wantErr: ErrUnsupportedFormat},
+		{name: "truncated png", data: pngData[:len(pngData)/2], minSide: 60, wantErr: ErrUnsupportedFormat},
+		{name: "empty", data: nil, wantErr: ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Decode(tt.data, tt.minSide)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Decode = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if img.Bounds().Empty() {
+				t.Errorf("Decode returned empty image")
+			}
+		})
+	}
+}
+
+func TestSniff(t *testing.T) {
+	pngData := encodePNG(t, filled(1, 1, color.White))
+
+	if got, err := Sniff(pngData); err != nil || got != "image/png" {
+		t.Errorf("Sniff(png) = %q, %v", got, err)
+	}
+	if got, err := Sniff([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Sniff(svg) = %q, %v, want ErrUnsupportedFormat", got, err)
+	}
+}
+
+func decodeThumbnail(t *testing.T, thumbnail Thumbnail) image.Image {
+	t.Helper()
+
+	img, err := jpeg.Decode(bytes.NewReader(thumbnail.Data))
+	if err != nil {
+		t.Fatalf("%s: thumbnail is not a JPEG: %v", thumbnail.Size.Name, err)
+	}
+	if got := img.Bounds(); got.Dx() != thumbnail.Width || got.Dy() != thumbnail.Height {
+		t.Errorf("%s: JPEG is %dx%d, thumbnail reports %dx%d", thumbnail.Size.Name, got.Dx(), got.Dy(), thumbnail.Width, thumbnail.Height)
+	}
+	return img
+}
+
+// near сравнивает цвета с допуском на сжатие JPEG.
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	diff := func(a uint32, b uint8) int {
+		d := int(a>>8) - int(b)
+		if d < 0 {
+			d = -d
+		}
+		return d
+	}
+	return diff(r, want.R) < 40 && diff(g, want.G) < 40 && diff(b, want.B) < 40
+}
+
+func TestThumbnails(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	green := color.RGBA{G: 255, A: 255}
+
+	// Широкое изображение: красные поля по бокам, зелёный квадрат в центре.
+	src := filled(600, 200, red)
+	for y := 0; y < 200; y++ {
+		for x := 200; x < 400; x++ {
+			src.Set(x, y, green)
+		}
+	}
+
+	sizes := []Size{
+		{Name: "large", Width: 400, Height: 400},
+		{Name: "medium", Width: 100, Height: 100},
+		{Name: "wide", Width: 300, Height: 100},
+	}
+
+	thumbnails, err := Thumbnails(src, sizes)
+	if err != nil {
+		t.Fatalf("Thumbnails: %v", err)
+	}
+	if len(thumbnails) != len(sizes) {
+		t.Fatalf("got %d thumbnails, want %d", len(thumbnails), len(sizes))
+	}
+
+	tests := []struct {
+		width, height int
+		// points — точки миниатюры и ожидаемые цвета.
+		points map[image.Point]color.RGBA
+	}{
+		// Квадрат вырезается из центра, а увеличение не выполняется.
+		{200, 200, map[image.Point]color.RGBA{{5, 5}: green, {100, 100}: green, {194, 194}: green}},
+		{100, 100, map[image.Point]color.RGBA{{3, 3}: green, {50, 50}: green, {96, 96}: green}},
+		// Пропорции 3:1 совпадают с исходником: обрезки нет, только уменьшение.
+		{300, 100, map[image.Point]color.RGBA{{5, 50}: red, {150, 50}: green, {294, 50}: red}},
+	}
+
+	for i, tt := range tests {
+		thumbnail := thumbnails[i]
+		if thumbnail.Size != sizes[i] {
+			t.Errorf("thumbnail %d size = %+v, want %+v", i, thumbnail.Size, sizes[i])
+		}
+		if thumbnail.Width != tt.width || thumbnail.Height != tt.height {
+			t.Errorf("%s = %dx%d, want %dx%d", thumbnail.Size.Name, thumbnail.Width, thumbnail.Height, tt.width, tt.height)
+			continue
+		}
+
+		img := decodeThumbnail(t, thumbnail)
+		for point, want := range tt.points {
+			if got := img.At(point.X, point.Y); !near(got, want) {
+				t.Errorf("%s: pixel %v = %v, want about %v", thumbnail.Size.Name, point, got, want)
+			}
+		}
+	}
+}
+
+func TestThumbnailsTransparentBackground(t *testing.T) {
+	data := encodePNG(t, filled(100, 100, color.NRGBA{}))
+
+	img, err := Decode(data, 64)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	thumbnails, err := Thumbnails(img, []Size{{Name: "small", Width: 64, Height: 64}})
+	if err != nil {
+		t.Fatalf("Thumbnails: %v", err)
+	}
+
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	if got := decodeThumbnail(t, thumbnails[0]).At(32, 32); !near(got, white) {
+		t.Errorf("transparent pixel = %v, want white", got)
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/darahayes/go-boom"
+)
+
+// AdminMiddleware пропускает только пользователей из списка администраторов.
+// Ставится после AuthMiddleware.
+func AdminMiddleware(adminIDs []string) func(http.Handler) http.Handler {
+	admins := make(map[string]struct{}, len(adminIDs))
+	for _, id := range adminIDs {
+		if id != "" {
+			admins[id] = struct{}{}
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value("user_id").(string)
+			if _, ok := admins[userID]; !ok {
+				boom.Forbidden(w, "недостаточно прав")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -13,6 +13,14 @@ func AuthMiddleware(jwtHelper *jwt_helper.JWTHelper) func(http.Handler) http.Han
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
+			// Браузер не может передать заголовок при открытии WebSocket или
+			// EventSource, поэтому для них токен принимается в параметре access_token.
+			if authHeader == "" && (IsWebSocketUpgrade(r) || IsEventStream(r)) {
+				if token := r.URL.Query().Get(accessTokenParam); token != "" {
+					authHeader = "Bearer " + token
+				}
+			}
+
 			if authHeader == "" {
 				boom.Unathorized(w, "Authorization header required")
 				return
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// accessTokenParam — параметр, в котором WebSocket и EventSource передают
+// токен доступа (см. AuthMiddleware).
+const accessTokenParam = "access_token"
+
+// Logger журналирует запросы так же, как chi_middleware.Logger, но заменяет
+// токен доступа в адресе запроса, чтобы он не попал в журнал.
+var Logger = chi_middleware.RequestLogger(&redactingLogFormatter{
+	next: &chi_middleware.DefaultLogFormatter{
+		Logger:  log.New(os.Stdout, "", log.LstdFlags),
+		NoColor: true,
+	},
+})
+
+type redactingLogFormatter struct {
+	next chi_middleware.LogFormatter
+}
+
+func (f *redactingLogFormatter) NewLogEntry(r *http.Request) chi_middleware.LogEntry {
+	return f.next.NewLogEntry(redactAccessToken(r))
+}
+
+// redactAccessToken возвращает копию запроса, в адресе которой значение
+// access_token заменено. Сам запрос не меняется: токен нужен AuthMiddleware.
+func redactAccessToken(r *http.Request) *http.Request {
+	query := r.URL.Query()
+	if !query.Has(accessTokenParam) {
+		return r
+	}
+	query.Set(accessTokenParam, "REDACTED")
+
+	redactedURL := *r.URL
+	redactedURL.RawQuery = query.Encode()
+
+	redacted := *r
+	redacted.URL = &redactedURL
+	redacted.RequestURI = redactedURL.RequestURI()
+
+	return &redacted
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SkipForStreams отключает middleware для долгоживущих соединений (WebSocket,
+// Server-Sent Events), например таймаут запроса, который иначе оборвал бы соединение.
+func SkipForStreams(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if IsWebSocketUpgrade(r) || IsEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// IsEventStream сообщает, что клиент открывает поток Server-Sent Events.
+func IsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
@@ -0,0 +1,59 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DB — транзакция или пул, через которые записывается сообщение.
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// Add записывает сообщение в outbox. Чтобы сообщение ушло тогда и только
+// тогда, когда сохранено изменение данных, db должен быть транзакцией этого
+// изменения.
+func Add(ctx context.Context, db DB, kind string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать сообщение: %w", err)
+	}
+
+	query := `INSERT INTO outbox (kind, payload) VALUES ($1, $2::jsonb)`
+
+	if _, err := db.Exec(ctx, query, kind, string(body)); err != nil {
+		return fmt.Errorf("не удалось записать сообщение в outbox: %w", err)
+	}
+
+	return nil
+}
+
+// AddEmail записывает письмо в outbox.
+func AddEmail(ctx context.Context, db DB, event *events.EmailEvent) error {
+	return Add(ctx, db, events.EmailEventKind, event)
+}
+
+// AddEvent записывает в outbox доменное событие. Ретранслятор опубликует его
+// в exchange событий с ключом, равным имени события.
+func AddEvent(ctx context.Context, db DB, envelope *events.Envelope) error {
+	return Add(ctx, db, envelope.Name, envelope)
+}
+
+// Outbox записывает сообщения, не связанные с изменением данных в Postgres,
+// отдельной транзакцией.
+type Outbox struct {
+	pool *pgxpool.Pool
+}
+
+func New(pool *pgxpool.Pool) *Outbox {
+	return &Outbox{pool: pool}
+}
+
+func (o *Outbox) AddEmail(ctx context.Context, event *events.EmailEvent) error {
+	return AddEmail(ctx, o.pool, event)
+}
@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	relayBatchSize = 100
+	// relayRetention — сколько хранятся опубликованные сообщения. Должно быть
+	// не меньше срока, в течение которого потребители помнят обработанные ID.
+	relayRetention = 7 * 24 * time.Hour
+	// maxErrorLength ограничивает сохраняемый текст ошибки публикации.
+	maxErrorLength = 1024
+)
+
+// Publisher публикует сообщение и возвращает nil, только когда брокер
+// подтвердил приём.
+type Publisher interface {
+	PublishConfirmed(ctx context.Context, kind, messageID string, payload []byte) error
+}
+
+type message struct {
+	id      uuid.UUID
+	kind    string
+	payload []byte
+}
+
+// Relay переносит сообщения из outbox в брокер. Сообщение отмечается
+// опубликованным после подтверждения брокера, поэтому при сбое между
+// подтверждением и отметкой оно уйдёт повторно: доставка «хотя бы один раз»,
+// повторы отсекают потребители по message_id.
+type Relay struct {
+	log       *slog.Logger
+	pool      *pgxpool.Pool
+	publisher Publisher
+}
+
+func NewRelay(log *slog.Logger, pool *pgxpool.Pool, publisher Publisher) *Relay {
+	return &Relay{
+		log:       log,
+		pool:      pool,
+		publisher: publisher,
+	}
+}
+
+// Run публикует накопившиеся сообщения и удаляет давно опубликованные.
+// Подходит как задача для scheduler.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		processed, failed, err := r.publishBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("не удалось опубликовать сообщений: %d", failed)
+		}
+		if processed < relayBatchSize {
+			break
+		}
+	}
+
+	return r.cleanup(ctx)
+}
+
+// publishBatch блокирует пачку неопубликованных сообщений (SKIP LOCKED
+// позволяет нескольким ретрансляторам не мешать друг другу) и публикует их.
+func (r *Relay) publishBatch(ctx context.Context) (int, int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id, kind, payload
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.Query(ctx, query, relayBatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("не удалось получить сообщения outbox: %w", err)
+	}
+
+	var messages []message
+	for rows.Next() {
+		var msg message
+		if err := rows.Scan(&msg.id, &msg.kind, &msg.payload); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("не удалось прочитать сообщение outbox: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("не удалось получить сообщения outbox: %w", err)
+	}
+
+	failed := 0
+	for _, msg := range messages {
+		if err := r.publisher.PublishConfirmed(ctx, msg.kind, msg.id.String(), msg.payload); err != nil {
+			failed++
+			r.log.Warn("failed to publish outbox message", "message_id", msg.id, "kind", msg.kind, "error", err)
+
+			text := err.Error()
+			if len(text) > maxErrorLength {
+				text = text[:maxErrorLength]
+			}
+			_, err = tx.Exec(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1`, msg.id, text)
+		} else {
+			_, err = tx.Exec(ctx, `UPDATE outbox SET published_at = NOW(), last_error = NULL WHERE id = $1`, msg.id)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("не удалось обновить сообщение outbox: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("не удалось зафиксировать транзакцию: %w", err)
+	}
+
+	if published := len(messages) - failed; published > 0 {
+		r.log.Info("outbox messages published", "count", published)
+	}
+
+	return len(messages), failed, nil
+}
+
+func (r *Relay) cleanup(ctx context.Context) error {
+	query := `DELETE FROM outbox WHERE published_at < $1`
+
+	tag, err := r.pool.Exec(ctx, query, time.Now().Add(-relayRetention))
+	if err != nil {
+		return fmt.Errorf("не удалось удалить опубликованные сообщения: %w", err)
+	}
+
+	if tag.RowsAffected() > 0 {
+		r.log.Info("published outbox messages deleted", "count", tag.RowsAffected())
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type ChatProvider interface {
+	// RevokeAccess закрывает открытые соединения пользователя с чатом события
+	// на всех экземплярах. Переподключиться он сможет, только если снова
+	// станет участником.
+	RevokeAccess(ctx context.Context, eventID, userID uuid.UUID) error
+}
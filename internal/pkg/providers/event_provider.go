@@ -0,0 +1,27 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventProvider interface {
+	IsParticipant(ctx context.Context, eventID, userID uuid.UUID) (bool, error)
+	IsOrganizer(ctx context.Context, eventID, userID uuid.UUID) (bool, error)
+	// SetCover сохраняет ссылку на обложку события и возвращает предыдущую.
+	SetCover(ctx context.Context, eventID uuid.UUID, coverUrl string) (*string, error)
+	// GetJoinedEvents возвращает события, которые пользователь создал или в которых участвует.
+	GetJoinedEvents(ctx context.Context, userID uuid.UUID) ([]EventInfo, error)
+	GetEventInfo(ctx context.Context, eventID uuid.UUID) (*EventInfo, error)
+	// SharesEvent сообщает, есть ли событие, в котором участвуют оба пользователя.
+	SharesEvent(ctx context.Context, userA, userB uuid.UUID) (bool, error)
+}
+
+type EventInfo struct {
+	ID          uuid.UUID
+	Title       string
+	StartsAt    *time.Time
+	CancelledAt *time.Time
+}
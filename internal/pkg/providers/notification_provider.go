@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/google/uuid"
+)
+
+// Типы уведомлений. Для каждого типа пользователь выбирает каналы доставки.
+const (
+	NotificationParticipantJoined  = "participant.joined"
+	NotificationEventCancelled     = "event.cancelled"
+	NotificationWaitlistPromoted   = "waitlist.promoted"
+	NotificationChatMention        = "chat.mention"
+	NotificationEventJoined        = "event.joined"
+	NotificationEventReminder      = "event.reminder"
+	NotificationJoinRequestUpdated = "join_request.updated"
+)
+
+type NotificationProvider interface {
+	// Notify создаёт уведомление в приложении и отправляет push на устройства
+	// пользователя — по каналам, которые он не отключил. Уведомления о
+	// собственных действиях пользователя (ActorID == userID) не создаются.
+	Notify(ctx context.Context, userID uuid.UUID, notification *Notification) error
+}
+
+// Notification описывает доменное событие для уведомления. Заполняются
+// только поля, относящиеся к типу.
+type Notification struct {
+	Type          string
+	ActorID       *uuid.UUID
+	EventID       *uuid.UUID
+	EventTitle    string
+	EventStartsAt *time.Time
+	MessageID     *uuid.UUID
+	Text          string
+}
+
+type NotificationPreferenceProvider interface {
+	// CheckEmail решает, можно ли сейчас отправить письмо типа notificationType
+	// на адрес email. Пустой тип означает служебное письмо (подтверждение
+	// адреса, сброс пароля), которое отправляется всегда.
+	CheckEmail(ctx context.Context, email, notificationType string) (*EmailDecision, error)
+	// DeferEmail откладывает письмо до момента until.
+	DeferEmail(ctx context.Context, userID uuid.UUID, event *events.EmailEvent, until time.Time) error
+}
+
+// EmailDecision — решение об отправке письма. Если получатель не найден
+// среди пользователей, UserID равен uuid.Nil, а письмо отправляется без
+// ссылки для отписки.
+type EmailDecision struct {
+	UserID           uuid.UUID
+	Allowed          bool
+	DeferUntil       *time.Time
+	UnsubscribeToken string
+	// Locale — язык писем получателя; пустой, если получатель не найден.
+	Locale string
+}
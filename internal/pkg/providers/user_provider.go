@@ -8,6 +8,17 @@ import (
 type UserProvider interface {
 	GetUsersByIDs(ctx context.Context, userIDs []uuid.UUID) (map[string]UserInfo, error)
 	GetUserByID(ctx context.Context, userID uuid.UUID) (*UserInfo, error)
+	GetUserEmail(ctx context.Context, userID uuid.UUID) (string, error)
+	// GetUserIDByEmail возвращает ID пользователя с указанным email или nil,
+	// если такого пользователя нет.
+	GetUserIDByEmail(ctx context.Context, email string) (*uuid.UUID, error)
+	// SetAvatar сохраняет ссылку на аватар и возвращает предыдущую.
+	SetAvatar(ctx context.Context, userID uuid.UUID, avatarUrl string) (string, error)
+	// HasBlock проверяет, заблокировал ли кто-то из двух пользователей другого.
+	HasBlock(ctx context.Context, userA, userB uuid.UUID) (bool, error)
+	// AllowsDirectMessages сообщает, принимает ли пользователь личные сообщения
+	// от людей без общих событий.
+	AllowsDirectMessages(ctx context.Context, userID uuid.UUID) (bool, error)
 }
 
 type UserInfo struct {
@@ -0,0 +1,58 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+type subscription struct {
+	prefix string
+	handle Handler
+}
+
+// Memory — PubSub в пределах одного процесса: для запуска в один экземпляр и тестов.
+// Publish доставляет сообщение синхронно под общей блокировкой, поэтому все
+// подписчики видят сообщения в одном порядке.
+type Memory struct {
+	mu            sync.Mutex
+	subscriptions map[*subscription]struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		subscriptions: make(map[*subscription]struct{}),
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, channel string, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for sub := range m.subscriptions {
+		if matches(channel, sub.prefix) {
+			sub.handle(&Message{Channel: channel, Payload: payload})
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, prefix string, handle Handler) error {
+	sub := &subscription{prefix: prefix, handle: handle}
+
+	m.mu.Lock()
+	m.subscriptions[sub] = struct{}{}
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	delete(m.subscriptions, sub)
+	m.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package pubsub
+
+import (
+	"context"
+	"strings"
+)
+
+// Message — сообщение, полученное из канала.
+type Message struct {
+	Channel string
+	Payload []byte
+}
+
+// Handler обрабатывает сообщения по одному в порядке их публикации.
+type Handler func(msg *Message)
+
+// PubSub рассылает сообщения всем подписчикам, в том числе на других экземплярах.
+// Сообщения одного канала доставляются каждому подписчику в одном и том же порядке.
+type PubSub interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe подписывается на все каналы с префиксом prefix и блокирует
+	// выполнение до отмены ctx. handle вызывается последовательно.
+	Subscribe(ctx context.Context, prefix string, handle Handler) error
+}
+
+func matches(channel, prefix string) bool {
+	return strings.HasPrefix(channel, prefix)
+}
@@ -0,0 +1,53 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis — PubSub поверх Redis pub/sub для работы нескольких экземпляров.
+// Redis доставляет сообщения подписчику в порядке публикации, а подписка
+// читает их одним соединением, поэтому порядок внутри канала сохраняется.
+// Сообщения, опубликованные во время переподключения подписки, теряются.
+type Redis struct {
+	redis *redis.Client
+}
+
+func NewRedis(redis *redis.Client) *Redis {
+	return &Redis{redis: redis}
+}
+
+func (r *Redis) Publish(ctx context.Context, channel string, payload []byte) error {
+	if err := r.redis.Publish(ctx, channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", channel, err)
+	}
+
+	return nil
+}
+
+func (r *Redis) Subscribe(ctx context.Context, prefix string, handle Handler) error {
+	pattern := prefix + "*"
+
+	sub := r.redis.PSubscribe(ctx, pattern)
+	defer sub.Close()
+
+	// Receive дожидается подтверждения, чтобы сразу сообщить о недоступном Redis.
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", pattern, err)
+	}
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			handle(&Message{Channel: msg.Channel, Payload: []byte(msg.Payload)})
+		}
+	}
+}
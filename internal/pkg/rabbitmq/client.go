@@ -3,34 +3,68 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
 	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/events"
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+const (
+	defaultEmailQueue = "email_events"
+	defaultExchange   = "meetly.events"
+)
+
+// Client — подключение к RabbitMQ, которое переживает перезапуск брокера:
+// соединение восстанавливается автоматически, очереди объявляются заново,
+// потребители продолжают работу.
 type Client struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	url     string
 	log     *slog.Logger
+	options Options
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	channel   *amqp.Channel
+	status    Status
+	connected chan struct{}
+
+	buffer    chan bufferedPublish
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func NewClient(url string, log *slog.Logger) (*Client, error) {
-	conn, err := amqp.Dial(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+func NewClient(url string, log *slog.Logger, options Options) (*Client, error) {
+	c := &Client{
+		url:       url,
+		log:       log,
+		options:   options.withDefaults(),
+		connected: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if c.options.PublishBufferSize > 0 {
+		c.buffer = make(chan bufferedPublish, c.options.PublishBufferSize)
 	}
 
-	channel, err := conn.Channel()
+	conn, channel, err := c.connect()
 	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to open channel: %w", err)
+		return nil, err
 	}
 
-	_, err = channel.QueueDeclare(
-		"email_events",
+	c.setConnected(conn, channel)
+	go c.supervise(conn, channel)
+
+	return c, nil
+}
+
+// declareTopology объявляет очередь писем и exchange доменных событий.
+func declareTopology(channel *amqp.Channel, options Options) error {
+	_, err := channel.QueueDeclare(
+		options.EmailQueue,
 		true,
 		false,
 		false,
@@ -38,16 +72,14 @@ func NewClient(url string, log *slog.Logger) (*Client, error) {
 		nil,
 	)
 	if err != nil {
-		channel.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	if err := channel.ExchangeDeclare(options.Exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
-	return &Client{
-		conn:    conn,
-		channel: channel,
-		log:     log,
-	}, nil
+	return declareRetryTopology(channel, options.EmailQueue, options.Retry)
 }
 
 func (c *Client) PublishEmailEvent(event events.EmailEvent) error {
@@ -56,21 +88,13 @@ func (c *Client) PublishEmailEvent(event events.EmailEvent) error {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	err = c.channel.PublishWithContext(
-		ctx,
-		"",
-		"email_events",
-		false,
-		false,
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         body,
-			DeliveryMode: amqp.Persistent,
-		},
-	)
+	err = c.publish(context.Background(), "", c.options.EmailQueue, amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         body,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    uuid.NewString(),
+		Timestamp:    time.Now(),
+	})
 	if err != nil {
 		return fmt.Errorf("failed to publish event: %w", err)
 	}
@@ -79,11 +103,200 @@ func (c *Client) PublishEmailEvent(event events.EmailEvent) error {
 	return nil
 }
 
-// ConsumeEmailEvents потребляет события email
-func (c *Client) ConsumeEmailEvents(ctx context.Context, handler func(events.EmailEvent) error) error {
-	msgs, err := c.channel.Consume(
-		"email_events",
-		"",
+// Publish публикует доменное событие в exchange с ключом маршрутизации,
+// равным имени события. Без соединения событие ждёт в буфере.
+func (c *Client) Publish(ctx context.Context, envelope *events.Envelope) error {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	err = c.publish(ctx, c.options.Exchange, envelope.Name, amqp.Publishing{
+		ContentType:  "application/json",
+		Type:         envelope.Name,
+		Body:         body,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    envelope.ID,
+		Timestamp:    envelope.OccurredAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	return nil
+}
+
+// PublishConfirmed публикует сообщение из outbox с заданным message_id и
+// возвращает nil только после подтверждения брокера. Письма идут в очередь
+// писем, остальные виды считаются доменными событиями и идут в exchange.
+func (c *Client) PublishConfirmed(ctx context.Context, kind, messageID string, payload []byte) error {
+	exchange, key := c.options.Exchange, kind
+	if kind == events.EmailEventKind {
+		exchange, key = "", c.options.EmailQueue
+	}
+
+	return c.publishConfirmed(ctx, exchange, key, amqp.Publishing{
+		ContentType:  "application/json",
+		Type:         kind,
+		Body:         payload,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Timestamp:    time.Now(),
+	})
+}
+
+// ConsumeEmailEvents потребляет события email, пока не отменён ctx. После
+// обрыва соединения потребление возобновляется при переподключении.
+// Если обработчик вернул ошибку, письмо откладывается и доставляется повторно
+// с нарастающей задержкой; окончательные ошибки (Permanent) и письма,
+// исчерпавшие повторы, попадают в очередь недоставленных. После отмены ctx
+// метод дожидается писем, которые уже обрабатываются (см. ConsumerOptions).
+func (c *Client) ConsumeEmailEvents(ctx context.Context, options ConsumerOptions, handler func(context.Context, events.EmailEvent) error) error {
+	return c.consumeLoop(ctx, consumer{
+		name:    "email",
+		queue:   c.options.EmailQueue,
+		options: options.withDefaults(),
+		handle: func(ctx context.Context, msg *amqp.Delivery) error {
+			var event events.EmailEvent
+			if err := json.Unmarshal(msg.Body, &event); err != nil {
+				c.log.Error("failed to unmarshal email event", "error", err)
+				return Permanent(err)
+			}
+
+			if err := handler(ctx, event); err != nil {
+				c.log.Error("failed to handle email event",
+					"error", err,
+					"template", event.Template,
+					"to", event.To,
+				)
+				return err
+			}
+
+			c.log.Debug("email event processed successfully",
+				"template", event.Template,
+				"to", event.To,
+			)
+			return nil
+		},
+	})
+}
+
+// Subscribe читает доменные события из очереди queue, привязанной к exchange
+// по шаблонам patterns, пока не отменён ctx. Очередь и её привязки
+// объявляются при каждом подключении. Повторная доставка и очередь
+// недоставленных работают так же, как для писем.
+func (c *Client) Subscribe(ctx context.Context, queue string, patterns []string, handler func(ctx context.Context, envelope *events.Envelope) error) error {
+	if queue == "" || len(patterns) == 0 {
+		return errors.New("queue and patterns are required")
+	}
+
+	setup := func(channel *amqp.Channel) error {
+		if _, err := channel.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("failed to declare queue: %w", err)
+		}
+
+		for _, pattern := range patterns {
+			if err := channel.QueueBind(queue, pattern, c.options.Exchange, false, nil); err != nil {
+				return fmt.Errorf("failed to bind queue: %w", err)
+			}
+		}
+
+		return declareRetryTopology(channel, queue, c.options.Retry)
+	}
+
+	return c.consumeLoop(ctx, consumer{
+		name:    queue,
+		queue:   queue,
+		options: ConsumerOptions{}.withDefaults(),
+		setup:   setup,
+		handle: func(ctx context.Context, msg *amqp.Delivery) error {
+			var envelope events.Envelope
+			if err := json.Unmarshal(msg.Body, &envelope); err != nil {
+				c.log.Error("failed to unmarshal domain event", "queue", queue, "error", err)
+				return Permanent(err)
+			}
+
+			if err := handler(ctx, &envelope); err != nil {
+				c.log.Error("failed to handle domain event",
+					"error", err,
+					"queue", queue,
+					"event", envelope.Name,
+					"event_id", envelope.ID,
+				)
+				return err
+			}
+
+			c.log.Debug("domain event processed successfully", "queue", queue, "event", envelope.Name, "event_id", envelope.ID)
+			return nil
+		},
+	})
+}
+
+// consumer описывает чтение одной очереди.
+type consumer struct {
+	name    string
+	queue   string
+	options ConsumerOptions
+	// setup, если задан, вызывается на канале потребителя перед чтением.
+	setup  func(*amqp.Channel) error
+	handle func(context.Context, *amqp.Delivery) error
+}
+
+// consumeLoop читает очередь, пока не отменён ctx, и возобновляет чтение
+// после переподключения.
+func (c *Client) consumeLoop(ctx context.Context, cons consumer) error {
+	c.log.Info("started consuming", "consumer", cons.name, "workers", cons.options.Workers, "prefetch", cons.options.Prefetch)
+
+	for {
+		conn, err := c.waitConnected(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.log.Info("stopping consumer", "consumer", cons.name)
+				return nil
+			}
+			return err
+		}
+
+		err = c.consume(ctx, conn, cons)
+		if ctx.Err() != nil {
+			c.log.Info("consumer stopped", "consumer", cons.name)
+			return nil
+		}
+
+		c.log.Warn("consumer interrupted, waiting for reconnect", "consumer", cons.name, "error", err)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.options.ReconnectDelay):
+		}
+	}
+}
+
+// consume читает очередь через отдельный канал, пока он открыт, и раздаёт
+// сообщения пулу обработчиков. После отмены ctx чтение прекращается,
+// полученные, но не начатые сообщения возвращаются в очередь, а начатым
+// даётся DrainTimeout на завершение.
+func (c *Client) consume(ctx context.Context, conn *amqp.Connection, cons consumer) error {
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer channel.Close()
+
+	if cons.setup != nil {
+		if err := cons.setup(channel); err != nil {
+			return err
+		}
+	}
+
+	if err := channel.Qos(cons.options.Prefetch, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	tag := cons.name + "-" + uuid.NewString()
+	msgs, err := channel.Consume(
+		cons.queue,
+		tag,
 		false,
 		false,
 		false,
@@ -94,62 +307,199 @@ func (c *Client) ConsumeEmailEvents(ctx context.Context, handler func(events.Ema
 		return fmt.Errorf("failed to consume messages: %w", err)
 	}
 
-	c.log.Info("started consuming email events")
+	// Обработчики не должны прерываться вместе с ctx: начатое письмо лучше
+	// дописать. Их контекст отменяется, только если не уложились в DrainTimeout.
+	handleCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	deliveries := make(chan amqp.Delivery)
+	var wg sync.WaitGroup
+	for range cons.options.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range deliveries {
+				c.process(handleCtx, channel, cons, &msg)
+			}
+		}()
+	}
 
+	err = dispatch(ctx, msgs, deliveries)
+	close(deliveries)
+
+	if ctx.Err() == nil {
+		// Канал закрыт: подтвердить сообщения уже нельзя, брокер вернёт их в очередь сам.
+		cancelHandlers()
+		wg.Wait()
+		return err
+	}
+
+	c.drain(channel, cons, tag, msgs, &wg, cancelHandlers)
+	return nil
+}
+
+// dispatch передаёт сообщения обработчикам, пока не отменён ctx или не
+// закрыт канал доставки.
+func dispatch(ctx context.Context, msgs <-chan amqp.Delivery, deliveries chan<- amqp.Delivery) error {
 	for {
 		select {
 		case <-ctx.Done():
-			c.log.Info("stopping email events consumer")
 			return nil
 		case msg, ok := <-msgs:
 			if !ok {
-				c.log.Warn("email events channel closed")
-				return nil
+				return errors.New("delivery channel closed")
 			}
 
-			var event events.EmailEvent
-			if err := json.Unmarshal(msg.Body, &event); err != nil {
-				c.log.Error("failed to unmarshal email event", "error", err)
-				msg.Nack(false, false)
-				continue
-			}
-
-			// Обрабатываем событие
-			if err := handler(event); err != nil {
-				c.log.Error("failed to handle email event",
-					"error", err,
-					"template", event.Template,
-					"to", event.To,
-				)
+			select {
+			case deliveries <- msg:
+			case <-ctx.Done():
 				msg.Nack(false, true)
-			} else {
-				msg.Ack(false)
-				c.log.Debug("email event processed successfully",
-					"template", event.Template,
-					"to", event.To,
-				)
+				return nil
 			}
 		}
 	}
 }
 
-func (c *Client) Close() error {
-	if c.channel != nil {
-		if err := c.channel.Close(); err != nil {
-			c.log.Error("failed to close channel", "error", err)
-		}
+// drain останавливает доставку, возвращает в очередь уже полученные
+// сообщения и ждёт обработчиков не дольше DrainTimeout. Обработчики, не
+// успевшие закончить, получают отменённый контекст и возвращают своё
+// сообщение в очередь.
+func (c *Client) drain(channel *amqp.Channel, cons consumer, tag string, msgs <-chan amqp.Delivery, wg *sync.WaitGroup, cancelHandlers context.CancelFunc) {
+	c.log.Info("draining consumer", "consumer", cons.name, "timeout", cons.options.DrainTimeout)
+
+	if err := channel.Cancel(tag, false); err != nil {
+		c.log.Warn("failed to cancel consumer", "consumer", cons.name, "error", err)
+	}
+
+	requeued := 0
+	for msg := range msgs {
+		msg.Nack(false, true)
+		requeued++
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(cons.options.DrainTimeout):
+		c.log.Warn("drain timeout exceeded, interrupting handlers", "consumer", cons.name)
+		cancelHandlers()
+		<-done
+	}
+
+	if requeued > 0 {
+		c.log.Info("prefetched messages requeued", "consumer", cons.name, "count", requeued)
 	}
-	if c.conn != nil {
-		if err := c.conn.Close(); err != nil {
-			c.log.Error("failed to close connection", "error", err)
+}
+
+// process обрабатывает одно сообщение и подтверждает его. Сообщение, чью
+// обработку прервала остановка, возвращается в очередь без учёта попытки.
+func (c *Client) process(ctx context.Context, channel *amqp.Channel, cons consumer, msg *amqp.Delivery) {
+	if c.seen(ctx, cons.queue, msg) {
+		msg.Ack(false)
+		return
+	}
+
+	if err := cons.handle(ctx, msg); err != nil {
+		if ctx.Err() != nil {
+			c.log.Info("message handling interrupted, requeued", "consumer", cons.name, "message_id", msg.MessageId)
+			msg.Nack(false, true)
+			return
 		}
+
+		c.handleFailure(ctx, channel, cons.queue, msg, err)
+		return
 	}
-	return nil
+
+	c.remember(ctx, cons.queue, msg)
+	msg.Ack(false)
 }
 
-func (c *Client) HealthCheck() error {
-	if c.conn == nil || c.conn.IsClosed() {
-		return fmt.Errorf("rabbitmq connection is closed")
+// seen сообщает, обработано ли сообщение раньше. При ошибке хранилища
+// сообщение обрабатывается: повтор лучше потери.
+func (c *Client) seen(ctx context.Context, queue string, msg *amqp.Delivery) bool {
+	if c.options.Deduplicator == nil || msg.MessageId == "" {
+		return false
+	}
+
+	seen, err := c.options.Deduplicator.Seen(ctx, queue+":"+msg.MessageId)
+	if err != nil {
+		c.log.Warn("failed to check message deduplication", "message_id", msg.MessageId, "error", err)
+		return false
+	}
+	if seen {
+		c.log.Info("duplicate message skipped", "queue", queue, "message_id", msg.MessageId)
+	}
+
+	return seen
+}
+
+func (c *Client) remember(ctx context.Context, queue string, msg *amqp.Delivery) {
+	if c.options.Deduplicator == nil || msg.MessageId == "" {
+		return
+	}
+
+	if err := c.options.Deduplicator.Remember(ctx, queue+":"+msg.MessageId); err != nil {
+		c.log.Warn("failed to remember processed message", "message_id", msg.MessageId, "error", err)
+	}
+}
+
+// handleFailure переносит сообщение в очередь ожидания или в очередь
+// недоставленных и подтверждает исходное. Если перенести не удалось,
+// сообщение возвращается в очередь, чтобы не потерять его. Публикация идёт
+// через канал потребителя, чтобы подтверждение не опередило перенос.
+func (c *Client) handleFailure(ctx context.Context, channel *amqp.Channel, queue string, msg *amqp.Delivery, handleErr error) {
+	headers := failureHeaders(msg, handleErr, time.Now())
+	retries := retryCount(msg.Headers)
+
+	target := deadLetterQueueName(queue)
+	if !IsPermanent(handleErr) && retries < c.options.Retry.MaxRetries {
+		retries++
+		target = retryQueueName(queue, c.options.Retry.Delay(retries))
+		headers[headerRetryCount] = int32(retries)
+	} else {
+		headers[headerOriginalQueue] = queue
+		headers[headerDeadLetteredAt] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	messageID := msg.MessageId
+	if messageID == "" {
+		messageID = uuid.NewString()
+	}
+
+	publishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+	defer cancel()
+
+	err := channel.PublishWithContext(
+		publishCtx,
+		"",
+		target,
+		false,
+		false,
+		amqp.Publishing{
+			Headers:      headers,
+			ContentType:  msg.ContentType,
+			Body:         msg.Body,
+			DeliveryMode: amqp.Persistent,
+			MessageId:    messageID,
+			Timestamp:    msg.Timestamp,
+		},
+	)
+	if err != nil {
+		c.log.Error("failed to move failed message", "queue", target, "error", err)
+		msg.Nack(false, true)
+		return
+	}
+
+	msg.Ack(false)
+
+	if target == deadLetterQueueName(queue) {
+		c.log.Warn("message dead-lettered", "message_id", messageID, "retries", retries, "error", handleErr)
+	} else {
+		c.log.Info("message scheduled for retry", "message_id", messageID, "retry", retries, "queue", target)
 	}
-	return nil
 }
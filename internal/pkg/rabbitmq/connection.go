@@ -0,0 +1,428 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// publishTimeout ограничивает публикацию одного сообщения.
+const publishTimeout = 5 * time.Second
+
+var (
+	ErrNotConnected      = errors.New("rabbitmq: not connected")
+	ErrPublishBufferFull = errors.New("rabbitmq: publish buffer is full")
+	ErrClientClosed      = errors.New("rabbitmq: client closed")
+	ErrPublishNacked     = errors.New("rabbitmq: publish not confirmed by broker")
+)
+
+// Options — настройки клиента.
+type Options struct {
+	// EmailQueue — очередь писем. По умолчанию "email_events".
+	EmailQueue string
+	// Exchange — topic exchange доменных событий. Ключ маршрутизации —
+	// имя события. По умолчанию "meetly.events".
+	Exchange string
+	Retry    RetryPolicy
+	// ReconnectDelay — пауза перед первой попыткой переподключения. Каждая
+	// неудачная попытка удваивает её, но не больше MaxReconnectDelay.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// PublishBufferSize — сколько сообщений держать в памяти, пока нет
+	// соединения. Они отправляются после переподключения и теряются при
+	// остановке процесса. При 0 публикация без соединения сразу завершается
+	// ошибкой ErrNotConnected.
+	PublishBufferSize int
+	// Deduplicator отсекает повторную доставку уже обработанных сообщений.
+	// Если не задан, дубликаты обрабатываются повторно.
+	Deduplicator Deduplicator
+}
+
+// Deduplicator запоминает обработанные сообщения по ключу, чтобы доставка
+// «хотя бы один раз» не приводила к повторной обработке.
+type Deduplicator interface {
+	Seen(ctx context.Context, key string) (bool, error)
+	Remember(ctx context.Context, key string) error
+}
+
+func (o Options) withDefaults() Options {
+	if o.EmailQueue == "" {
+		o.EmailQueue = defaultEmailQueue
+	}
+	if o.Exchange == "" {
+		o.Exchange = defaultExchange
+	}
+	o.Retry = o.Retry.withDefaults()
+	if o.ReconnectDelay <= 0 {
+		o.ReconnectDelay = time.Second
+	}
+	if o.MaxReconnectDelay < o.ReconnectDelay {
+		o.MaxReconnectDelay = max(30*time.Second, o.ReconnectDelay)
+	}
+	return o
+}
+
+// ConsumerOptions — настройки чтения очереди.
+type ConsumerOptions struct {
+	// Workers — сколько сообщений обрабатывается одновременно. По умолчанию 1.
+	Workers int
+	// Prefetch — сколько сообщений брокер выдаёт без подтверждения. Меньше
+	// Workers не имеет смысла, поэтому по умолчанию и при меньшем значении
+	// равно Workers.
+	Prefetch int
+	// DrainTimeout — сколько при остановке ждать сообщений, которые уже
+	// обрабатываются. По умолчанию 30 секунд.
+	DrainTimeout time.Duration
+}
+
+func (o ConsumerOptions) withDefaults() ConsumerOptions {
+	if o.Workers <= 0 {
+		o.Workers = 1
+	}
+	if o.Prefetch < o.Workers {
+		o.Prefetch = o.Workers
+	}
+	if o.DrainTimeout <= 0 {
+		o.DrainTimeout = 30 * time.Second
+	}
+	return o
+}
+
+type State string
+
+const (
+	StateConnected    State = "connected"
+	StateReconnecting State = "reconnecting"
+	StateClosed       State = "closed"
+)
+
+// Status — состояние подключения для проверки здоровья.
+type Status struct {
+	State      State     `json:"state"`
+	Since      time.Time `json:"since"`
+	Reconnects int       `json:"reconnects"`
+	Buffered   int       `json:"buffered"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+type bufferedPublish struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+// connect открывает соединение и канал публикации и объявляет очереди. Канал
+// публикации работает в режиме подтверждений (publisher confirms).
+func (c *Client) connect() (*amqp.Connection, *amqp.Channel, error) {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	if err := declareTopology(channel, c.options); err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, channel, nil
+}
+
+// supervise следит за соединением и каналом публикации и после обрыва
+// восстанавливает их, пока клиент не закрыт.
+func (c *Client) supervise(conn *amqp.Connection, channel *amqp.Channel) {
+	for {
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		channelClosed := channel.NotifyClose(make(chan *amqp.Error, 1))
+
+		var reason *amqp.Error
+		select {
+		case <-c.done:
+			return
+		case reason = <-connClosed:
+		case reason = <-channelClosed:
+		}
+
+		channel.Close()
+		conn.Close()
+
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		c.setDisconnected(reason)
+		c.log.Warn("rabbitmq connection lost", "error", reason)
+
+		conn, channel = c.reconnect()
+		if conn == nil {
+			return
+		}
+
+		c.setConnected(conn, channel)
+		c.log.Info("rabbitmq connection restored")
+
+		c.flushBuffer()
+	}
+}
+
+// reconnect повторяет подключение с нарастающей паузой. Возвращает nil,
+// если клиент закрыли.
+func (c *Client) reconnect() (*amqp.Connection, *amqp.Channel) {
+	delay := c.options.ReconnectDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.done:
+			return nil, nil
+		case <-time.After(delay):
+		}
+
+		conn, channel, err := c.connect()
+		if err == nil {
+			return conn, channel
+		}
+
+		c.mu.Lock()
+		c.status.LastError = err.Error()
+		c.mu.Unlock()
+
+		c.log.Warn("rabbitmq reconnect failed", "attempt", attempt, "retry_in", delay, "error", err)
+		delay = min(delay*2, c.options.MaxReconnectDelay)
+	}
+}
+
+func (c *Client) setConnected(conn *amqp.Connection, channel *amqp.Channel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.status.State == StateReconnecting {
+		c.status.Reconnects++
+	}
+
+	c.conn = conn
+	c.channel = channel
+	c.status.State = StateConnected
+	c.status.Since = time.Now()
+	c.status.LastError = ""
+	close(c.connected)
+}
+
+func (c *Client) setDisconnected(reason *amqp.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conn = nil
+	c.channel = nil
+	c.status.State = StateReconnecting
+	c.status.Since = time.Now()
+	if reason != nil {
+		c.status.LastError = reason.Error()
+	}
+	c.connected = make(chan struct{})
+}
+
+// waitConnected ждёт соединения и возвращает его.
+func (c *Client) waitConnected(ctx context.Context) (*amqp.Connection, error) {
+	for {
+		c.mu.RLock()
+		conn, connected := c.conn, c.connected
+		c.mu.RUnlock()
+
+		if conn != nil {
+			return conn, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, ErrClientClosed
+		case <-connected:
+		}
+	}
+}
+
+// openChannel открывает отдельный канал на текущем соединении.
+func (c *Client) openChannel() (*amqp.Channel, error) {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	return channel, nil
+}
+
+// publish отправляет сообщение в exchange с ключом key; для пустого exchange
+// key — имя очереди. Если соединения нет, сообщение откладывается в буфер до
+// переподключения; без буфера или при заполненном буфере возвращается ошибка.
+func (c *Client) publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
+	c.mu.RLock()
+	channel := c.channel
+	c.mu.RUnlock()
+
+	if channel != nil {
+		ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+		defer cancel()
+
+		err := channel.PublishWithContext(ctx, exchange, key, false, false, msg)
+		if !errors.Is(err, amqp.ErrClosed) {
+			return err
+		}
+	}
+
+	if c.buffer == nil {
+		return ErrNotConnected
+	}
+
+	select {
+	case c.buffer <- bufferedPublish{exchange: exchange, key: key, msg: msg}:
+		c.log.Warn("rabbitmq unavailable, message buffered", "exchange", exchange, "key", key, "buffered", len(c.buffer))
+	default:
+		return ErrPublishBufferFull
+	}
+
+	// Соединение могло восстановиться, пока публикация завершалась ошибкой:
+	// тогда буфер уже разобран и сообщение ждало бы следующего обрыва.
+	c.mu.RLock()
+	reconnected := c.channel != nil
+	c.mu.RUnlock()
+	if reconnected {
+		go c.flushBuffer()
+	}
+
+	return nil
+}
+
+// publishConfirmed отправляет сообщение и ждёт подтверждения брокера. Без
+// соединения сообщение не буферизуется: вызывающий повторит попытку сам.
+func (c *Client) publishConfirmed(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	c.mu.RLock()
+	channel := c.channel
+	c.mu.RUnlock()
+
+	if channel == nil {
+		return ErrNotConnected
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for publish confirmation: %w", err)
+	}
+	if !acked {
+		return ErrPublishNacked
+	}
+
+	return nil
+}
+
+// flushBuffer отправляет сообщения, накопленные без соединения. Если
+// соединение снова оборвётся, неотправленные сообщения вернутся в буфер.
+func (c *Client) flushBuffer() {
+	if c.buffer == nil {
+		return
+	}
+
+	flushed := 0
+	defer func() {
+		if flushed > 0 {
+			c.log.Info("buffered messages published", "count", flushed)
+		}
+	}()
+
+	for n := len(c.buffer); n > 0; n-- {
+		var item bufferedPublish
+		select {
+		case item = <-c.buffer:
+		default:
+			return
+		}
+
+		if err := c.publish(context.Background(), item.exchange, item.key, item.msg); err != nil {
+			c.log.Error("failed to publish buffered message", "exchange", item.exchange, "key", item.key, "error", err)
+			continue
+		}
+		flushed++
+	}
+}
+
+// Status возвращает состояние подключения.
+func (c *Client) Status() Status {
+	c.mu.RLock()
+	status := c.status
+	c.mu.RUnlock()
+
+	status.Buffered = len(c.buffer)
+	return status
+}
+
+func (c *Client) HealthCheck() error {
+	if status := c.Status(); status.State != StateConnected {
+		return fmt.Errorf("rabbitmq is %s", status.State)
+	}
+	return nil
+}
+
+// Close закрывает соединение и останавливает переподключение. Сообщения,
+// оставшиеся в буфере, теряются.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.status.State = StateClosed
+		c.status.Since = time.Now()
+
+		if c.channel != nil {
+			if err := c.channel.Close(); err != nil {
+				c.log.Error("failed to close channel", "error", err)
+			}
+		}
+		if c.conn != nil {
+			if err := c.conn.Close(); err != nil {
+				c.log.Error("failed to close connection", "error", err)
+			}
+		}
+		c.conn = nil
+		c.channel = nil
+	})
+	return nil
+}
@@ -0,0 +1,187 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// DeadLetter — сообщение из очереди недоставленных.
+type DeadLetter struct {
+	MessageID      string
+	Queue          string
+	Retries        int
+	LastError      string
+	FirstFailureAt *time.Time
+	DeadLetteredAt *time.Time
+	Body           []byte
+}
+
+// DeadLetters — часть очереди недоставленных и общее число сообщений в ней.
+type DeadLetters struct {
+	Total    int
+	Messages []DeadLetter
+}
+
+// PeekEmailDeadLetters возвращает первые limit недоставленных писем, не
+// удаляя их из очереди.
+func (c *Client) PeekEmailDeadLetters(ctx context.Context, limit int) (*DeadLetters, error) {
+	channel, err := c.openChannel()
+	if err != nil {
+		return nil, err
+	}
+	defer channel.Close()
+
+	queue, err := channel.QueueDeclarePassive(deadLetterQueueName(c.options.EmailQueue), true, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect dead letter queue: %w", err)
+	}
+
+	result := &DeadLetters{Total: queue.Messages}
+
+	var last uint64
+	for len(result.Messages) < limit && ctx.Err() == nil {
+		msg, ok, err := channel.Get(queue.Name, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dead letter: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		last = msg.DeliveryTag
+		result.Messages = append(result.Messages, deliveryToDeadLetter(&msg))
+	}
+
+	// Возвращаем всё прочитанное в очередь на прежние места.
+	if last != 0 {
+		if err := channel.Nack(last, true, true); err != nil {
+			return nil, fmt.Errorf("failed to requeue dead letters: %w", err)
+		}
+	}
+
+	return result, ctx.Err()
+}
+
+// ReplayEmailDeadLetters возвращает недоставленные письма в очередь отправки
+// со сброшенным счётчиком повторов. Если messageIDs пуст, возвращаются все.
+// Возвращает число отправленных повторно писем. Письмо удаляется из очереди
+// недоставленных только после того, как брокер подтвердил его публикацию.
+func (c *Client) ReplayEmailDeadLetters(ctx context.Context, messageIDs []string) (int, error) {
+	channel, err := c.openChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer channel.Close()
+
+	if err := channel.Confirm(false); err != nil {
+		return 0, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	queue, err := channel.QueueDeclarePassive(deadLetterQueueName(c.options.EmailQueue), true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect dead letter queue: %w", err)
+	}
+
+	var skipped []amqp.Delivery
+	defer func() {
+		for _, msg := range skipped {
+			msg.Nack(false, true)
+		}
+	}()
+
+	replayed := 0
+	for i := 0; i < queue.Messages && ctx.Err() == nil; i++ {
+		msg, ok, err := channel.Get(queue.Name, false)
+		if err != nil {
+			return replayed, fmt.Errorf("failed to get dead letter: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		if len(messageIDs) > 0 && !slices.Contains(messageIDs, msg.MessageId) {
+			skipped = append(skipped, msg)
+			continue
+		}
+
+		if err := c.replay(ctx, channel, &msg); err != nil {
+			msg.Nack(false, true)
+			return replayed, err
+		}
+		if err := msg.Ack(false); err != nil {
+			return replayed, fmt.Errorf("failed to ack dead letter: %w", err)
+		}
+		replayed++
+	}
+
+	return replayed, ctx.Err()
+}
+
+func (c *Client) replay(ctx context.Context, channel *amqp.Channel, msg *amqp.Delivery) error {
+	queue := c.options.EmailQueue
+	if original, ok := msg.Headers[headerOriginalQueue].(string); ok && original != "" {
+		queue = original
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(
+		ctx,
+		"",
+		queue,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType:  msg.ContentType,
+			Body:         msg.Body,
+			DeliveryMode: amqp.Persistent,
+			MessageId:    msg.MessageId,
+			Timestamp:    msg.Timestamp,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to replay dead letter: %w", err)
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for replay confirmation: %w", err)
+	}
+	if !acked {
+		return ErrPublishNacked
+	}
+
+	return nil
+}
+
+func deliveryToDeadLetter(msg *amqp.Delivery) DeadLetter {
+	letter := DeadLetter{
+		MessageID:      msg.MessageId,
+		Retries:        retryCount(msg.Headers),
+		FirstFailureAt: headerTime(msg.Headers, headerFirstFailureAt),
+		DeadLetteredAt: headerTime(msg.Headers, headerDeadLetteredAt),
+		Body:           msg.Body,
+	}
+	letter.Queue, _ = msg.Headers[headerOriginalQueue].(string)
+	letter.LastError, _ = msg.Headers[headerLastError].(string)
+
+	return letter
+}
+
+func headerTime(headers amqp.Table, key string) *time.Time {
+	value, ok := headers[key].(string)
+	if !ok {
+		return nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
@@ -0,0 +1,167 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Заголовки, которыми сопровождается повторная доставка.
+const (
+	headerRetryCount     = "x-retry-count"
+	headerFirstFailureAt = "x-first-failure-at"
+	headerLastError      = "x-last-error"
+	headerOriginalQueue  = "x-original-queue"
+	headerDeadLetteredAt = "x-dead-lettered-at"
+)
+
+// maxErrorLength ограничивает текст ошибки, сохраняемый в заголовке.
+const maxErrorLength = 512
+
+// RetryPolicy задаёт повторную доставку сообщений, которые не удалось
+// обработать. Задержка начинается с InitialDelay и удваивается с каждой
+// попыткой, но не превышает MaxDelay. После MaxRetries повторов сообщение
+// попадает в очередь недоставленных.
+type RetryPolicy struct {
+	MaxRetries   int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// DefaultRetryPolicy даёт шесть повторов: через 30 секунд, 1, 2, 4, 8 и 16 минут.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:   6,
+	InitialDelay: 30 * time.Second,
+	MaxDelay:     30 * time.Minute,
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxRetries <= 0 {
+		p.MaxRetries = DefaultRetryPolicy.MaxRetries
+	}
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = DefaultRetryPolicy.InitialDelay
+	}
+	if p.MaxDelay < p.InitialDelay {
+		p.MaxDelay = max(DefaultRetryPolicy.MaxDelay, p.InitialDelay)
+	}
+	return p
+}
+
+// Delay возвращает задержку перед повтором с номером retry (начиная с 1).
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// delays перечисляет различные задержки политики: для каждой объявляется
+// своя очередь ожидания.
+func (p RetryPolicy) delays() []time.Duration {
+	var result []time.Duration
+	for retry := 1; retry <= p.MaxRetries; retry++ {
+		delay := p.Delay(retry)
+		if len(result) == 0 || result[len(result)-1] != delay {
+			result = append(result, delay)
+		}
+	}
+	return result
+}
+
+// retryQueueName — очередь ожидания для задержки. Задержка входит в имя,
+// потому что аргументы существующей очереди изменить нельзя.
+func retryQueueName(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queue, delay)
+}
+
+func deadLetterQueueName(queue string) string {
+	return queue + ".dead"
+}
+
+// declareRetryTopology объявляет для очереди queue очереди ожидания и очередь
+// недоставленных. Очередь ожидания не читается: сообщение лежит в ней до
+// истечения TTL и возвращается через dead-letter exchange в исходную очередь.
+func declareRetryTopology(channel *amqp.Channel, queue string, policy RetryPolicy) error {
+	for _, delay := range policy.delays() {
+		_, err := channel.QueueDeclare(
+			retryQueueName(queue, delay),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue: %w", err)
+		}
+	}
+
+	if _, err := channel.QueueDeclare(deadLetterQueueName(queue), true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead letter queue: %w", err)
+	}
+
+	return nil
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку обработчика как окончательную: сообщение сразу
+// попадает в очередь недоставленных без повторов.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent сообщает, помечена ли ошибка как окончательная.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+func retryCount(headers amqp.Table) int {
+	switch value := headers[headerRetryCount].(type) {
+	case int32:
+		return int(value)
+	case int64:
+		return int(value)
+	case int:
+		return value
+	default:
+		return 0
+	}
+}
+
+// failureHeaders копирует заголовки сообщения и дополняет их сведениями об ошибке.
+func failureHeaders(msg *amqp.Delivery, handleErr error, now time.Time) amqp.Table {
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	if _, ok := headers[headerFirstFailureAt]; !ok {
+		headers[headerFirstFailureAt] = now.UTC().Format(time.RFC3339)
+	}
+
+	text := handleErr.Error()
+	if len(text) > maxErrorLength {
+		text = text[:maxErrorLength]
+	}
+	headers[headerLastError] = text
+
+	return headers
+}
@@ -0,0 +1,337 @@
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// maxPeriods ограничивает перебор периодов, чтобы правило без UNTIL и COUNT
+// не зациклилось на далёком окне.
+const maxPeriods = 100000
+
+// Weekday — элемент BYDAY. N задаёт порядковый номер дня в месяце
+// (1MO — первый понедельник, -1FR — последняя пятница), 0 — любой такой день.
+type Weekday struct {
+	Day time.Weekday
+	N   int
+}
+
+// Rule — поддерживаемое подмножество RRULE из RFC 5545:
+// FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, BYDAY, UNTIL и COUNT.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []Weekday
+	Until    *time.Time
+	Count    int
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+	time.Sunday:    "SU",
+}
+
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("empty rule")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch Frequency(strings.ToUpper(val)) {
+			case Daily, Weekly, Monthly:
+				rule.Freq = Frequency(strings.ToUpper(val))
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				weekday, err := parseWeekday(day)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "WKST":
+			if strings.ToUpper(val) != "MO" {
+				return nil, fmt.Errorf("unsupported WKST %q", val)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, errors.New("COUNT and UNTIL are mutually exclusive")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly {
+			return nil, fmt.Errorf("ordinal BYDAY is supported only for MONTHLY")
+		}
+	}
+
+	return rule, nil
+}
+
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			name := weekdayNames[day.Day]
+			if day.N != 0 {
+				name = strconv.Itoa(day.N) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Between возвращает начала повторений в интервале [from, to) в порядке возрастания.
+// Повторения рассчитываются в часовом поясе dtstart, поэтому время начала
+// сохраняется при переходе на летнее время. Даты из exdates исключаются,
+// но, как и в RFC 5545, учитываются в COUNT.
+func (r *Rule) Between(dtstart, from, to time.Time, exdates []time.Time) []time.Time {
+	excluded := make(map[int64]struct{}, len(exdates))
+	for _, exdate := range exdates {
+		excluded[exdate.Unix()] = struct{}{}
+	}
+
+	result := make([]time.Time, 0)
+	generated := 0
+
+	for period := 0; period < maxPeriods; period++ {
+		for _, occurrence := range r.period(dtstart, period) {
+			if occurrence.Before(dtstart) {
+				continue
+			}
+			if r.Until != nil && occurrence.After(*r.Until) {
+				return result
+			}
+			if !occurrence.Before(to) {
+				return result
+			}
+
+			generated++
+			if r.Count > 0 && generated > r.Count {
+				return result
+			}
+
+			if occurrence.Before(from) {
+				continue
+			}
+			if _, ok := excluded[occurrence.Unix()]; ok {
+				continue
+			}
+
+			result = append(result, occurrence)
+		}
+	}
+
+	return result
+}
+
+// Contains сообщает, является ли t началом одного из повторений правила.
+func (r *Rule) Contains(dtstart, t time.Time) bool {
+	occurrences := r.Between(dtstart, t, t.Add(time.Second), nil)
+	return len(occurrences) == 1 && occurrences[0].Equal(t)
+}
+
+func (r *Rule) period(dtstart time.Time, period int) []time.Time {
+	loc := dtstart.Location()
+	hour, minute, second := dtstart.Clock()
+	step := period * r.Interval
+
+	switch r.Freq {
+	case Daily:
+		day := dtstart.AddDate(0, 0, step)
+		if len(r.ByDay) > 0 && !r.hasWeekday(day.Weekday()) {
+			return nil
+		}
+		return []time.Time{day}
+	case Weekly:
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		weekStart := time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step, hour, minute, second, 0, loc)
+
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []Weekday{{Day: dtstart.Weekday()}}
+		}
+
+		result := make([]time.Time, 0, len(days))
+		for _, day := range days {
+			dayOffset := (int(day.Day) + 6) % 7
+			result = append(result, time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day()+dayOffset, hour, minute, second, 0, loc))
+		}
+		sortTimes(result)
+		return result
+	case Monthly:
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1, hour, minute, second, 0, loc)
+		daysInMonth := time.Date(first.Year(), first.Month()+1, 0, 0, 0, 0, 0, loc).Day()
+
+		if len(r.ByDay) == 0 {
+			if dtstart.Day() > daysInMonth {
+				return nil
+			}
+			return []time.Time{time.Date(first.Year(), first.Month(), dtstart.Day(), hour, minute, second, 0, loc)}
+		}
+
+		result := make([]time.Time, 0)
+		for _, day := range r.ByDay {
+			for _, monthDay := range monthWeekdays(first, daysInMonth, day) {
+				result = append(result, time.Date(first.Year(), first.Month(), monthDay, hour, minute, second, 0, loc))
+			}
+		}
+		sortTimes(result)
+		return dedupeTimes(result)
+	}
+
+	return nil
+}
+
+func (r *Rule) hasWeekday(day time.Weekday) bool {
+	for _, weekday := range r.ByDay {
+		if weekday.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+func monthWeekdays(first time.Time, daysInMonth int, weekday Weekday) []int {
+	firstMatch := 1 + (int(weekday.Day)-int(first.Weekday())+7)%7
+
+	all := make([]int, 0, 5)
+	for day := firstMatch; day <= daysInMonth; day += 7 {
+		all = append(all, day)
+	}
+
+	switch {
+	case weekday.N == 0:
+		return all
+	case weekday.N > 0 && weekday.N <= len(all):
+		return []int{all[weekday.N-1]}
+	case weekday.N < 0 && -weekday.N <= len(all):
+		return []int{all[len(all)+weekday.N]}
+	}
+
+	return nil
+}
+
+func parseWeekday(value string) (Weekday, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return Weekday{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+
+	day, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return Weekday{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+
+	weekday := Weekday{Day: day}
+	if prefix := value[:len(value)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n > 5 || n < -5 {
+			return Weekday{}, fmt.Errorf("invalid BYDAY %q", value)
+		}
+		weekday.N = n
+	}
+
+	return weekday, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if until, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			return until, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", value)
+}
+
+func sortTimes(times []time.Time) {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+}
+
+func dedupeTimes(times []time.Time) []time.Time {
+	result := times[:0]
+	for i, t := range times {
+		if i == 0 || !t.Equal(times[i-1]) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
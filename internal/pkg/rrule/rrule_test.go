@@ -0,0 +1,279 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestBetween(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	berlin := mustLocation(t, "Europe/Berlin")
+	newYork := mustLocation(t, "America/New_York")
+
+	date := func(loc *time.Location, year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		from    time.Time
+		to      time.Time
+		exdates []time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "last friday of month",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart: date(berlin, 2025, time.January, 1, 18, 0),
+			from:    date(berlin, 2025, time.January, 1, 0, 0),
+			to:      date(berlin, 2025, time.May, 1, 0, 0),
+			want: []time.Time{
+				date(berlin, 2025, time.January, 31, 18, 0),
+				date(berlin, 2025, time.February, 28, 18, 0),
+				date(berlin, 2025, time.March, 28, 18, 0),
+				date(berlin, 2025, time.April, 25, 18, 0),
+			},
+		},
+		{
+			name:    "last friday skips months before dtstart day",
+			rule:    "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR",
+			dtstart: date(moscow, 2025, time.January, 31, 19, 0),
+			from:    date(moscow, 2025, time.January, 1, 0, 0),
+			to:      date(moscow, 2025, time.June, 1, 0, 0),
+			want: []time.Time{
+				date(moscow, 2025, time.January, 31, 19, 0),
+				date(moscow, 2025, time.March, 28, 19, 0),
+				date(moscow, 2025, time.May, 30, 19, 0),
+			},
+		},
+		{
+			name:    "second tuesday and last sunday",
+			rule:    "FREQ=MONTHLY;BYDAY=2TU,-1SU;COUNT=4",
+			dtstart: date(moscow, 2025, time.March, 1, 12, 0),
+			from:    date(moscow, 2025, time.March, 1, 0, 0),
+			to:      date(moscow, 2026, time.January, 1, 0, 0),
+			want: []time.Time{
+				date(moscow, 2025, time.March, 11, 12, 0),
+				date(moscow, 2025, time.March, 30, 12, 0),
+				date(moscow, 2025, time.April, 8, 12, 0),
+				date(moscow, 2025, time.April, 27, 12, 0),
+			},
+		},
+		{
+			name:    "count includes excluded dates",
+			rule:    "FREQ=DAILY;COUNT=5",
+			dtstart: date(moscow, 2025, time.March, 3, 10, 0),
+			from:    date(moscow, 2025, time.March, 1, 0, 0),
+			to:      date(moscow, 2025, time.April, 1, 0, 0),
+			exdates: []time.Time{
+				date(moscow, 2025, time.March, 4, 10, 0),
+				date(moscow, 2025, time.March, 7, 10, 0),
+			},
+			want: []time.Time{
+				date(moscow, 2025, time.March, 3, 10, 0),
+				date(moscow, 2025, time.March, 5, 10, 0),
+				date(moscow, 2025, time.March, 6, 10, 0),
+			},
+		},
+		{
+			name:    "count is applied from dtstart, not from window start",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=4",
+			dtstart: date(moscow, 2025, time.March, 3, 10, 0),
+			from:    date(moscow, 2025, time.March, 7, 0, 0),
+			to:      date(moscow, 2025, time.April, 1, 0, 0),
+			exdates: []time.Time{date(moscow, 2025, time.March, 10, 10, 0)},
+			want: []time.Time{
+				date(moscow, 2025, time.March, 13, 10, 0),
+			},
+		},
+		{
+			name:    "exdate in another time zone",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: date(moscow, 2025, time.March, 3, 10, 0),
+			from:    date(moscow, 2025, time.March, 1, 0, 0),
+			to:      date(moscow, 2025, time.April, 1, 0, 0),
+			exdates: []time.Time{time.Date(2025, time.March, 4, 7, 0, 0, 0, time.UTC)},
+			want: []time.Time{
+				date(moscow, 2025, time.March, 3, 10, 0),
+				date(moscow, 2025, time.March, 5, 10, 0),
+			},
+		},
+		{
+			name:    "until is inclusive",
+			rule:    "FREQ=DAILY;UNTIL=20250305T070000Z",
+			dtstart: date(moscow, 2025, time.March, 3, 10, 0),
+			from:    date(moscow, 2025, time.March, 1, 0, 0),
+			to:      date(moscow, 2025, time.April, 1, 0, 0),
+			want: []time.Time{
+				date(moscow, 2025, time.March, 3, 10, 0),
+				date(moscow, 2025, time.March, 4, 10, 0),
+				date(moscow, 2025, time.March, 5, 10, 0),
+			},
+		},
+		{
+			name:    "berlin spring forward keeps wall clock",
+			rule:    "FREQ=WEEKLY",
+			dtstart: date(berlin, 2025, time.March, 23, 19, 0),
+			from:    date(berlin, 2025, time.March, 1, 0, 0),
+			to:      date(berlin, 2025, time.April, 7, 0, 0),
+			want: []time.Time{
+				date(berlin, 2025, time.March, 23, 19, 0),
+				date(berlin, 2025, time.March, 30, 19, 0),
+				date(berlin, 2025, time.April, 6, 19, 0),
+			},
+		},
+		{
+			name:    "berlin fall back keeps wall clock",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: date(berlin, 2025, time.October, 25, 9, 30),
+			from:    date(berlin, 2025, time.October, 1, 0, 0),
+			to:      date(berlin, 2025, time.November, 1, 0, 0),
+			want: []time.Time{
+				date(berlin, 2025, time.October, 25, 9, 30),
+				date(berlin, 2025, time.October, 26, 9, 30),
+				date(berlin, 2025, time.October, 27, 9, 30),
+			},
+		},
+		{
+			name:    "moscow offset change keeps wall clock",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: date(moscow, 2014, time.October, 25, 10, 0),
+			from:    date(moscow, 2014, time.October, 1, 0, 0),
+			to:      date(moscow, 2014, time.November, 1, 0, 0),
+			want: []time.Time{
+				date(moscow, 2014, time.October, 25, 10, 0),
+				date(moscow, 2014, time.October, 26, 10, 0),
+				date(moscow, 2014, time.October, 27, 10, 0),
+			},
+		},
+		{
+			name:    "interval with wkst",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			dtstart: date(newYork, 1997, time.August, 5, 9, 0),
+			from:    date(newYork, 1997, time.August, 1, 0, 0),
+			to:      date(newYork, 1997, time.December, 1, 0, 0),
+			want: []time.Time{
+				date(newYork, 1997, time.August, 5, 9, 0),
+				date(newYork, 1997, time.August, 10, 9, 0),
+				date(newYork, 1997, time.August, 19, 9, 0),
+				date(newYork, 1997, time.August, 24, 9, 0),
+			},
+		},
+		{
+			name:    "interval skips weeks across month boundary",
+			rule:    "FREQ=WEEKLY;INTERVAL=3;BYDAY=WE;WKST=MO",
+			dtstart: date(moscow, 2025, time.January, 29, 20, 0),
+			from:    date(moscow, 2025, time.January, 1, 0, 0),
+			to:      date(moscow, 2025, time.April, 1, 0, 0),
+			want: []time.Time{
+				date(moscow, 2025, time.January, 29, 20, 0),
+				date(moscow, 2025, time.February, 19, 20, 0),
+				date(moscow, 2025, time.March, 12, 20, 0),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+
+			got := rule.Between(tt.dtstart, tt.from, tt.to, tt.exdates)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Between() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+				if got[i].Location() != tt.dtstart.Location() {
+					t.Errorf("occurrence %d location = %v, want %v", i, got[i].Location(), tt.dtstart.Location())
+				}
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	dtstart := time.Date(2025, time.March, 23, 19, 0, 0, 0, berlin)
+
+	rule, err := Parse("FREQ=WEEKLY;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"dtstart", dtstart, true},
+		{"after spring forward", time.Date(2025, time.March, 30, 19, 0, 0, 0, berlin), true},
+		{"same instant in utc", time.Date(2025, time.March, 30, 17, 0, 0, 0, time.UTC), true},
+		{"old offset after spring forward", time.Date(2025, time.March, 30, 18, 0, 0, 0, time.UTC), false},
+		{"beyond count", time.Date(2025, time.April, 13, 19, 0, 0, 0, berlin), false},
+		{"before dtstart", time.Date(2025, time.March, 16, 19, 0, 0, 0, berlin), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Contains(dtstart, tt.t); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{rule: "RRULE:FREQ=monthly;BYDAY=-1fr", want: "FREQ=MONTHLY;BYDAY=-1FR"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=MO;COUNT=4", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;COUNT=4"},
+		{rule: "FREQ=DAILY;UNTIL=20250305", want: "FREQ=DAILY;UNTIL=20250305T235959Z"},
+		{rule: "FREQ=YEARLY", wantErr: true},
+		{rule: "FREQ=WEEKLY;WKST=SU", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYDAY=6MO", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20250305", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{rule: "INTERVAL=2", wantErr: true},
+		{rule: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %v, want error", tt.rule, rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
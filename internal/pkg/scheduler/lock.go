@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// extendScript продлевает блокировку, только если она всё ещё принадлежит владельцу.
+var extendScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	return 0
+`)
+
+// releaseScript снимает блокировку, только если она принадлежит владельцу.
+var releaseScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0
+`)
+
+// Lock — блокировка в Redis для выбора ведущего экземпляра. Ключ хранит
+// идентификатор владельца и истекает через ttl, если владелец перестал его продлевать.
+type Lock struct {
+	redis *redis.Client
+	key   string
+	owner string
+	ttl   time.Duration
+}
+
+func NewLock(redis *redis.Client, key string, ttl time.Duration) *Lock {
+	return &Lock{
+		redis: redis,
+		key:   key,
+		owner: uuid.NewString(),
+		ttl:   ttl,
+	}
+}
+
+// Acquire захватывает блокировку или продлевает уже принадлежащую этому экземпляру.
+// Возвращает false, если блокировкой владеет другой экземпляр.
+func (l *Lock) Acquire(ctx context.Context) (bool, error) {
+	acquired, err := l.redis.SetNX(ctx, l.key, l.owner, l.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %w", l.key, err)
+	}
+	if acquired {
+		return true, nil
+	}
+
+	extended, err := extendScript.Run(ctx, l.redis, []string{l.key}, l.owner, l.ttl.Milliseconds()).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("failed to extend lock %s: %w", l.key, err)
+	}
+
+	return extended == 1, nil
+}
+
+func (l *Lock) Release(ctx context.Context) error {
+	if err := releaseScript.Run(ctx, l.redis, []string{l.key}, l.owner).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to release lock %s: %w", l.key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Job func(ctx context.Context) error
+
+// Scheduler периодически запускает задачу на ведущем экземпляре.
+// Остальные экземпляры на каждом тике пытаются перехватить блокировку
+// и начинают выполнять задачу, если ведущий перестал её продлевать.
+type Scheduler struct {
+	log      *slog.Logger
+	name     string
+	lock     *Lock
+	interval time.Duration
+	job      Job
+}
+
+func New(log *slog.Logger, name string, lock *Lock, interval time.Duration, job Job) *Scheduler {
+	return &Scheduler{
+		log:      log,
+		name:     name,
+		lock:     lock,
+		interval: interval,
+		job:      job,
+	}
+}
+
+// Start блокирует выполнение до отмены ctx.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.log.Info("starting scheduler", "name", s.name, "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	isLeader := false
+	for {
+		isLeader = s.tick(ctx, isLeader)
+
+		select {
+		case <-ctx.Done():
+			if isLeader {
+				if err := s.lock.Release(context.Background()); err != nil {
+					s.log.Error("failed to release scheduler lock", "name", s.name, "error", err)
+				}
+			}
+			s.log.Info("scheduler stopped", "name", s.name)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context, wasLeader bool) bool {
+	isLeader, err := s.lock.Acquire(ctx)
+	if err != nil {
+		s.log.Error("failed to acquire scheduler lock", "name", s.name, "error", err)
+		return false
+	}
+
+	if isLeader != wasLeader {
+		s.log.Info("scheduler leadership changed", "name", s.name, "leader", isLeader)
+	}
+	if !isLeader {
+		return false
+	}
+
+	if err := s.job(ctx); err != nil {
+		s.log.Error("scheduled job failed", "name", s.name, "error", err)
+	}
+
+	return true
+}
@@ -0,0 +1,120 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// Параметры кодирования aes128gcm (RFC 8188) для Web Push (RFC 8291).
+const (
+	saltLength       = 16
+	authLength       = 16
+	recordSize       = 4096
+	tagLength        = 16
+	keyLength        = 65
+	headerLength     = saltLength + 4 + 1 + keyLength
+	paddingDelimiter = 0x02
+)
+
+// MaxPayloadSize — наибольший размер данных, помещающийся в одну запись.
+const MaxPayloadSize = recordSize - tagLength - 1
+
+var (
+	ErrInvalidSubscription = errors.New("неверные ключи подписки")
+	ErrPayloadTooLarge     = errors.New("слишком большое push-уведомление")
+)
+
+// encrypt шифрует данные для подписчика: общий секрет выводится из
+// одноразового ключа сервера и ключа браузера (p256dh) с учётом auth.
+func encrypt(subscription *Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	userAgentKey, authSecret, err := subscription.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	serverPublicKey := serverKey.PublicKey().Bytes()
+	cek, nonce, err := deriveKeys(serverKey, userAgentKey, authSecret, salt, userAgentKey.Bytes(), serverPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := append(append(make([]byte, 0, len(payload)+1), payload...), paddingDelimiter)
+
+	message := make([]byte, headerLength, headerLength+len(plaintext)+tagLength)
+	copy(message, salt)
+	binary.BigEndian.PutUint32(message[saltLength:], recordSize)
+	message[saltLength+4] = keyLength
+	copy(message[saltLength+5:], serverPublicKey)
+
+	return gcm.Seal(message, nonce, plaintext, nil), nil
+}
+
+// deriveKeys вычисляет ключ шифрования содержимого и nonce по RFC 8291,
+// раздел 3.4. Ключи сторон передаются в порядке «браузер, сервер».
+func deriveKeys(private *ecdh.PrivateKey, peer *ecdh.PublicKey, authSecret, salt, userAgentPublicKey, serverPublicKey []byte) ([]byte, []byte, error) {
+	sharedSecret, err := private.ECDH(peer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyInfo := make([]byte, 0, 14+2*keyLength)
+	keyInfo = append(keyInfo, "WebPush: info\x00"...)
+	keyInfo = append(keyInfo, userAgentPublicKey...)
+	keyInfo = append(keyInfo, serverPublicKey...)
+
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cek, nonce, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,146 @@
+package webpush_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush"
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush/webpushtest"
+)
+
+func newSender(t *testing.T, client *http.Client) *webpush.Sender {
+	t.Helper()
+
+	publicKey, privateKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("GenerateVAPIDKeys: %v", err)
+	}
+
+	sender, err := webpush.NewSender(webpush.VAPID{
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+		Subject:    "mailto:push@example.com",
+	}, client)
+	if err != nil {
+		t.Fatalf("NewSender: %v", err)
+	}
+	return sender
+}
+
+func TestSendEncryptsPayload(t *testing.T) {
+	service := webpushtest.NewFakeService()
+	defer service.Close()
+
+	subscription, err := service.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`{"title":"Встреча","body":"Начало через час"}`)
+	options := webpush.Options{TTL: time.Hour, Urgency: webpush.UrgencyHigh, Topic: "event-reminder"}
+
+	if err := newSender(t, nil).Send(context.Background(), subscription, payload, options); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	messages := service.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+
+	got := messages[0]
+	if !bytes.Equal(got.Payload, payload) {
+		t.Errorf("payload = %q, want %q", got.Payload, payload)
+	}
+	if got.Endpoint != subscription.Endpoint {
+		t.Errorf("endpoint = %q, want %q", got.Endpoint, subscription.Endpoint)
+	}
+	if got.TTL != "3600" || got.Urgency != "high" || got.Topic != "event-reminder" {
+		t.Errorf("headers = TTL %q, Urgency %q, Topic %q", got.TTL, got.Urgency, got.Topic)
+	}
+}
+
+func TestSendRejectsPayloadTooLarge(t *testing.T) {
+	service := webpushtest.NewFakeService()
+	defer service.Close()
+
+	subscription, err := service.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := bytes.Repeat([]byte("a"), webpush.MaxPayloadSize+1)
+	err = newSender(t, nil).Send(context.Background(), subscription, payload, webpush.Options{TTL: time.Minute})
+	if !errors.Is(err, webpush.ErrPayloadTooLarge) {
+		t.Fatalf("Send = %v, want ErrPayloadTooLarge", err)
+	}
+}
+
+// tamperTransport портит подпись JWT в заголовке Authorization.
+type tamperTransport struct{}
+
+func (tamperTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	authorization := []byte(req.Header.Get("Authorization"))
+	signature := bytes.LastIndexByte(authorization, '.') + 1
+	if authorization[signature] == 'A' {
+		authorization[signature] = 'B'
+	} else {
+		authorization[signature] = 'A'
+	}
+	req.Header.Set("Authorization", string(authorization))
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestSendVAPID(t *testing.T) {
+	service := webpushtest.NewFakeService()
+	defer service.Close()
+
+	subscription, err := service.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		if err := newSender(t, nil).Send(context.Background(), subscription, []byte("ok"), webpush.Options{TTL: time.Minute}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	})
+
+	t.Run("tampered signature", func(t *testing.T) {
+		sender := newSender(t, &http.Client{Transport: tamperTransport{}})
+
+		err := sender.Send(context.Background(), subscription, []byte("tampered"), webpush.Options{TTL: time.Minute})
+
+		var statusErr *webpush.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Send = %v, want status 401", err)
+		}
+	})
+
+	if got := len(service.Messages()); got != 1 {
+		t.Errorf("got %d messages, want 1", got)
+	}
+}
+
+func TestSendExpiredSubscription(t *testing.T) {
+	service := webpushtest.NewFakeService()
+	defer service.Close()
+
+	subscription, err := service.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.Expire(subscription.Endpoint)
+
+	err = newSender(t, nil).Send(context.Background(), subscription, []byte("gone"), webpush.Options{TTL: time.Minute})
+	if !errors.Is(err, webpush.ErrSubscriptionGone) {
+		t.Fatalf("Send = %v, want ErrSubscriptionGone", err)
+	}
+}
@@ -0,0 +1,117 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// vapidTokenTTL — срок действия подписи VAPID. RFC 8292 ограничивает его сутками.
+const vapidTokenTTL = 12 * time.Hour
+
+var ErrInvalidVAPIDKey = errors.New("неверный ключ VAPID")
+
+// VAPID — ключи сервера приложения (RFC 8292). Ключи передаются в base64url
+// без выравнивания: открытый — точкой P-256 в несжатом виде (65 байт),
+// закрытый — скаляром (32 байта). Subject — адрес для связи (mailto: или https:).
+type VAPID struct {
+	PublicKey  string
+	PrivateKey string
+	Subject    string
+}
+
+// GenerateVAPIDKeys создаёт новую пару ключей VAPID в формате base64url.
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	return encode(key.PublicKey().Bytes()), encode(key.Bytes()), nil
+}
+
+type vapidSigner struct {
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+}
+
+func newVAPIDSigner(vapid VAPID) (*vapidSigner, error) {
+	raw, err := decode(vapid.PrivateKey)
+	if err != nil {
+		return nil, ErrInvalidVAPIDKey
+	}
+
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, ErrInvalidVAPIDKey
+	}
+
+	publicKey, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, ErrInvalidVAPIDKey
+	}
+	if vapid.PublicKey != "" && vapid.PublicKey != encode(publicKey) {
+		return nil, fmt.Errorf("%w: открытый ключ не соответствует закрытому", ErrInvalidVAPIDKey)
+	}
+
+	return &vapidSigner{key: key, publicKey: encode(publicKey), subject: vapid.Subject}, nil
+}
+
+// authorization возвращает заголовок Authorization для запроса к сервису
+// доставки: JWT (ES256) с адресатом, равным origin сервиса.
+func (s *vapidSigner) authorization(endpoint string, now time.Time) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header := encode([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": endpointURL.Scheme + "://" + endpointURL.Host,
+		"exp": now.Add(vapidTokenTTL).Unix(),
+		"sub": s.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+
+	return "vapid t=" + signingInput + "." + encode(signature) + ", k=" + s.publicKey, nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decode принимает base64url как с выравниванием, так и без: браузеры и
+// библиотеки отдают ключи подписки в обоих видах.
+func decode(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(trimPadding(value))
+}
+
+func trimPadding(value string) string {
+	for len(value) > 0 && value[len(value)-1] == '=' {
+		value = value[:len(value)-1]
+	}
+	return value
+}
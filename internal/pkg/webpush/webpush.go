@@ -0,0 +1,147 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrSubscriptionGone означает, что сервис доставки больше не знает подписку
+// (ответ 404 или 410): её нужно удалить.
+var ErrSubscriptionGone = errors.New("push-подписка больше не действует")
+
+// Urgency — приоритет доставки (RFC 8030, раздел 5.3).
+type Urgency string
+
+const (
+	UrgencyVeryLow Urgency = "very-low"
+	UrgencyLow     Urgency = "low"
+	UrgencyNormal  Urgency = "normal"
+	UrgencyHigh    Urgency = "high"
+)
+
+// Subscription — подписка браузера из PushSubscription.toJSON(): адрес
+// сервиса доставки и ключи для шифрования в base64url.
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Validate проверяет, что ключи подписки пригодны для шифрования.
+func (s *Subscription) Validate() error {
+	_, _, err := s.keys()
+	return err
+}
+
+func (s *Subscription) keys() (*ecdh.PublicKey, []byte, error) {
+	rawKey, err := decode(s.P256dh)
+	if err != nil {
+		return nil, nil, ErrInvalidSubscription
+	}
+
+	key, err := ecdh.P256().NewPublicKey(rawKey)
+	if err != nil {
+		return nil, nil, ErrInvalidSubscription
+	}
+
+	auth, err := decode(s.Auth)
+	if err != nil || len(auth) != authLength {
+		return nil, nil, ErrInvalidSubscription
+	}
+
+	return key, auth, nil
+}
+
+// Options — параметры доставки. TTL — сколько сервис хранит сообщение, пока
+// браузер недоступен; Topic заменяет ещё не доставленное сообщение с тем же топиком.
+type Options struct {
+	TTL     time.Duration
+	Urgency Urgency
+	Topic   string
+}
+
+// StatusError — ответ сервиса доставки с неуспешным статусом.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("push service responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Sender отправляет зашифрованные уведомления (RFC 8291) с подписью VAPID.
+type Sender struct {
+	client *http.Client
+	signer *vapidSigner
+}
+
+func NewSender(vapid VAPID, client *http.Client) (*Sender, error) {
+	signer, err := newVAPIDSigner(vapid)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Sender{client: client, signer: signer}, nil
+}
+
+// PublicKey возвращает открытый ключ VAPID для PushManager.subscribe на клиенте.
+func (s *Sender) PublicKey() string {
+	return s.signer.publicKey
+}
+
+func (s *Sender) Send(ctx context.Context, subscription *Subscription, payload []byte, options Options) error {
+	body, err := encrypt(subscription, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := s.signer.authorization(subscription.Endpoint, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to sign push request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(options.TTL.Seconds())))
+	if options.Urgency != "" {
+		req.Header.Set("Urgency", string(options.Urgency))
+	}
+	if options.Topic != "" {
+		req.Header.Set("Topic", options.Topic)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send push request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	default:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(message)}
+	}
+}
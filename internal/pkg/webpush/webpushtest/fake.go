@@ -0,0 +1,308 @@
+// Package webpushtest содержит локальный сервис доставки push-уведомлений
+// для тестов кода, использующего webpush.Sender.
+package webpushtest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RuLap/meetly-api/meetly/internal/pkg/webpush"
+	"github.com/google/uuid"
+)
+
+// Параметры кодирования aes128gcm (RFC 8188), которые проверяет сервис.
+const (
+	saltLength       = 16
+	authLength       = 16
+	keyLength        = 65
+	headerLength     = saltLength + 4 + 1 + keyLength
+	tagLength        = 16
+	maxRecordSize    = 4096
+	paddingDelimiter = 0x02
+)
+
+var errInvalidMessage = errors.New("invalid encrypted message")
+
+// FakeMessage — сообщение, принятое тестовым сервисом доставки.
+type FakeMessage struct {
+	Endpoint string
+	Payload  []byte
+	TTL      string
+	Urgency  string
+	Topic    string
+}
+
+// FakeService — локальный сервис доставки для проверки webpush.Sender без браузера.
+// Он выдаёт подписки со своими ключами, проверяет подпись VAPID, расшифровывает
+// сообщения и отвечает 410 на подписки, отмеченные как истёкшие.
+type FakeService struct {
+	server *httptest.Server
+
+	mu          sync.Mutex
+	subscribers map[string]*fakeSubscriber
+	messages    []FakeMessage
+}
+
+type fakeSubscriber struct {
+	key     *ecdh.PrivateKey
+	auth    []byte
+	expired bool
+}
+
+func NewFakeService() *FakeService {
+	service := &FakeService{subscribers: make(map[string]*fakeSubscriber)}
+	service.server = httptest.NewServer(http.HandlerFunc(service.handle))
+	return service
+}
+
+func (f *FakeService) URL() string {
+	return f.server.URL
+}
+
+func (f *FakeService) Close() {
+	f.server.Close()
+}
+
+// Subscribe создаёт подписку так же, как это делает браузер.
+func (f *FakeService) Subscribe() (*webpush.Subscription, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := make([]byte, authLength)
+	if _, err := rand.Read(auth); err != nil {
+		return nil, err
+	}
+
+	endpoint := f.server.URL + "/push/" + uuid.NewString()
+
+	f.mu.Lock()
+	f.subscribers[endpoint] = &fakeSubscriber{key: key, auth: auth}
+	f.mu.Unlock()
+
+	return &webpush.Subscription{
+		Endpoint: endpoint,
+		P256dh:   encode(key.PublicKey().Bytes()),
+		Auth:     encode(auth),
+	}, nil
+}
+
+// Expire отмечает подписку истёкшей: дальнейшие запросы получат 410.
+func (f *FakeService) Expire(endpoint string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if subscriber, ok := f.subscribers[endpoint]; ok {
+		subscriber.expired = true
+	}
+}
+
+// Messages возвращает расшифрованные сообщения в порядке получения.
+func (f *FakeService) Messages() []FakeMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeMessage(nil), f.messages...)
+}
+
+func (f *FakeService) handle(w http.ResponseWriter, r *http.Request) {
+	endpoint := f.server.URL + r.URL.Path
+
+	f.mu.Lock()
+	subscriber, ok := f.subscribers[endpoint]
+	f.mu.Unlock()
+
+	switch {
+	case r.Method != http.MethodPost:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	case !ok:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case subscriber.expired:
+		w.WriteHeader(http.StatusGone)
+		return
+	case r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "":
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !verifyVAPID(r.Header.Get("Authorization"), f.server.URL) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRecordSize+headerLength+1))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	payload, err := decrypt(subscriber.key, subscriber.auth, body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.messages = append(f.messages, FakeMessage{
+		Endpoint: endpoint,
+		Payload:  payload,
+		TTL:      r.Header.Get("TTL"),
+		Urgency:  r.Header.Get("Urgency"),
+		Topic:    r.Header.Get("Topic"),
+	})
+	f.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// verifyVAPID проверяет подпись JWT открытым ключом из заголовка, адресата
+// и срок действия.
+func verifyVAPID(authorization, audience string) bool {
+	params, ok := strings.CutPrefix(authorization, "vapid ")
+	if !ok {
+		return false
+	}
+
+	var token, publicKey string
+	for _, param := range strings.Split(params, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		switch name {
+		case "t":
+			token = value
+		case "k":
+			publicKey = value
+		}
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	rawKey, err := decode(publicKey)
+	if err != nil {
+		return false
+	}
+	key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), rawKey)
+	if err != nil {
+		return false
+	}
+
+	signature, err := decode(parts[2])
+	if err != nil || len(signature) != 64 {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		return false
+	}
+
+	rawClaims, err := decode(parts[1])
+	if err != nil {
+		return false
+	}
+
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(rawClaims, &claims); err != nil {
+		return false
+	}
+
+	return claims.Aud == audience && claims.Exp > time.Now().Unix() && claims.Sub != ""
+}
+
+// decrypt расшифровывает сообщение так, как это делает браузер (RFC 8291).
+// Реализация намеренно не использует код пакета webpush: иначе ошибка в
+// выводе ключей осталась бы незамеченной.
+func decrypt(userAgentKey *ecdh.PrivateKey, authSecret, message []byte) ([]byte, error) {
+	if len(message) < headerLength+tagLength || message[saltLength+4] != keyLength {
+		return nil, errInvalidMessage
+	}
+
+	salt := message[:saltLength]
+	if recordSize := binary.BigEndian.Uint32(message[saltLength:]); recordSize > maxRecordSize || int(recordSize) < len(message)-headerLength {
+		return nil, errInvalidMessage
+	}
+	serverPublicKey := message[saltLength+5 : headerLength]
+
+	serverKey, err := ecdh.P256().NewPublicKey(serverPublicKey)
+	if err != nil {
+		return nil, errInvalidMessage
+	}
+
+	sharedSecret, err := userAgentKey.ECDH(serverKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(userAgentKey.PublicKey().Bytes()) + string(serverPublicKey)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, message[headerLength:], nil)
+	if err != nil {
+		return nil, errInvalidMessage
+	}
+
+	plaintext = bytes.TrimRight(plaintext, "\x00")
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != paddingDelimiter {
+		return nil, errInvalidMessage
+	}
+
+	return plaintext[:len(plaintext)-1], nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(value)
+}
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN max_participants INT CHECK (max_participants > 0);
+
+DELETE FROM participants a
+    USING participants b
+WHERE a.event_id = b.event_id
+  AND a.user_id = b.user_id
+  AND a.ctid > b.ctid;
+
+CREATE UNIQUE INDEX idx_participants_event_user ON participants(event_id, user_id);
+
+CREATE TABLE waitlist (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    event_id UUID REFERENCES events(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_waitlist_event_user ON waitlist(event_id, user_id);
+CREATE INDEX idx_waitlist_event_created_at ON waitlist(event_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_waitlist_event_created_at;
+DROP INDEX IF EXISTS idx_waitlist_event_user;
+DROP TABLE IF EXISTS waitlist;
+DROP INDEX IF EXISTS idx_participants_event_user;
+ALTER TABLE events DROP COLUMN IF EXISTS max_participants;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE event_bans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID REFERENCES events(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    banned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_event_bans_event_user ON event_bans(event_id, user_id);
+CREATE INDEX idx_participants_event_joined_at ON participants(event_id, joined_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_participants_event_joined_at;
+DROP INDEX IF EXISTS idx_event_bans_event_user;
+DROP TABLE IF EXISTS event_bans;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN join_mode VARCHAR(20) NOT NULL DEFAULT 'open';
+
+CREATE TABLE event_invites (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID REFERENCES events(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    token VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    max_uses INT CHECK (max_uses > 0),
+    uses INT NOT NULL DEFAULT 0,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_event_invites_event_id ON event_invites(event_id);
+
+CREATE TABLE join_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID REFERENCES events(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    decided_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_join_requests_event_user ON join_requests(event_id, user_id);
+CREATE INDEX idx_join_requests_event_status ON join_requests(event_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_join_requests_event_status;
+DROP INDEX IF EXISTS idx_join_requests_event_user;
+DROP TABLE IF EXISTS join_requests;
+DROP INDEX IF EXISTS idx_event_invites_event_id;
+DROP TABLE IF EXISTS event_invites;
+ALTER TABLE events DROP COLUMN IF EXISTS join_mode;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN recurrence_rule TEXT,
+    ADD COLUMN series_id UUID REFERENCES events(id) ON DELETE CASCADE,
+    ADD COLUMN occurrence_starts_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN cancelled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX idx_events_series_occurrence ON events(series_id, occurrence_starts_at);
+
+CREATE TABLE event_recurrence_exceptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID REFERENCES events(id) ON DELETE CASCADE,
+    occurs_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_event_recurrence_exceptions_event_occurs_at ON event_recurrence_exceptions(event_id, occurs_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_event_recurrence_exceptions_event_occurs_at;
+DROP TABLE IF EXISTS event_recurrence_exceptions;
+DROP INDEX IF EXISTS idx_events_series_occurrence;
+ALTER TABLE events
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS occurrence_starts_at,
+    DROP COLUMN IF EXISTS series_id,
+    DROP COLUMN IF EXISTS recurrence_rule,
+    DROP COLUMN IF EXISTS timezone;
+-- +goose StatementEnd
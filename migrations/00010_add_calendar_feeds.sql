@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events
+    ADD COLUMN sequence INT NOT NULL DEFAULT 0,
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+UPDATE events SET updated_at = created_at WHERE created_at IS NOT NULL;
+
+CREATE TABLE calendar_feeds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS calendar_feeds;
+ALTER TABLE events
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS sequence;
+-- +goose StatementEnd
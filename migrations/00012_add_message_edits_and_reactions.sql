@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE messages
+    ADD COLUMN edited_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Предыдущие версии отредактированных сообщений, доступные организатору.
+CREATE TABLE message_edits (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    edited_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_message_edits_message_id ON message_edits(message_id);
+
+CREATE TABLE message_reactions (
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (message_id, user_id, emoji)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS message_reactions;
+DROP INDEX IF EXISTS idx_message_edits_message_id;
+DROP TABLE IF EXISTS message_edits;
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS edited_at;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Последнее прочитанное сообщение пользователя в чате события. Время создания
+-- сообщения хранится рядом, чтобы отметка двигалась только вперёд.
+CREATE TABLE chat_reads (
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    message_created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    read_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (event_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS chat_reads;
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Разрешает личные сообщения от пользователей без общих событий,
+-- если такое же согласие дал и собеседник.
+ALTER TABLE users ADD COLUMN open_direct_messages BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE user_blocks (
+    blocker_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX idx_user_blocks_blocked_id ON user_blocks(blocked_id);
+
+-- Собеседники хранятся упорядоченно, чтобы у пары был только один диалог.
+CREATE TABLE conversations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_low_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_high_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (user_low_id < user_high_id)
+);
+
+CREATE UNIQUE INDEX idx_conversations_users ON conversations(user_low_id, user_high_id);
+CREATE INDEX idx_conversations_user_high_id ON conversations(user_high_id);
+
+CREATE TABLE direct_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_direct_messages_conversation_created ON direct_messages(conversation_id, created_at, id);
+
+CREATE TABLE conversation_reads (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message_id UUID NOT NULL REFERENCES direct_messages(id) ON DELETE CASCADE,
+    message_created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    read_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (conversation_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS conversation_reads;
+DROP INDEX IF EXISTS idx_direct_messages_conversation_created;
+DROP TABLE IF EXISTS direct_messages;
+DROP INDEX IF EXISTS idx_conversations_user_high_id;
+DROP INDEX IF EXISTS idx_conversations_users;
+DROP TABLE IF EXISTS conversations;
+DROP INDEX IF EXISTS idx_user_blocks_blocked_id;
+DROP TABLE IF EXISTS user_blocks;
+ALTER TABLE users DROP COLUMN IF EXISTS open_direct_messages;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ссылка на обложку события. Файлы лежат в хранилище объектов,
+-- в таблице хранится только адрес основной миниатюры.
+ALTER TABLE events ADD COLUMN cover_url TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN IF EXISTS cover_url;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Общие настройки уведомлений пользователя. Тихие часы хранятся в минутах
+-- от полуночи в часовом поясе timezone; unsubscribe_token подписывает ссылку
+-- List-Unsubscribe в письмах.
+CREATE TABLE notification_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    quiet_hours_start SMALLINT CHECK (quiet_hours_start BETWEEN 0 AND 1439),
+    quiet_hours_end SMALLINT CHECK (quiet_hours_end BETWEEN 0 AND 1439),
+    timezone VARCHAR(64),
+    unsubscribed_at TIMESTAMP WITH TIME ZONE,
+    unsubscribe_token VARCHAR(64) NOT NULL UNIQUE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Выбор каналов по типу уведомления. NULL — значение по умолчанию.
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(64) NOT NULL,
+    email BOOLEAN,
+    in_app BOOLEAN,
+    push BOOLEAN,
+    PRIMARY KEY (user_id, type)
+);
+
+-- Письма, отложенные до окончания тихих часов получателя.
+CREATE TABLE deferred_emails (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payload JSONB NOT NULL,
+    deliver_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_deferred_emails_deliver_at ON deferred_emails(deliver_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS deferred_emails;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notification_settings;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Подписки браузеров на Web Push. endpoint уникален: при повторной подписке
+-- с того же браузера запись обновляется.
+CREATE TABLE push_subscriptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint TEXT NOT NULL UNIQUE,
+    p256dh VARCHAR(128) NOT NULL,
+    auth VARCHAR(64) NOT NULL,
+    user_agent VARCHAR(512),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_push_subscriptions_user_id ON push_subscriptions(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS push_subscriptions;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Сообщения для брокера, записанные в одной транзакции с изменением данных.
+-- Ретранслятор публикует их с подтверждением брокера и отмечает published_at;
+-- id передаётся как message_id и служит ключом дедупликации у потребителей.
+CREATE TABLE outbox (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbox_pending ON outbox(created_at) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_published_at ON outbox(published_at) WHERE published_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Язык писем пользователя. NULL — язык по умолчанию.
+ALTER TABLE notification_settings ADD COLUMN locale VARCHAR(8);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notification_settings DROP COLUMN IF EXISTS locale;
+-- +goose StatementEnd